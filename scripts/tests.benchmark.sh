@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+# Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+# See the file LICENSE for licensing terms.
+
+set -e
+
+if ! [[ "$0" =~ scripts/tests.benchmark.sh ]]; then
+  echo "must be run from repository root"
+  exit 255
+fi
+
+HYPERSDK_PATH=$(
+  cd "$(dirname "${BASH_SOURCE[0]}")"
+  cd .. && pwd
+)
+source "$HYPERSDK_PATH"/scripts/constants.sh
+
+# runtime hot paths of the wasm programs runtime
+go test -run=^$ -bench=. -benchmem -timeout="10m" ./x/programs/runtime/...
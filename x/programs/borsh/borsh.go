@@ -0,0 +1,117 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package borsh implements a borsh encoder for passing structured arguments
+// to programs.
+//
+// ref. https://borsh.io
+package borsh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// Serialize returns the borsh encoding of [v].
+//
+// Supported kinds are bool, fixed size integers, strings, arrays, slices,
+// pointers (encoded as an option) and structs whose exported fields are
+// encoded in declaration order.
+func Serialize(v interface{}) ([]byte, error) {
+	return appendValue(nil, reflect.ValueOf(v))
+}
+
+func appendValue(buf []byte, v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case reflect.Uint8:
+		return append(buf, uint8(v.Uint())), nil
+	case reflect.Uint16:
+		return binary.LittleEndian.AppendUint16(buf, uint16(v.Uint())), nil
+	case reflect.Uint32:
+		return binary.LittleEndian.AppendUint32(buf, uint32(v.Uint())), nil
+	case reflect.Uint64:
+		return binary.LittleEndian.AppendUint64(buf, v.Uint()), nil
+	case reflect.Int8:
+		return append(buf, uint8(v.Int())), nil
+	case reflect.Int16:
+		return binary.LittleEndian.AppendUint16(buf, uint16(v.Int())), nil
+	case reflect.Int32:
+		return binary.LittleEndian.AppendUint32(buf, uint32(v.Int())), nil
+	case reflect.Int64:
+		return binary.LittleEndian.AppendUint64(buf, uint64(v.Int())), nil
+	case reflect.String:
+		buf, err := appendLen(buf, v.Len())
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, v.String()...), nil
+	case reflect.Array:
+		// fixed length arrays are not length prefixed
+		return appendElems(buf, v)
+	case reflect.Slice:
+		buf, err := appendLen(buf, v.Len())
+		if err != nil {
+			return nil, err
+		}
+		return appendElems(buf, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return append(buf, 0), nil
+		}
+		return appendValue(append(buf, 1), v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			var err error
+			buf, err = appendValue(buf, v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, v.Kind())
+	}
+}
+
+func appendElems(buf []byte, v reflect.Value) ([]byte, error) {
+	// fast path for byte arrays and slices
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		if v.Kind() == reflect.Slice {
+			return append(buf, v.Bytes()...), nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			buf = append(buf, uint8(v.Index(i).Uint()))
+		}
+		return buf, nil
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		var err error
+		buf, err = appendValue(buf, v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func appendLen(buf []byte, l int) ([]byte, error) {
+	if l > math.MaxUint32 {
+		return nil, fmt.Errorf("length exceeds u32: %d", l)
+	}
+	return binary.LittleEndian.AppendUint32(buf, uint32(l)), nil
+}
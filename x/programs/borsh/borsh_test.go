@@ -0,0 +1,61 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package borsh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type transfer struct {
+	To     [4]byte
+	Amount uint64
+	Memo   string
+	Tags   []uint16
+	Fee    *uint32
+	Nested nested
+
+	ignored bool
+}
+
+type nested struct {
+	Ok    bool
+	Delta int16
+}
+
+func TestSerialize(t *testing.T) {
+	require := require.New(t)
+
+	fee := uint32(7)
+	bytes, err := Serialize(transfer{
+		To:     [4]byte{1, 2, 3, 4},
+		Amount: 258,
+		Memo:   "hi",
+		Tags:   []uint16{1, 2},
+		Fee:    &fee,
+		Nested: nested{Ok: true, Delta: -2},
+	})
+	require.NoError(err)
+	require.Equal([]byte{
+		1, 2, 3, 4, // To
+		2, 1, 0, 0, 0, 0, 0, 0, // Amount
+		2, 0, 0, 0, 'h', 'i', // Memo
+		2, 0, 0, 0, 1, 0, 2, 0, // Tags
+		1, 7, 0, 0, 0, // Fee
+		1, 0xfe, 0xff, // Nested
+	}, bytes)
+
+	// nil pointer is encoded as none
+	bytes, err = Serialize(transfer{})
+	require.NoError(err)
+	require.Equal(byte(0), bytes[4+8+4+4])
+
+	bytes, err = Serialize([]byte{1, 2})
+	require.NoError(err)
+	require.Equal([]byte{2, 0, 0, 0, 1, 2}, bytes)
+
+	_, err = Serialize(map[string]string{})
+	require.ErrorIs(err, ErrUnsupportedType)
+}
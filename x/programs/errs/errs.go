@@ -0,0 +1,86 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package errs defines the categories of errors returned by the program
+// runtime, its imports and storage. The sentinel errors of these packages
+// each belong to a category and the runtime assigns a category to the errors
+// of guest calls, so callers can use errors.Is instead of matching messages.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrValidation is the category of invalid configs, params and
+	// arguments provided to the runtime or by the guest.
+	ErrValidation = errors.New("validation error")
+	// ErrOutOfFuel is the category of calls which ran out of units.
+	ErrOutOfFuel = errors.New("out of fuel")
+	// ErrTrap is the category of guest traps other than running out of
+	// units or accessing memory out of bounds.
+	ErrTrap = errors.New("trap")
+	// ErrMemory is the category of failures to read, write, allocate or
+	// grow guest memory.
+	ErrMemory = errors.New("memory error")
+	// ErrState is the category of failures to read or write program state.
+	ErrState = errors.New("state error")
+)
+
+// Error is an error of a category optionally wrapping the error causing it.
+type Error struct {
+	Category error
+	Msg      string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	default:
+		return e.Msg + ": " + e.Err.Error()
+	}
+}
+
+// Unwrap returns the error causing this error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if [target] is the category of this error.
+func (e *Error) Is(target error) bool {
+	return target == e.Category
+}
+
+// New returns a new error of [category] with the message [text].
+func New(category error, text string) error {
+	return &Error{Category: category, Msg: text}
+}
+
+// Wrap returns [err] as an error of [category]. If [err] is nil or already
+// of a category it is returned unchanged.
+func Wrap(category error, err error) error {
+	if err == nil || Category(err) != nil {
+		return err
+	}
+	return &Error{Category: category, Err: err}
+}
+
+// Wrapf returns [err] as an error of [category] with its message prefixed by
+// the formatted message.
+func Wrapf(category error, err error, format string, args ...interface{}) error {
+	return &Error{Category: category, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Category returns the category of [err] or nil if it has none.
+func Category(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+	return nil
+}
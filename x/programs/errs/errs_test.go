@@ -0,0 +1,45 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategory(t *testing.T) {
+	require := require.New(t)
+
+	errTest := New(ErrState, "test")
+	require.Equal("test", errTest.Error())
+	require.ErrorIs(errTest, ErrState)
+	require.NotErrorIs(errTest, ErrMemory)
+
+	// the category is kept when wrapped
+	err := fmt.Errorf("failed: %w", errTest)
+	require.ErrorIs(err, errTest)
+	require.ErrorIs(err, ErrState)
+	require.Equal(ErrState, Category(err))
+
+	require.Nil(Category(errors.New("test")))
+	require.NoError(Wrap(ErrTrap, nil))
+
+	// the first category is kept
+	require.Equal(err, Wrap(ErrTrap, err))
+
+	cause := errors.New("cause")
+	err = Wrap(ErrMemory, cause)
+	require.Equal("cause", err.Error())
+	require.ErrorIs(err, cause)
+	require.ErrorIs(err, ErrMemory)
+	require.Equal(ErrMemory, Category(err))
+
+	err = Wrapf(ErrTrap, cause, "call %s", "test")
+	require.Equal("call test: cause", err.Error())
+	require.Equal(cause, errors.Unwrap(err))
+	require.ErrorIs(err, ErrTrap)
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
 
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/program"
 	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
 	"github.com/ava-labs/hypersdk/x/programs/runtime"
 )
@@ -43,25 +45,29 @@ type Counter struct {
 	db           state.Mutable
 }
 
-func (c *Counter) Run(ctx context.Context) error {
-	rt := runtime.New(c.log, c.cfg, c.imports)
-	err := rt.Initialize(ctx, c.programBytes)
-	if err != nil {
-		return err
-	}
+// externalParams are the params of a function of [Caller] calling [Target]
+// on behalf of an account.
+type externalParams struct {
+	Caller   ids.ID            `program:"ptr"`
+	Target   ids.ID            `program:"ptr"`
+	MaxUnits uint64            `program:"u64"`
+	Account  ed25519.PublicKey `program:"ptr"`
+}
 
-	c.log.Debug("initial meter",
-		zap.Uint64("balance", rt.Meter().GetBalance()),
-	)
+// externalAmountParams are the params of a function of [Caller] calling
+// [Target] on behalf of an account with an amount.
+type externalAmountParams struct {
+	Caller   ids.ID            `program:"ptr"`
+	Target   ids.ID            `program:"ptr"`
+	MaxUnits uint64            `program:"u64"`
+	Account  ed25519.PublicKey `program:"ptr"`
+	Amount   uint64            `program:"u64"`
+}
 
+func (c *Counter) Run(ctx context.Context) error {
 	// simulate create program transaction
 	programID := ids.GenerateTestID()
-	err = storage.SetProgram(ctx, c.db, programID, c.programBytes)
-	if err != nil {
-		return err
-	}
-
-	programIDPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	err := storage.SetProgram(ctx, c.db, programID, c.programBytes)
 	if err != nil {
 		return err
 	}
@@ -70,25 +76,29 @@ func (c *Counter) Run(ctx context.Context) error {
 		zap.String("id", programID.String()),
 	)
 
-	// generate alice keys
-	_, aliceKey, err := newKey()
+	rt := runtime.New(c.log, c.cfg, program.BindImports(c.imports, programID))
+	err = rt.Initialize(ctx, c.programBytes)
 	if err != nil {
 		return err
 	}
 
-	// write alice's key to stack and get pointer
-	alicePtr, err := newKeyPtr(ctx, aliceKey, rt)
+	c.log.Debug("initial meter",
+		zap.Uint64("balance", rt.Meter().GetBalance()),
+	)
+
+	// generate alice keys
+	_, aliceKey, err := newKey()
 	if err != nil {
 		return err
 	}
 
 	// create counter for alice on program 1
-	_, err = rt.Call(ctx, "initialize_address", programIDPtr, alicePtr)
+	_, err = callWithParams(ctx, rt, "initialize_address", accountParams{ProgramID: programID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
 
-	result, err := rt.Call(ctx, "get_value", programIDPtr, alicePtr)
+	result, err := callWithParams(ctx, rt, "get_value", accountParams{ProgramID: programID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
@@ -96,13 +106,6 @@ func (c *Counter) Run(ctx context.Context) error {
 		zap.Uint64("alice", result[0]),
 	)
 
-	// initialize second runtime to create second counter program
-	rt2 := runtime.New(c.log, c.cfg2, c.imports)
-	err = rt2.Initialize(ctx, c.programBytes)
-	if err != nil {
-		return err
-	}
-
 	// simulate creating second program transaction
 	program2ID := ids.GenerateTestID()
 	err = storage.SetProgram(ctx, c.db, program2ID, c.programBytes)
@@ -110,33 +113,29 @@ func (c *Counter) Run(ctx context.Context) error {
 		return err
 	}
 
-	programID2Ptr, err := runtime.WriteBytes(rt2.Memory(), program2ID[:])
-	if err != nil {
-		return err
-	}
-
 	c.log.Debug("new counter program created",
 		zap.String("id", program2ID.String()),
 	)
 
-	// write alice's key to stack and get pointer
-	alicePtr2, err := newKeyPtr(ctx, aliceKey, rt2)
+	// initialize second runtime for the second counter program
+	rt2 := runtime.New(c.log, c.cfg2, program.BindImports(c.imports, program2ID))
+	err = rt2.Initialize(ctx, c.programBytes)
 	if err != nil {
 		return err
 	}
 
-	_, err = rt2.Call(ctx, "initialize_address", programID2Ptr, alicePtr2)
+	_, err = callWithParams(ctx, rt2, "initialize_address", accountParams{ProgramID: program2ID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
 
 	// increment alice's counter on program 2 by 10
-	_, err = rt2.Call(ctx, "inc", programID2Ptr, alicePtr2, 10)
+	_, err = callWithParams(ctx, rt2, "inc", amountParams{ProgramID: program2ID, Account: aliceKey, Amount: 10})
 	if err != nil {
 		return err
 	}
 
-	result, err = rt2.Call(ctx, "get_value", programID2Ptr, alicePtr2)
+	result, err = callWithParams(ctx, rt2, "get_value", accountParams{ProgramID: program2ID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
@@ -149,12 +148,12 @@ func (c *Counter) Run(ctx context.Context) error {
 	rt2.Stop()
 
 	// increment alice's counter on program 1
-	_, err = rt.Call(ctx, "inc", programIDPtr, alicePtr, 1)
+	_, err = callWithParams(ctx, rt, "inc", amountParams{ProgramID: programID, Account: aliceKey, Amount: 1})
 	if err != nil {
 		return err
 	}
 
-	result, err = rt.Call(ctx, "get_value", programIDPtr, alicePtr)
+	result, err = callWithParams(ctx, rt, "get_value", accountParams{ProgramID: programID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
@@ -163,24 +162,27 @@ func (c *Counter) Run(ctx context.Context) error {
 		zap.Uint64("alice", result[0]),
 	)
 
-	// write program id 2 to stack of program 1
-	programID2Ptr, err = runtime.WriteBytes(rt.Memory(), program2ID[:])
-	if err != nil {
-		return err
-	}
-
 	// set the max units to the current balance
 	maxUnits := uint64(20000)
-	caller := programIDPtr
-	target := programID2Ptr
 
 	// increment alice's counter on program 2 by 5 from program 1
-	_, err = rt.Call(ctx, "inc_external", caller, target, maxUnits, alicePtr, 5)
+	_, err = callWithParams(ctx, rt, "inc_external", externalAmountParams{
+		Caller:   programID,
+		Target:   program2ID,
+		MaxUnits: maxUnits,
+		Account:  aliceKey,
+		Amount:   5,
+	})
 	if err != nil {
 		return err
 	}
 
-	result, err = rt.Call(ctx, "get_value_external", caller, target, maxUnits, alicePtr)
+	result, err = callWithParams(ctx, rt, "get_value_external", externalParams{
+		Caller:   programID,
+		Target:   program2ID,
+		MaxUnits: maxUnits,
+		Account:  aliceKey,
+	})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,39 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package examples
+
+import (
+	"context"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+// Deploy simulates a create program transaction. [programBytes] are stored at
+// [programID]. If [storeArtifact] is true the program is also precompiled with
+// [cfg] and stored as its artifact, see storage.SetProgramArtifact, so
+// runtimes of the same engine load it with program.LoadProgram without
+// compiling it again. [cfg] is only used to precompile the program.
+func Deploy(
+	ctx context.Context,
+	db state.Mutable,
+	cfg *runtime.Config,
+	programID ids.ID,
+	programBytes []byte,
+	storeArtifact bool,
+) error {
+	err := storage.SetProgram(ctx, db, programID, programBytes)
+	if err != nil || !storeArtifact {
+		return err
+	}
+
+	artifact, err := runtime.PreCompileWasmBytes(programBytes, cfg)
+	if err != nil {
+		return err
+	}
+	return storage.SetProgramArtifact(ctx, db, programID, cfg.ArtifactVersion(), artifact)
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package examples
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/program"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+func TestDeploy(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+
+	newProgram := func(result int) []byte {
+		wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+		(module
+		  (func (export "get_guest") (result i32)
+		    i32.const %d
+		  )
+		)
+		`, result))
+		require.NoError(err)
+		return wasm
+	}
+	newConfig := func(strategy runtime.EngineCompileStrategy) *runtime.Config {
+		cfg, err := runtime.NewConfigBuilder(10000).
+			WithCompileStrategy(strategy).
+			Build()
+		require.NoError(err)
+		return cfg
+	}
+	artifactVersion := newConfig(runtime.CompileWasm).ArtifactVersion()
+
+	// without the flag the program is compiled when loaded
+	programID := ids.GenerateTestID()
+	require.NoError(Deploy(ctx, db, nil, programID, newProgram(1), false))
+	_, strategy, err := program.LoadProgram(ctx, db, programID[:], 0, artifactVersion)
+	require.NoError(err)
+	require.Equal(runtime.CompileWasm, strategy)
+
+	// with the flag the stored artifact is loaded
+	require.NoError(Deploy(ctx, db, newConfig(runtime.CompileWasm), programID, newProgram(1), true))
+	programBytes, strategy, err := program.LoadProgram(ctx, db, programID[:], 0, artifactVersion)
+	require.NoError(err)
+	require.Equal(runtime.PrecompiledWasm, strategy)
+
+	rt := runtime.New(log, newConfig(strategy), runtime.NoSupportedImports)
+	require.NoError(rt.Initialize(ctx, programBytes))
+	resp, err := rt.Call(ctx, "get")
+	require.NoError(err)
+	require.Equal(uint64(1), resp[0])
+	rt.Stop()
+
+	// storing new code removes the artifact compiled from the previous code
+	require.NoError(storage.SetProgram(ctx, db, programID, newProgram(2)))
+	_, strategy, err = program.LoadProgram(ctx, db, programID[:], 0, artifactVersion)
+	require.NoError(err)
+	require.Equal(runtime.CompileWasm, strategy)
+}
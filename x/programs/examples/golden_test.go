@@ -0,0 +1,221 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package examples
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/program"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/pstate"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+// go test -run ^TestGolden$ github.com/ava-labs/hypersdk/x/programs/examples -update
+var update = flag.Bool("update", false, "update the golden files of the example programs")
+
+var (
+	goldenProgramID  = ids.ID{1}
+	goldenProgram2ID = ids.ID{2}
+	goldenAlice      = ed25519.PublicKey{0xa}
+	goldenBob        = ed25519.PublicKey{0xb}
+)
+
+// goldenParam writes a param of a golden step to the runtime and returns its
+// value and description.
+type goldenParam func(runtime.Runtime) (uint64, string, error)
+
+func idParam(id ids.ID) goldenParam {
+	return func(rt runtime.Runtime) (uint64, string, error) {
+		ptr, err := runtime.WriteBytes(rt.Memory(), id[:])
+		return ptr, "id:" + id.String(), err
+	}
+}
+
+func keyParam(name string, key ed25519.PublicKey) goldenParam {
+	return func(rt runtime.Runtime) (uint64, string, error) {
+		ptr, err := runtime.WriteBytes(rt.Memory(), key[:])
+		return ptr, "key:" + name, err
+	}
+}
+
+func intParam(v uint64) goldenParam {
+	return func(runtime.Runtime) (uint64, string, error) {
+		return v, fmt.Sprint(v), nil
+	}
+}
+
+type goldenStep struct {
+	function string
+	params   []goldenParam
+}
+
+// goldenPlan is a canonical plan of calls to an example program.
+type goldenPlan struct {
+	name         string
+	programBytes []byte
+	// programs are stored before the plan starts
+	programs []ids.ID
+	steps    []goldenStep
+}
+
+func TestGolden(t *testing.T) {
+	plans := []goldenPlan{
+		{
+			name:         "counter",
+			programBytes: counterProgramBytes,
+			programs:     []ids.ID{goldenProgramID, goldenProgram2ID},
+			steps: []goldenStep{
+				{"initialize_address", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice)}},
+				{"get_value", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice)}},
+				{"initialize_address", []goldenParam{idParam(goldenProgram2ID), keyParam("alice", goldenAlice)}},
+				{"inc", []goldenParam{idParam(goldenProgram2ID), keyParam("alice", goldenAlice), intParam(10)}},
+				{"get_value", []goldenParam{idParam(goldenProgram2ID), keyParam("alice", goldenAlice)}},
+				{"inc", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice), intParam(1)}},
+				{"get_value", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice)}},
+				{"inc_external", []goldenParam{idParam(goldenProgramID), idParam(goldenProgram2ID), intParam(20000), keyParam("alice", goldenAlice), intParam(5)}},
+				{"get_value_external", []goldenParam{idParam(goldenProgramID), idParam(goldenProgram2ID), intParam(20000), keyParam("alice", goldenAlice)}},
+			},
+		},
+		{
+			name:         "token",
+			programBytes: tokenProgramBytes,
+			programs:     []ids.ID{goldenProgramID},
+			steps: []goldenStep{
+				{"init", []goldenParam{idParam(goldenProgramID)}},
+				{"get_total_supply", []goldenParam{idParam(goldenProgramID)}},
+				{"get_balance", []goldenParam{idParam(goldenProgramID), keyParam("bob", goldenBob)}},
+				{"mint_to", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice), intParam(1000)}},
+				{"get_balance", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice)}},
+				{"transfer", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice), keyParam("bob", goldenBob), intParam(50)}},
+				{"transfer", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice), keyParam("bob", goldenBob), intParam(1)}},
+				{"get_balance", []goldenParam{idParam(goldenProgramID), keyParam("alice", goldenAlice)}},
+				{"get_balance", []goldenParam{idParam(goldenProgramID), keyParam("bob", goldenBob)}},
+			},
+		},
+	}
+	for _, plan := range plans {
+		t.Run(plan.name, func(t *testing.T) {
+			require := require.New(t)
+
+			got := runGoldenPlan(t, plan)
+			path := filepath.Join("testdata", plan.name+".golden")
+			if *update {
+				require.NoError(os.WriteFile(path, []byte(got), 0o600))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(err, "run with -update to create the golden file")
+			require.Equal(string(want), got, "run with -update to accept the changes")
+		})
+	}
+}
+
+// runGoldenPlan runs [plan] and returns a snapshot of the result, units spent
+// and state changes of each step.
+func runGoldenPlan(t *testing.T, plan goldenPlan) string {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	maxUnits := uint64(1_000_000)
+
+	for _, id := range plan.programs {
+		require.NoError(storage.SetProgram(ctx, db, id, plan.programBytes))
+	}
+
+	supported := runtime.NewSupportedImports()
+	supported.Register(pstate.Name, func() runtime.Import {
+		return pstate.New(log, db)
+	})
+	supported.Register(program.Name, func() runtime.Import {
+		return program.New(log, db)
+	})
+	cfg, err := runtime.NewConfigBuilder(maxUnits).
+		WithLimitMaxMemory(18 * runtime.MemoryPageSize). // 18 pages
+		Build()
+	require.NoError(err)
+	rt := runtime.New(log, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, plan.programBytes))
+	defer rt.Stop()
+
+	var out strings.Builder
+	prev := dumpState(t, db)
+	for i, step := range plan.steps {
+		params := make([]uint64, len(step.params))
+		descs := make([]string, len(step.params))
+		for j, param := range step.params {
+			params[j], descs[j], err = param(rt)
+			require.NoError(err)
+		}
+
+		balance := rt.Meter().GetBalance()
+		resp, err := rt.Call(ctx, step.function, params...)
+		result := "error"
+		if err == nil {
+			result = fmt.Sprint(resp)
+		}
+		fmt.Fprintf(&out, "%d: %s(%s) = %s units=%d\n",
+			i, step.function, strings.Join(descs, ", "), result, balance-rt.Meter().GetBalance())
+
+		next := dumpState(t, db)
+		writeStateDiff(&out, prev, next)
+		prev = next
+	}
+	return out.String()
+}
+
+// dumpState returns the hex encoded key values of [db].
+func dumpState(t *testing.T, db interface{ NewIterator() database.Iterator }) map[string]string {
+	iter := db.NewIterator()
+	defer iter.Release()
+
+	state := map[string]string{}
+	for iter.Next() {
+		state[hex.EncodeToString(iter.Key())] = hex.EncodeToString(iter.Value())
+	}
+	require.NoError(t, iter.Error())
+	return state
+}
+
+// writeStateDiff writes the keys added (+), changed (~) and removed (-) from
+// [prev] to [next] ordered by key.
+func writeStateDiff(out *strings.Builder, prev, next map[string]string) {
+	keys := make([]string, 0, len(prev)+len(next))
+	for k := range prev {
+		keys = append(keys, k)
+	}
+	for k := range next {
+		if _, ok := prev[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		before, hadBefore := prev[k]
+		after, hasAfter := next[k]
+		switch {
+		case !hadBefore:
+			fmt.Fprintf(out, "  + %s = %s\n", k, after)
+		case !hasAfter:
+			fmt.Fprintf(out, "  - %s\n", k)
+		case before != after:
+			fmt.Fprintf(out, "  ~ %s = %s\n", k, after)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package context
+
+import (
+	"fmt"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "context"
+
+	programIDCost       = 100
+	programCodeHashCost = 100
+	actorCost           = 100
+)
+
+var _ runtime.Import = &Import{}
+
+// New returns a context module which exposes the ID and code hash of the
+// program being executed, so programs can derive namespaced keys and verify
+// their own identity.
+func New(log logging.Logger, programID ids.ID, codeHash ids.ID) *Import {
+	return &Import{
+		log:       log,
+		programID: programID,
+		codeHash:  codeHash,
+	}
+}
+
+// WithActor sets the address of the account which signed the transaction
+// executing the program, so programs can authorize operations on behalf of
+// the actor. Without an actor, actor returns runtime.StatusNotFound.
+func (i *Import) WithActor(actor ed25519.PublicKey) *Import {
+	i.actor = &actor
+	return i
+}
+
+// ForProgram returns an unregistered copy of the module for the program
+// [programID] with the code hash [codeHash], executed by the same actor.
+func (i *Import) ForProgram(programID ids.ID, codeHash ids.ID) *Import {
+	c := New(i.log, programID, codeHash)
+	c.actor = i.actor
+	return c
+}
+
+type Import struct {
+	log        logging.Logger
+	programID  ids.ID
+	codeHash   ids.ID
+	actor      *ed25519.PublicKey
+	registered bool
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.registered = true
+
+	if err := link.MeteredFuncWrap(meter, programIDCost, Name, "program_id", i.programIDFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, programCodeHashCost, Name, "program_code_hash", i.programCodeHashFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, actorCost, Name, "actor", i.actorFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// programIDFn writes the ID of the program to [outPtr], which must have room
+// for ids.IDLen bytes. Returns 0 on success.
+func (i *Import) programIDFn(caller *wasmtime.Caller, outPtr int32) int32 {
+	return i.write(caller, outPtr, i.programID[:])
+}
+
+// programCodeHashFn writes the hash of the code of the program to [outPtr],
+// which must have room for ids.IDLen bytes. Returns 0 on success.
+func (i *Import) programCodeHashFn(caller *wasmtime.Caller, outPtr int32) int32 {
+	return i.write(caller, outPtr, i.codeHash[:])
+}
+
+// actorFn writes the address of the actor to [outPtr], which must have room
+// for ed25519.PublicKeyLen bytes. Returns 0 on success or
+// runtime.StatusNotFound if the module has no actor.
+func (i *Import) actorFn(caller *wasmtime.Caller, outPtr int32) int32 {
+	if i.actor == nil {
+		return runtime.StatusNotFound
+	}
+	return i.write(caller, outPtr, i.actor[:])
+}
+
+func (i *Import) write(caller *wasmtime.Caller, outPtr int32, b []byte) int32 {
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	if err := memory.Write(uint64(outPtr), b); err != nil {
+		i.log.Error("failed to write to memory",
+			zap.Error(err),
+		)
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+func TestContext(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "context" "program_id" (func $program_id (param i32) (result i32)))
+	  (import "context" "program_code_hash" (func $program_code_hash (param i32) (result i32)))
+	  (import "context" "actor" (func $actor (param i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "program_id_guest") (param i32) (result i32)
+	    (call $program_id (local.get 0))
+	  )
+	  (func (export "program_code_hash_guest") (param i32) (result i32)
+	    (call $program_code_hash (local.get 0))
+	  )
+	  (func (export "actor_guest") (param i32) (result i32)
+	    (call $actor (local.get 0))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	programID := ids.GenerateTestID()
+	codeHash := ids.GenerateTestID()
+	actor := ed25519.PublicKey{1}
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{}, programID, codeHash).WithActor(actor)
+	})
+	cfg, err := runtime.NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	for fn, want := range map[string][32]byte{
+		"program_id":        programID,
+		"program_code_hash": codeHash,
+		"actor":             actor,
+	} {
+		resp, err := rt.Call(ctx, fn, 64)
+		require.NoError(err)
+		require.Equal(int32(0), int32(resp[0]))
+		got, err := rt.Memory().Range(64, ids.IDLen)
+		require.NoError(err)
+		require.Equal(want[:], got)
+
+		// out of bounds
+		resp, err = rt.Call(ctx, fn, runtime.MemoryPageSize-1)
+		require.NoError(err)
+		require.Equal(int32(-1), int32(resp[0]))
+	}
+
+	// without an actor
+	supported = runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{}, programID, codeHash)
+	})
+	cfg, err = runtime.NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	rt = runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+	resp, err := rt.Call(ctx, "actor", 64)
+	require.NoError(err)
+	require.Equal(int32(runtime.StatusNotFound), int32(resp[0]))
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "crypto"
+
+	verifyBLSCost = 10000
+)
+
+var _ runtime.Import = &Import{}
+
+// New returns a crypto module which exposes signature verification to the
+// guest.
+func New(log logging.Logger) runtime.Import {
+	return &Import{log: log}
+}
+
+type Import struct {
+	log        logging.Logger
+	meter      runtime.Meter
+	registered bool
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.meter = meter
+	i.registered = true
+
+	if err := link.MeteredFuncWrap(meter, verifyBLSCost, Name, "verify_bls", i.verifyBLSFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// verifyBLSFn verifies a compressed BLS signature over the message for the
+// given compressed public key. Returns 1 if the signature is valid, 0 if
+// invalid and -1 if the inputs could not be read or parsed.
+func (i *Import) verifyBLSFn(
+	caller *wasmtime.Caller,
+	pubKeyPtr int32,
+	sigPtr int32,
+	msgPtr int32,
+	msgLength int32,
+) int32 {
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	pubKeyBytes, err := memory.Range(uint64(pubKeyPtr), bls.PublicKeyLen)
+	if err != nil {
+		i.log.Error("failed to read public key from memory",
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	sigBytes, err := memory.Range(uint64(sigPtr), bls.SignatureLen)
+	if err != nil {
+		i.log.Error("failed to read signature from memory",
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	msgBytes, err := memory.Range(uint64(msgPtr), uint64(msgLength))
+	if err != nil {
+		i.log.Error("failed to read message from memory",
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	pubKey, err := bls.PublicKeyFromBytes(pubKeyBytes)
+	if err != nil {
+		i.log.Error("failed to parse public key",
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	sig, err := bls.SignatureFromBytes(sigBytes)
+	if err != nil {
+		i.log.Error("failed to parse signature",
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	if !bls.Verify(pubKey, sig, msgBytes) {
+		return 0
+	}
+
+	return 1
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/utils/crypto/bls"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+func TestVerifyBLS(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "crypto" "verify_bls" (func $verify_bls (param i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "verify_guest") (param $pk i32) (param $sig i32) (param $msg i32) (param $len i32) (result i32)
+	    (call $verify_bls (local.get $pk) (local.get $sig) (local.get $msg) (local.get $len))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{})
+	})
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+
+	sk, err := bls.NewSecretKey()
+	require.NoError(err)
+	msg := []byte("validator")
+	pk := bls.PublicKeyToBytes(bls.PublicFromSecretKey(sk))
+	sig := bls.SignatureToBytes(bls.Sign(sk, msg))
+
+	pkPtr := uint64(0)
+	sigPtr := pkPtr + bls.PublicKeyLen
+	msgPtr := sigPtr + bls.SignatureLen
+	require.NoError(rt.Memory().Write(pkPtr, pk))
+	require.NoError(rt.Memory().Write(sigPtr, sig))
+	require.NoError(rt.Memory().Write(msgPtr, msg))
+
+	resp, err := rt.Call(ctx, "verify", pkPtr, sigPtr, msgPtr, uint64(len(msg)))
+	require.NoError(err)
+	require.Equal(uint64(1), resp[0])
+
+	// signature over a different message
+	require.NoError(rt.Memory().Write(msgPtr, []byte("Validator")))
+	resp, err = rt.Call(ctx, "verify", pkPtr, sigPtr, msgPtr, uint64(len(msg)))
+	require.NoError(err)
+	require.Equal(uint64(0), resp[0])
+
+	// malformed public key
+	require.NoError(rt.Memory().Write(pkPtr, make([]byte, bls.PublicKeyLen)))
+	resp, err = rt.Call(ctx, "verify", pkPtr, sigPtr, msgPtr, uint64(len(msg)))
+	require.NoError(err)
+	require.Equal(uint64(0xffffffffffffffff), resp[0])
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "encoding"
+
+	encodeCost = 100
+	decodeCost = 100
+	// units charged per byte of input in addition to the cost of the call.
+	perByteCost = 1
+)
+
+var _ runtime.Import = &Import{}
+
+// New returns an encoding module which exposes hex and base64 encoding to
+// the guest, so programs do not need to bundle encoding libraries.
+func New(log logging.Logger) runtime.Import {
+	return &Import{log: log}
+}
+
+type Import struct {
+	log        logging.Logger
+	meter      runtime.Meter
+	registered bool
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.meter = meter
+	i.registered = true
+
+	if err := link.MeteredFuncWrap(meter, encodeCost, Name, "hex_encode", i.hexEncodeFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, decodeCost, Name, "hex_decode", i.hexDecodeFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, encodeCost, Name, "base64_encode", i.base64EncodeFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, decodeCost, Name, "base64_decode", i.base64DecodeFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// hexEncodeFn writes the hex encoding of the input to [outPtr], which must
+// have room for twice the input length. Returns the length written.
+func (i *Import) hexEncodeFn(caller *wasmtime.Caller, inPtr, inLen, outPtr int32) (int32, *wasmtime.Trap) {
+	return i.convert(caller, inPtr, inLen, outPtr, func(in []byte) ([]byte, error) {
+		return []byte(hex.EncodeToString(in)), nil
+	})
+}
+
+// hexDecodeFn writes the bytes of the hex encoded input to [outPtr], which
+// must have room for half the input length. Returns the length written.
+func (i *Import) hexDecodeFn(caller *wasmtime.Caller, inPtr, inLen, outPtr int32) (int32, *wasmtime.Trap) {
+	return i.convert(caller, inPtr, inLen, outPtr, func(in []byte) ([]byte, error) {
+		return hex.DecodeString(string(in))
+	})
+}
+
+// base64EncodeFn writes the standard padded base64 encoding of the input to
+// [outPtr], which must have room for 4 bytes per started 3 bytes of input.
+// Returns the length written.
+func (i *Import) base64EncodeFn(caller *wasmtime.Caller, inPtr, inLen, outPtr int32) (int32, *wasmtime.Trap) {
+	return i.convert(caller, inPtr, inLen, outPtr, func(in []byte) ([]byte, error) {
+		return []byte(base64.StdEncoding.EncodeToString(in)), nil
+	})
+}
+
+// base64DecodeFn writes the bytes of the standard padded base64 encoded
+// input to [outPtr], which must have room for 3 bytes per 4 bytes of input.
+// Returns the length written.
+func (i *Import) base64DecodeFn(caller *wasmtime.Caller, inPtr, inLen, outPtr int32) (int32, *wasmtime.Trap) {
+	return i.convert(caller, inPtr, inLen, outPtr, func(in []byte) ([]byte, error) {
+		return base64.StdEncoding.DecodeString(string(in))
+	})
+}
+
+// convert charges the per byte cost of the input, converts it with [fn] and
+// writes the output to [outPtr]. Returns the length of the output or -1 if
+// the input could not be converted or memory could not be accessed. Traps if
+// the meter has insufficient units.
+func (i *Import) convert(
+	caller *wasmtime.Caller,
+	inPtr, inLen, outPtr int32,
+	fn func([]byte) ([]byte, error),
+) (int32, *wasmtime.Trap) {
+	if inLen < 0 {
+		i.log.Error("invalid input length",
+			zap.Int32("length", inLen),
+		)
+		return -1, nil
+	}
+	if _, err := i.meter.Spend(perByteCost * uint64(inLen)); err != nil {
+		return -1, wasmtime.NewTrap(fmt.Sprintf("%s: %s", err, Name))
+	}
+
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	in, err := memory.Range(uint64(inPtr), uint64(inLen))
+	if err != nil {
+		i.log.Error("failed to read input from memory",
+			zap.Error(err),
+		)
+		return -1, nil
+	}
+
+	out, err := fn(in)
+	if err != nil {
+		i.log.Debug("failed to convert input",
+			zap.Error(err),
+		)
+		return -1, nil
+	}
+
+	err = memory.Write(uint64(outPtr), out)
+	if err != nil {
+		i.log.Error("failed to write output to memory",
+			zap.Error(err),
+		)
+		return -1, nil
+	}
+
+	return int32(len(out)), nil
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package encoding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+// outPtr is the offset the output of each call is written to.
+const outPtr = 1024
+
+func TestEncoding(t *testing.T) {
+	fns := []string{"hex_encode", "hex_decode", "base64_encode", "base64_decode"}
+	var imports, exports string
+	for _, fn := range fns {
+		imports += fmt.Sprintf(`(import "encoding" "%s" (func $%s (param i32 i32 i32) (result i32)))`, fn, fn)
+		exports += fmt.Sprintf(`
+		  (func (export "%s_guest") (param i32 i32) (result i32)
+		    (call $%s (local.get 0) (local.get 1) (i32.const %d))
+		  )`, fn, fn, outPtr)
+	}
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  %s
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  %s
+	)
+	`, imports, exports))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		fn      string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "hex encode", fn: "hex_encode", in: "\x01\xab", want: "01ab"},
+		{name: "hex decode", fn: "hex_decode", in: "01AB", want: "\x01\xab"},
+		{name: "hex decode invalid", fn: "hex_decode", in: "0g", wantErr: true},
+		{name: "hex decode odd length", fn: "hex_decode", in: "012", wantErr: true},
+		{name: "base64 encode", fn: "base64_encode", in: "hello", want: "aGVsbG8="},
+		{name: "base64 decode", fn: "base64_decode", in: "aGVsbG8=", want: "hello"},
+		{name: "base64 decode invalid", fn: "base64_decode", in: "aGVsbG8", wantErr: true},
+		{name: "empty", fn: "hex_encode", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			rt := newRuntime(t, wasm, 10000)
+			defer rt.Stop()
+
+			mem := rt.Memory()
+			require.NoError(mem.Write(0, []byte(tt.in)))
+			balance := rt.Meter().GetBalance()
+			resp, err := rt.Call(context.Background(), tt.fn, 0, uint64(len(tt.in)))
+			require.NoError(err)
+			// the input is charged per byte
+			require.LessOrEqual(balance-rt.Meter().GetBalance(), uint64(decodeCost+perByteCost*len(tt.in)+10))
+			if tt.wantErr {
+				require.Equal(int32(-1), int32(resp[0]))
+				return
+			}
+			require.Equal(int32(len(tt.want)), int32(resp[0]))
+			out, err := mem.Range(outPtr, uint64(len(tt.want)))
+			require.NoError(err)
+			require.Equal(tt.want, string(out))
+		})
+	}
+
+	t.Run("invalid memory", func(t *testing.T) {
+		require := require.New(t)
+		rt := newRuntime(t, wasm, 10000)
+		defer rt.Stop()
+
+		resp, err := rt.Call(context.Background(), "hex_encode", runtime.MemoryPageSize, 1)
+		require.NoError(err)
+		require.Equal(int32(-1), int32(resp[0]))
+	})
+
+	t.Run("insufficient units", func(t *testing.T) {
+		require := require.New(t)
+		rt := newRuntime(t, wasm, 1000)
+		defer rt.Stop()
+
+		_, err := rt.Call(context.Background(), "hex_encode", 0, 2000)
+		require.ErrorContains(err, runtime.ErrInsufficientUnits.Error())
+	})
+}
+
+func newRuntime(t *testing.T, wasm []byte, maxUnits uint64) runtime.Runtime {
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{})
+	})
+	cfg, err := runtime.NewConfigBuilder(maxUnits).Build()
+	require.NoError(t, err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(t, rt.Initialize(context.Background(), wasm))
+	return rt
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package meter
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "meter"
+
+	remainingFuelCost = 10
+)
+
+var _ runtime.Import = &Import{}
+
+// New returns a meter module which exposes the remaining units of the call
+// to the guest, so programs can return partial results instead of running
+// out of units mid-write.
+func New(log logging.Logger) runtime.Import {
+	return &Import{log: log}
+}
+
+type Import struct {
+	log        logging.Logger
+	meter      runtime.Meter
+	registered bool
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.meter = meter
+	i.registered = true
+
+	return link.MeteredFuncWrap(meter, remainingFuelCost, Name, "remaining_fuel", i.remainingFuelFn)
+}
+
+// remainingFuelFn returns the balance of the meter after charging for this
+// call.
+func (i *Import) remainingFuelFn() int64 {
+	return int64(i.meter.GetBalance())
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package meter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+func TestRemainingFuel(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// writes the remaining fuel while at least [min] remains, returns the
+	// number of writes.
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "meter" "remaining_fuel" (func $remaining_fuel (result i64)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "remaining_fuel_guest") (result i64)
+	    (call $remaining_fuel)
+	  )
+	  (func (export "fill_guest") (param $min i64) (result i32)
+	    (local $n i32)
+	    (block $done
+	      (loop $l
+	        (br_if $done (i64.lt_u (call $remaining_fuel) (local.get $min)))
+	        (i64.store (i32.mul (local.get $n) (i32.const 8)) (i64.const 1))
+	        (local.set $n (i32.add (local.get $n) (i32.const 1)))
+	        (br $l)
+	      )
+	    )
+	    (local.get $n)
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{})
+	})
+	maxUnits := uint64(10000)
+	cfg, err := runtime.NewConfigBuilder(maxUnits).Build()
+	require.NoError(err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	// the value matches the host meter
+	resp, err := rt.Call(ctx, "remaining_fuel")
+	require.NoError(err)
+	require.Equal(rt.Meter().GetBalance(), resp[0])
+	require.Less(resp[0], maxUnits-remainingFuelCost)
+
+	// the guest stops before running out of units
+	min := uint64(1000)
+	resp, err = rt.Call(ctx, "fill", min)
+	require.NoError(err)
+	require.Positive(resp[0])
+	require.GreaterOrEqual(rt.Meter().GetBalance(), min-100)
+}
@@ -5,28 +5,59 @@ package program
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 
 	"go.uber.org/zap"
 
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/bytecodealliance/wasmtime-go/v13"
 
 	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/consts"
 	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+	pcontext "github.com/ava-labs/hypersdk/x/programs/examples/imports/context"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/pstate"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/token"
 	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
 	"github.com/ava-labs/hypersdk/x/programs/runtime"
 )
 
-const Name = "program"
+const (
+	Name = "program"
+
+	callProgramCost         = 1000
+	callProgramReadonlyCost = 1000
+	callProgramVersionCost  = 1000
+)
+
+var (
+	ErrInvalidArgSize         = errs.New(errs.ErrValidation, "invalid argument size")
+	ErrMissingReadonlyImports = errs.New(errs.ErrValidation, "missing readonly imports")
+	ErrMissingRuntime         = errs.New(errs.ErrValidation, "import not registered by a runtime")
+)
+
+// StateImport is implemented by imports backed by state. The imports of a
+// program invoked by call_program are set to a pending view of the state of
+// this module, which is only committed once the call succeeds, so an invoked
+// program can not commit state on its own.
+type StateImport interface {
+	SetState(state.Mutable)
+}
 
 type Import struct {
-	db         state.Mutable
-	log        logging.Logger
-	imports    runtime.SupportedImports
-	meter      runtime.Meter
-	registered bool
+	db              state.Mutable
+	log             logging.Logger
+	imports         runtime.SupportedImports
+	readonlyImports runtime.SupportedImports
+	meter           runtime.Meter
+	link            runtime.Link
+	registered      bool
 }
 
 // New returns a new program invoke host module which can perform program to program calls.
@@ -37,6 +68,19 @@ func New(log logging.Logger, db state.Mutable) *Import {
 	}
 }
 
+// WithReadonlyImports sets the imports available to programs invoked by
+// call_program_readonly. The state of these imports must be a read-only view,
+// see storage.NewReadOnlyState, so the invoked program can not mutate state.
+func (i *Import) WithReadonlyImports(imports runtime.SupportedImports) *Import {
+	i.readonlyImports = imports
+	return i
+}
+
+// SetState sets the state of the module, see StateImport.
+func (i *Import) SetState(mu state.Mutable) {
+	i.db = mu
+}
+
 func (i *Import) Name() string {
 	return Name
 }
@@ -47,15 +91,24 @@ func (i *Import) Register(link runtime.Link, meter runtime.Meter, imports runtim
 	}
 	i.imports = imports
 	i.meter = meter
+	i.link = link
 
-	if err := link.FuncWrap(Name, "call_program", i.callProgramFn); err != nil {
+	if err := link.MeteredFuncWrap(meter, callProgramCost, Name, "call_program", i.callProgramFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, callProgramReadonlyCost, Name, "call_program_readonly", i.callProgramReadonlyFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, callProgramVersionCost, Name, "call_program_version", i.callProgramVersionFn); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// callProgramFn makes a call to an entry function of a program in the context of another program's ID.
+// callProgramFn makes a call to an entry function of a program in the context
+// of another program's ID. The result of the entry function is written to
+// [resultPtr] as a little endian i64, see callProgram for the returned status.
 func (i *Import) callProgramFn(
 	caller *wasmtime.Caller,
 	callerIDPtr int64,
@@ -64,19 +117,107 @@ func (i *Import) callProgramFn(
 	functionPtr,
 	functionLen,
 	argsPtr,
-	argsLen int32,
-) int64 {
-	ctx, cancel := context.WithCancel(context.Background())
+	argsLen,
+	resultPtr int32,
+) int32 {
+	return i.callProgram(caller, i.imports, true, programIDPtr, 0, maxUnits, functionPtr, functionLen, argsPtr, argsLen, resultPtr)
+}
+
+// callProgramVersionFn makes a call to an entry function of [version] of a
+// program rather than its latest version, see storage.SetProgramVersion.
+// Returns runtime.StatusNotFound if the version does not exist.
+func (i *Import) callProgramVersionFn(
+	caller *wasmtime.Caller,
+	callerIDPtr int64,
+	programIDPtr int64,
+	version int64,
+	maxUnits int64,
+	functionPtr,
+	functionLen,
+	argsPtr,
+	argsLen,
+	resultPtr int32,
+) int32 {
+	if version <= 0 || version > math.MaxUint32 {
+		i.log.Error("failed to call program",
+			zap.Error(storage.ErrInvalidVersion),
+			zap.Int64("version", version),
+		)
+		return runtime.StatusError
+	}
+	return i.callProgram(caller, i.imports, true, programIDPtr, uint32(version), maxUnits, functionPtr, functionLen, argsPtr, argsLen, resultPtr)
+}
+
+// callProgramReadonlyFn makes a call to an entry function of a program with
+// the readonly imports. Any write of the invoked program to state fails with
+// runtime.StatusPermissionDenied.
+func (i *Import) callProgramReadonlyFn(
+	caller *wasmtime.Caller,
+	callerIDPtr int64,
+	programIDPtr int64,
+	maxUnits int64,
+	functionPtr,
+	functionLen,
+	argsPtr,
+	argsLen,
+	resultPtr int32,
+) int32 {
+	if i.readonlyImports == nil {
+		i.log.Error("failed to call program",
+			zap.Error(ErrMissingReadonlyImports),
+		)
+		return runtime.StatusError
+	}
+	return i.callProgram(caller, i.readonlyImports, false, programIDPtr, 0, maxUnits, functionPtr, functionLen, argsPtr, argsLen, resultPtr)
+}
+
+// callProgram invokes the entry function of a program with [imports]
+// transferring at most [maxUnits] from the caller and writes its result, or 0
+// for an entry function without results, to [resultPtr]. An entry function
+// may return a (status_code, result_ptr) pair if multi-value is enabled for
+// the engine of the caller, see runtime.DecodeStatusResult. Then result_ptr is
+// written if the status code is runtime.StatusOK and the call fails
+// otherwise. Returns 0 on
+// success, runtime.StatusNotFound if the program does not exist or
+// runtime.StatusError if the call fails. The result is only written on
+// success, so it can not be mistaken for a status.
+//
+// If [buffered] is true the state imports of the invoked program write to a
+// pending view of state, which is committed only if the call succeeds. If
+// [version] is not zero that version of the program is invoked instead of
+// the latest.
+func (i *Import) callProgram(
+	caller *wasmtime.Caller,
+	imports runtime.SupportedImports,
+	buffered bool,
+	programIDPtr int64,
+	version uint32,
+	maxUnits int64,
+	functionPtr,
+	functionLen,
+	argsPtr,
+	argsLen,
+	resultPtr int32,
+) int32 {
+	// the call is cancelled along with the call of the caller
+	ctx, cancel := context.WithCancel(i.link.Context())
 	defer cancel()
 	memory := runtime.NewMemory(runtime.NewExportClient(caller))
 
+	if maxUnits < 0 {
+		i.log.Error("invalid max units",
+			zap.Int64("maxUnits", maxUnits),
+		)
+		return runtime.StatusError
+	}
+
 	// get the entry function for invoke to call.
-	functionBytes, err := memory.Range(uint64(functionPtr), uint64(functionLen))
+	functionBytes, err := runtime.ReadResultBytes(memory, uint64(functionPtr), uint64(functionLen), i.link.MaxResultSize())
 	if err != nil {
 		i.log.Error("failed to read function name from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	programIDBytes, err := memory.Range(uint64(programIDPtr), uint64(ids.IDLen))
@@ -84,37 +225,91 @@ func (i *Import) callProgramFn(
 		i.log.Error("failed to read id from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	// get the program bytes from storage
-	programWasmBytes, err := getProgramWasmBytes(i.log, i.db, programIDBytes)
+	programBytes, strategy, err := LoadProgram(ctx, i.db, programIDBytes, version, i.link.ArtifactVersion())
+	if errors.Is(err, database.ErrNotFound) {
+		i.log.Debug("key does not exist",
+			zap.Binary("id", programIDBytes),
+		)
+		return runtime.StatusNotFound
+	}
 	if err != nil {
 		i.log.Error("failed to get program bytes from storage",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
+	}
+
+	var pending *storage.PendingState
+	if buffered {
+		pending = storage.NewPendingState(i.db)
+	}
+	imports, err = i.calleeImports(ctx, imports, programIDBytes, version, pending)
+	if err != nil {
+		i.log.Error("failed to get program code hash from storage",
+			zap.Error(err),
+		)
+		return runtime.StatusError
 	}
 
 	// initialize a new runtime config with zero balance
 	cfg, err := runtime.NewConfigBuilder(runtime.NoUnits).
 		WithLimitMaxMemory(18 * runtime.MemoryPageSize). // 18 pages
+		WithCompileStrategy(strategy).
 		Build()
 	if err != nil {
 		i.log.Error("failed to create runtime config",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
-	// create a new runtime for the program to be invoked
-	rt := runtime.New(i.log, cfg, i.imports)
-	err = rt.Initialize(context.Background(), programWasmBytes)
+	// reject args exceeding the params of the callee before instantiation,
+	// the program id is written along with the args.
+	if argsLen < 0 || uint64(argsLen)+ids.IDLen > cfg.MaxParamsSize() {
+		i.log.Error("failed to call program",
+			zap.Error(runtime.ErrParamsTooLarge),
+			zap.Int32("argsLen", argsLen),
+			zap.Uint64("max", cfg.MaxParamsSize()),
+		)
+		return runtime.StatusError
+	}
+
+	parent := i.link.Runtime()
+	if parent == nil {
+		i.log.Error("failed to create runtime",
+			zap.Error(ErrMissingRuntime),
+		)
+		return runtime.StatusError
+	}
+	// create a child runtime for the program to be invoked, which shares the
+	// engine of the caller so stopping the caller interrupts the call.
+	rt, err := parent.NewChildRuntime(cfg, imports)
+	if err != nil {
+		i.log.Error("failed to create runtime",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+	// the remaining balance is returned to the caller regardless of the
+	// outcome of the call.
+	defer func() {
+		rt.Stop()
+		if err := runtime.ReturnUnits(rt, parent); err != nil {
+			i.log.Error("failed to return units to caller",
+				zap.Error(err),
+			)
+		}
+	}()
+	err = rt.Initialize(ctx, programBytes)
 	if err != nil {
 		i.log.Error("failed to initialize runtime",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	// transfer the units from the caller to the new runtime before any calls are made.
@@ -125,33 +320,44 @@ func (i *Import) callProgramFn(
 			zap.Int64("required", maxUnits),
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
+	// the params of the call are freed once it returns, the guest only
+	// copies them.
+	scratch := runtime.NewScratch(rt.Memory())
+	defer func() {
+		if err := scratch.Free(); err != nil {
+			i.log.Debug("failed to free call params",
+				zap.Error(err),
+			)
+		}
+	}()
+
 	// write the program id to the new runtime memory
-	ptr, err := runtime.WriteBytes(rt.Memory(), programIDBytes)
+	ptr, err := scratch.WriteBytes(programIDBytes)
 	if err != nil {
 		i.log.Error("failed to write program id to memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
-	argsBytes, err := memory.Range(uint64(argsPtr), uint64(argsLen))
+	argsBytes, err := runtime.ReadResultBytes(memory, uint64(argsPtr), uint64(argsLen), i.link.MaxResultSize())
 	if err != nil {
 		i.log.Error("failed to read program args name from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	// sync args to new runtime and return arguments to the invoke call
-	params, err := getCallArgs(ctx, rt, argsBytes, ptr)
+	params, err := getCallArgs(scratch, argsBytes, ptr)
 	if err != nil {
 		i.log.Error("failed to unmarshal call arguments",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	function := string(functionBytes)
@@ -160,39 +366,155 @@ func (i *Import) callProgramFn(
 		i.log.Error("failed to call entry function",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
-	// stop the runtime to prevent further execution
-	rt.Stop()
+	// entry functions returning a (status_code, result_ptr) pair return the
+	// result pointer or fail with the program error.
+	if len(res) > 1 {
+		ptr, err := runtime.DecodeStatusResult(rt.Memory(), res, cfg.MaxResultSize())
+		var programErr *runtime.ProgramError
+		if errors.As(err, &programErr) {
+			i.log.Error("entry function returned an error",
+				zap.Uint32("code", programErr.Code),
+				zap.String("message", programErr.Message),
+			)
+			return runtime.StatusError
+		}
+		if err != nil {
+			i.log.Error("failed to decode entry function result",
+				zap.Error(err),
+			)
+			return runtime.StatusError
+		}
+		res = []uint64{ptr}
+	}
 
-	// transfer remaining balance back to parent runtime
-	_, err = rt.Meter().TransferUnits(i.meter, rt.Meter().GetBalance())
-	if err != nil {
-		i.log.Error("failed to transfer remaining balance to caller",
+	// entry functions without results return 0
+	result := make([]byte, consts.Uint64Len)
+	if len(res) > 0 {
+		binary.LittleEndian.PutUint64(result, res[0])
+	}
+	if err := memory.Write(uint64(resultPtr), result); err != nil {
+		i.log.Error("failed to write result to memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
+	}
+	if pending != nil {
+		if err := pending.Commit(ctx); err != nil {
+			i.log.Error("failed to commit state",
+				zap.Error(err),
+			)
+			return runtime.StatusError
+		}
+	}
+	return 0
+}
+
+// calleeImports returns [imports] bound to the invoked program [idBytes], see
+// BindImports, with the context import bound to its [version], or its latest
+// version if zero, if supported. If [pending] is not nil the state imports
+// are set to it, see StateImport.
+func (i *Import) calleeImports(ctx context.Context, imports runtime.SupportedImports, idBytes []byte, version uint32, pending *storage.PendingState) (runtime.SupportedImports, error) {
+	id, err := ids.ToID(idBytes)
+	if err != nil {
+		return nil, err
 	}
 
-	return int64(res[0])
+	callee := BindImports(imports, id)
+	if contextFn, ok := imports[pcontext.Name]; ok {
+		codeHash, _, err := storage.GetProgramCodeHash(ctx, i.db, id)
+		if version != 0 {
+			codeHash, _, err = storage.GetProgramVersionCodeHash(ctx, i.db, id, version)
+		}
+		if err != nil {
+			return nil, err
+		}
+		callee[pcontext.Name] = func() runtime.Import {
+			imp := contextFn()
+			if c, ok := imp.(*pcontext.Import); ok {
+				return c.ForProgram(id, codeHash)
+			}
+			return pcontext.New(i.log, id, codeHash)
+		}
+	}
+	if pending != nil {
+		callee = WithState(callee, pending)
+	}
+	return callee, nil
+}
+
+// BindImports returns a copy of [imports] with the state and token imports
+// bound to the program [programID], so the program can only access its own
+// state, see pstate.Import.ForProgram and token.Import.ForProgram. Hosts use
+// it for the runtime of a program they do not trust, the programs invoked by
+// call_program are always bound.
+func BindImports(imports runtime.SupportedImports, programID ids.ID) runtime.SupportedImports {
+	bound := make(runtime.SupportedImports, len(imports))
+	for name, f := range imports {
+		bound[name] = f
+	}
+	if stateFn, ok := imports[pstate.Name]; ok {
+		bound[pstate.Name] = func() runtime.Import {
+			imp := stateFn()
+			if s, ok := imp.(*pstate.Import); ok {
+				return s.ForProgram(programID)
+			}
+			return imp
+		}
+	}
+	if tokenFn, ok := imports[token.Name]; ok {
+		bound[token.Name] = func() runtime.Import {
+			imp := tokenFn()
+			if t, ok := imp.(*token.Import); ok {
+				return t.ForProgram(programID)
+			}
+			return imp
+		}
+	}
+	return bound
 }
 
-func getCallArgs(ctx context.Context, rt runtime.Runtime, buffer []byte, invokeProgramID uint64) ([]uint64, error) {
+// WithState returns a copy of [imports] whose state imports are set to [mu],
+// see StateImport. Hosts use it to run a call against a pending view of
+// state, see storage.NewPendingState.
+func WithState(imports runtime.SupportedImports, mu state.Mutable) runtime.SupportedImports {
+	withState := make(runtime.SupportedImports, len(imports))
+	for name, f := range imports {
+		f := f
+		withState[name] = func() runtime.Import {
+			imp := f()
+			if s, ok := imp.(StateImport); ok {
+				s.SetState(mu)
+			}
+			return imp
+		}
+	}
+	return withState
+}
+
+// getCallArgs returns the params of a call with the args packed in [buffer].
+// Byte args are written with [scratch].
+func getCallArgs(scratch *runtime.Scratch, buffer []byte, invokeProgramID uint64) ([]uint64, error) {
 	// first arg contains id of program to call
 	args := []uint64{invokeProgramID}
 	p := codec.NewReader(buffer, len(buffer))
 	i := 0
-	for !p.Empty() {
+	for !p.Empty() && p.Err() == nil {
 		size := p.UnpackInt64(true)
 		isInt := p.UnpackBool()
 		if isInt {
 			valueInt := p.UnpackUint64(true)
 			args = append(args, valueInt)
 		} else {
+			// the value can not be larger than the remaining buffer
+			if size < 0 || size > int64(len(buffer)) {
+				return nil, fmt.Errorf("%w: %d", ErrInvalidArgSize, size)
+			}
 			valueBytes := make([]byte, size)
 			p.UnpackFixedBytes(int(size), &valueBytes)
-			ptr, err := runtime.WriteBytes(rt.Memory(), valueBytes)
+			ptr, err := scratch.WriteBytes(valueBytes)
 			if err != nil {
 				return nil, err
 			}
@@ -206,20 +528,43 @@ func getCallArgs(ctx context.Context, rt runtime.Runtime, buffer []byte, invokeP
 	return args, nil
 }
 
-func getProgramWasmBytes(log logging.Logger, db state.Immutable, idBytes []byte) ([]byte, error) {
+// LoadProgram returns the module of [version] of the program [idBytes], or of
+// its latest version if zero, and the strategy to compile it with. If the
+// latest version has an artifact compiled for [artifactVersion], see
+// storage.SetProgramArtifact, the artifact is returned so the program is not
+// compiled again. Returns database.ErrNotFound if the program does not exist.
+func LoadProgram(ctx context.Context, db state.Immutable, idBytes []byte, version uint32, artifactVersion string) ([]byte, runtime.EngineCompileStrategy, error) {
 	id, err := ids.ToID(idBytes)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	if version == 0 {
+		artifact, exists, err := storage.GetProgramArtifact(ctx, db, id, artifactVersion)
+		if err != nil {
+			return nil, 0, err
+		}
+		if exists {
+			return artifact, runtime.PrecompiledWasm, nil
+		}
 	}
 
 	// get the program bytes from storage
-	bytes, exists, err := storage.GetProgram(context.Background(), db, id)
-	if !exists {
-		log.Debug("key does not exist", zap.String("id", id.String()))
+	var (
+		bytes  []byte
+		exists bool
+	)
+	if version == 0 {
+		bytes, exists, err = storage.GetProgram(ctx, db, id)
+	} else {
+		bytes, exists, err = storage.GetProgramVersion(ctx, db, id, version)
 	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, database.ErrNotFound
 	}
 
-	return bytes, nil
+	return bytes, runtime.CompileWasm, nil
 }
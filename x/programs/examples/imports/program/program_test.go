@@ -0,0 +1,1002 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package program
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/codec"
+	"github.com/ava-labs/hypersdk/consts"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	pcontext "github.com/ava-labs/hypersdk/x/programs/examples/imports/context"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/pstate"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/token"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+// newCallProgram returns a program which calls "run" of [target] with
+// [maxUnits] and returns the result.
+func newCallProgram(t *testing.T, target ids.ID, maxUnits int64) []byte {
+	return newCallProgramWith(t, "call_program", target, maxUnits)
+}
+
+// newCallProgramWith returns a program which calls "run" of [target] using
+// the [fn] import with [maxUnits] and returns the result, or the status of
+// the call if it failed. The status is stored at offset 48 and the result at
+// offset 64 of its memory.
+func newCallProgramWith(t *testing.T, fn string, target ids.ID, maxUnits int64) []byte {
+	var id strings.Builder
+	for _, b := range target {
+		fmt.Fprintf(&id, "\\%02x", b)
+	}
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (import "program" "%s" (func $call_program (param i64 i64 i64 i32 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "%s")
+	  (data (i32.const 32) "run")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    (local $status i32)
+	    (local.set $status
+	      (call $call_program (local.get 0) (i64.const 0) (i64.const %d) (i32.const 32) (i32.const 3) (i32.const 0) (i32.const 0) (i32.const 64)))
+	    (i32.store (i32.const 48) (local.get $status))
+	    (if (result i64) (local.get $status)
+	      (then (i64.extend_i32_s (local.get $status)))
+	      (else (i64.load (i32.const 64))))
+	  )
+	)
+	`, fn, id.String(), maxUnits))
+	require.NoError(t, err)
+	return wasm
+}
+
+// newCallProgramVersion returns a program which calls "run" of [version] of
+// [target] with [maxUnits] and returns the result, or the status of the call
+// if it failed.
+func newCallProgramVersion(t *testing.T, target ids.ID, version int64, maxUnits int64) []byte {
+	var id strings.Builder
+	for _, b := range target {
+		fmt.Fprintf(&id, "\\%02x", b)
+	}
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (import "program" "call_program_version" (func $call_program_version (param i64 i64 i64 i64 i32 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "%s")
+	  (data (i32.const 32) "run")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    (local $status i32)
+	    (local.set $status
+	      (call $call_program_version (local.get 0) (i64.const 0) (i64.const %d) (i64.const %d) (i32.const 32) (i32.const 3) (i32.const 0) (i32.const 0) (i32.const 64)))
+	    (if (result i64) (local.get $status)
+	      (then (i64.extend_i32_s (local.get $status)))
+	      (else (i64.load (i32.const 64))))
+	  )
+	)
+	`, id.String(), version, maxUnits))
+	require.NoError(t, err)
+	return wasm
+}
+
+// newLeafProgram returns a program whose "run" function executes [body].
+func newLeafProgram(t *testing.T, body string) []byte {
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    %s
+	  )
+	)
+	`, body))
+	require.NoError(t, err)
+	return wasm
+}
+
+// newNoResultProgram returns a program whose "run" function has no results.
+func newNoResultProgram(t *testing.T) []byte {
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64))
+	)
+	`)
+	require.NoError(t, err)
+	return wasm
+}
+
+func TestCallProgramUnits(t *testing.T) {
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	var (
+		leafID       = ids.GenerateTestID()
+		loopID       = ids.GenerateTestID()
+		trapID       = ids.GenerateTestID()
+		noResultID   = ids.GenerateTestID()
+		midLeafID    = ids.GenerateTestID()
+		midLoopID    = ids.GenerateTestID()
+		midInsuffID  = ids.GenerateTestID()
+		rootMaxUnits = uint64(100000)
+	)
+	programs := map[ids.ID][]byte{
+		leafID:      newLeafProgram(t, "i64.const 7"),
+		loopID:      newLeafProgram(t, "(loop br 0) i64.const 0"),
+		trapID:      newLeafProgram(t, "unreachable"),
+		noResultID:  newNoResultProgram(t),
+		midLeafID:   newCallProgram(t, leafID, 5000),
+		midLoopID:   newCallProgram(t, loopID, 5000),
+		midInsuffID: newCallProgram(t, leafID, 50000),
+	}
+	for id, wasm := range programs {
+		require.NoError(t, storage.SetProgram(ctx, db, id, wasm))
+	}
+
+	tests := []struct {
+		name       string
+		target     ids.ID
+		maxUnits   int64
+		wantResult int64
+		// range of units spent by the root program
+		minSpent uint64
+		maxSpent uint64
+	}{
+		{
+			name:       "leftover returned to caller",
+			target:     leafID,
+			maxUnits:   10000,
+			wantResult: 7,
+			minSpent:   callProgramCost,
+			maxSpent:   callProgramCost + 100,
+		},
+		{
+			name:       "callee exhausts units",
+			target:     loopID,
+			maxUnits:   10000,
+			wantResult: -1,
+			minSpent:   callProgramCost + 10000,
+			maxSpent:   callProgramCost + 10000 + 100,
+		},
+		{
+			name:       "leftover returned to caller on trap",
+			target:     trapID,
+			maxUnits:   10000,
+			wantResult: -1,
+			minSpent:   callProgramCost,
+			maxSpent:   callProgramCost + 100,
+		},
+		{
+			name:       "callee without results",
+			target:     noResultID,
+			maxUnits:   10000,
+			wantResult: 0,
+			minSpent:   callProgramCost,
+			maxSpent:   callProgramCost + 100,
+		},
+		{
+			name:       "caller insufficient units",
+			target:     leafID,
+			maxUnits:   int64(rootMaxUnits) + 1,
+			wantResult: -1,
+			minSpent:   callProgramCost,
+			maxSpent:   callProgramCost + 100,
+		},
+		{
+			name:       "nested leftover returned to caller",
+			target:     midLeafID,
+			maxUnits:   20000,
+			wantResult: 7,
+			minSpent:   2 * callProgramCost,
+			maxSpent:   2*callProgramCost + 200,
+		},
+		{
+			name:       "nested callee exhausts units",
+			target:     midLoopID,
+			maxUnits:   20000,
+			wantResult: -1,
+			minSpent:   2*callProgramCost + 5000,
+			maxSpent:   2*callProgramCost + 5000 + 200,
+		},
+		{
+			name:       "nested caller insufficient units",
+			target:     midInsuffID,
+			maxUnits:   20000,
+			wantResult: -1,
+			minSpent:   2 * callProgramCost,
+			maxSpent:   2*callProgramCost + 200,
+		},
+		{
+			name:       "missing program",
+			target:     ids.GenerateTestID(),
+			maxUnits:   10000,
+			wantResult: runtime.StatusNotFound,
+			minSpent:   callProgramCost,
+			maxSpent:   callProgramCost + 100,
+		},
+		{
+			name:       "negative units",
+			target:     leafID,
+			maxUnits:   -1,
+			wantResult: -1,
+			minSpent:   callProgramCost,
+			maxSpent:   callProgramCost + 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			supported := runtime.NewSupportedImports()
+			supported.Register(Name, func() runtime.Import {
+				return New(log, db)
+			})
+			cfg, err := runtime.NewConfigBuilder(rootMaxUnits).Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, newCallProgram(t, tt.target, tt.maxUnits)))
+			defer rt.Stop()
+
+			resp, err := rt.Call(ctx, "run", 0)
+			require.NoError(err)
+			require.Equal(tt.wantResult, int64(resp[0]))
+
+			spent := rootMaxUnits - rt.Meter().GetBalance()
+			require.GreaterOrEqual(spent, tt.minSpent)
+			require.LessOrEqual(spent, tt.maxSpent)
+		})
+	}
+}
+
+func TestCallProgramArtifact(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	newConfig := func() *runtime.Config {
+		cfg, err := runtime.NewConfigBuilder(100000).Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	// the artifact is compiled from other code than the program, so the
+	// result tells which one was loaded
+	calleeID := ids.GenerateTestID()
+	require.NoError(storage.SetProgram(ctx, db, calleeID, newLeafProgram(t, "i64.const 1")))
+	cfg := newConfig()
+	artifact, err := runtime.PreCompileWasmBytes(newLeafProgram(t, "i64.const 2"), cfg)
+	require.NoError(err)
+	require.NoError(storage.SetProgramArtifact(ctx, db, calleeID, cfg.ArtifactVersion(), artifact))
+
+	call := func() int64 {
+		supported := runtime.NewSupportedImports()
+		supported.Register(Name, func() runtime.Import {
+			return New(log, db)
+		})
+		rt := runtime.New(log, newConfig(), supported.Imports())
+		require.NoError(rt.Initialize(ctx, newCallProgram(t, calleeID, 10000)))
+		defer rt.Stop()
+		resp, err := rt.Call(ctx, "run", 0)
+		require.NoError(err)
+		return int64(resp[0])
+	}
+
+	// the artifact is deserialized instead of compiling the program
+	require.Equal(int64(2), call())
+
+	// artifacts of another engine are ignored
+	require.NoError(storage.SetProgramArtifact(ctx, db, calleeID, "v0.0.0", artifact))
+	require.Equal(int64(1), call())
+}
+
+func TestCallProgramStop(t *testing.T) {
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	loopID := ids.GenerateTestID()
+	require.NoError(t, storage.SetProgram(ctx, db, loopID, newLeafProgram(t, "(loop br 0) i64.const 0")))
+
+	tests := []struct {
+		name string
+		// interrupt interrupts the caller after a delay
+		interrupt func(rt runtime.Runtime, cancel context.CancelFunc)
+	}{
+		{
+			name:      "stop",
+			interrupt: func(rt runtime.Runtime, _ context.CancelFunc) { rt.Stop() },
+		},
+		{
+			name:      "cancel",
+			interrupt: func(_ runtime.Runtime, cancel context.CancelFunc) { cancel() },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			supported := runtime.NewSupportedImports()
+			supported.Register(Name, func() runtime.Import {
+				return New(log, db)
+			})
+			cfg, err := runtime.NewConfigBuilder(math.MaxInt64).Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, newCallProgram(t, loopID, math.MaxInt64/2)))
+			defer rt.Stop()
+
+			// interrupting the caller interrupts the invoked program, which
+			// would otherwise loop until it runs out of units
+			callCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			timer := time.AfterFunc(50*time.Millisecond, func() { tt.interrupt(rt, cancel) })
+			defer timer.Stop()
+			resp, err := rt.Call(callCtx, "run", 0)
+			require.NoError(err)
+			require.Equal(int64(runtime.StatusError), int64(resp[0]))
+		})
+	}
+}
+
+// newContextProgram returns a program whose "run" function returns the
+// first 8 bytes written by the context import [fn].
+func newContextProgram(t *testing.T, fn string) []byte {
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (import "context" "%s" (func $fn (param i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    (drop (call $fn (i32.const 512)))
+	    (i64.load (i32.const 512))
+	  )
+	)
+	`, fn))
+	require.NoError(t, err)
+	return wasm
+}
+
+func TestCallProgramContext(t *testing.T) {
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	idCallee := ids.GenerateTestID()
+	hashCallee := ids.GenerateTestID()
+	idWasm := newContextProgram(t, "program_id")
+	hashWasm := newContextProgram(t, "program_code_hash")
+	actorCallee := ids.GenerateTestID()
+	require.NoError(t, storage.SetProgram(ctx, db, idCallee, idWasm))
+	require.NoError(t, storage.SetProgram(ctx, db, hashCallee, hashWasm))
+	require.NoError(t, storage.SetProgram(ctx, db, actorCallee, newContextProgram(t, "actor")))
+	actor := ed25519.PublicKey{1, 2, 3, 4, 5, 6, 7, 8}
+
+	tests := []struct {
+		name   string
+		target ids.ID
+		want   []byte
+	}{
+		{
+			name:   "program id of callee",
+			target: idCallee,
+			want:   idCallee[:8],
+		},
+		{
+			name:   "code hash of callee",
+			target: hashCallee,
+			want:   func() []byte { h := hashing.ComputeHash256(hashWasm); return h[:8] }(),
+		},
+		{
+			name:   "actor of caller",
+			target: actorCallee,
+			want:   actor[:8],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			// the root program has its own context
+			supported := runtime.NewSupportedImports()
+			supported.Register(Name, func() runtime.Import {
+				return New(log, db)
+			})
+			supported.Register(pcontext.Name, func() runtime.Import {
+				return pcontext.New(log, ids.Empty, ids.Empty).WithActor(actor)
+			})
+			cfg, err := runtime.NewConfigBuilder(100000).Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, newCallProgram(t, tt.target, 10000)))
+			defer rt.Stop()
+
+			resp, err := rt.Call(ctx, "run", 0)
+			require.NoError(err)
+			require.Equal(binary.LittleEndian.Uint64(tt.want), resp[0])
+		})
+	}
+}
+
+func TestCallProgramVersion(t *testing.T) {
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	callee := ids.GenerateTestID()
+	hashWasm := newContextProgram(t, "program_code_hash")
+	version, err := storage.SetProgramVersion(ctx, db, callee, hashWasm)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), version)
+	version, err = storage.SetProgramVersion(ctx, db, callee, newLeafProgram(t, "i64.const 2"))
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), version)
+
+	tests := []struct {
+		name    string
+		program []byte
+		want    int64
+	}{
+		{
+			name:    "latest version",
+			program: newCallProgram(t, callee, 10000),
+			want:    2,
+		},
+		{
+			name:    "previous version with its code hash",
+			program: newCallProgramVersion(t, callee, 1, 10000),
+			want:    func() int64 { h := hashing.ComputeHash256(hashWasm); return int64(binary.LittleEndian.Uint64(h[:8])) }(),
+		},
+		{
+			name:    "selected latest version",
+			program: newCallProgramVersion(t, callee, 2, 10000),
+			want:    2,
+		},
+		{
+			name:    "missing version",
+			program: newCallProgramVersion(t, callee, 3, 10000),
+			want:    int64(runtime.StatusNotFound),
+		},
+		{
+			name:    "invalid version",
+			program: newCallProgramVersion(t, callee, 0, 10000),
+			want:    int64(runtime.StatusError),
+		},
+		{
+			name:    "version out of range",
+			program: newCallProgramVersion(t, callee, math.MaxUint32+1, 10000),
+			want:    int64(runtime.StatusError),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			supported := runtime.NewSupportedImports()
+			supported.Register(Name, func() runtime.Import {
+				return New(log, db)
+			})
+			supported.Register(pcontext.Name, func() runtime.Import {
+				return pcontext.New(log, ids.Empty, ids.Empty)
+			})
+			cfg, err := runtime.NewConfigBuilder(100000).Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, tt.program))
+			defer rt.Stop()
+
+			resp, err := rt.Call(ctx, "run", 0)
+			require.NoError(err)
+			require.Equal(tt.want, int64(resp[0]))
+		})
+	}
+}
+
+func TestCallProgramToken(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	// the callee mints 5 to the zero key at offset 0 of its memory
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "token" "mint" (func $mint (param i32 i64) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    (call $mint (i32.const 0) (i64.const 5))
+	    i64.extend_i32_s
+	  )
+	)
+	`)
+	require.NoError(err)
+	callee := ids.GenerateTestID()
+	require.NoError(storage.SetProgram(ctx, db, callee, wasm))
+
+	// the root program has its own token
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(log, db)
+	})
+	supported.Register(token.Name, func() runtime.Import {
+		return token.New(log, db, ids.Empty)
+	})
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt := runtime.New(log, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, newCallProgram(t, callee, 10000)))
+	defer rt.Stop()
+
+	resp, err := rt.Call(ctx, "run", 0)
+	require.NoError(err)
+	require.Equal(uint64(0), resp[0])
+
+	// the callee minted its own token
+	v, err := db.GetValue(ctx, token.TotalSupplyKey(callee[:]))
+	require.NoError(err)
+	require.Equal([]byte{0, 0, 0, 0, 0, 0, 0, 5}, v)
+	_, err = db.GetValue(ctx, token.TotalSupplyKey(ids.Empty[:]))
+	require.ErrorIs(err, database.ErrNotFound)
+}
+
+// newStatusResultProgram returns a program whose "run" function returns the
+// status code [code] and a pointer to the result 42, or to the error message
+// "invalid owner" if [code] is not zero.
+func newStatusResultProgram(t *testing.T, code int64) []byte {
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "\00\00\00\0dinvalid owner")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64 i64)
+	    (i64.const %d)
+	    (if (result i64) (i64.eqz (i64.const %d))
+	      (then (i64.const 42))
+	      (else (i64.const 0)))
+	  )
+	)
+	`, code, code))
+	require.NoError(t, err)
+	return wasm
+}
+
+func TestCallProgramResultStatus(t *testing.T) {
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+
+	errorResultID := ids.GenerateTestID()
+	notFoundResultID := ids.GenerateTestID()
+	require.NoError(t, storage.SetProgram(ctx, db, errorResultID, newLeafProgram(t, "i64.const -1")))
+	require.NoError(t, storage.SetProgram(ctx, db, notFoundResultID, newLeafProgram(t, "i64.const -2")))
+	okStatusID := ids.GenerateTestID()
+	errorStatusID := ids.GenerateTestID()
+	negativeStatusID := ids.GenerateTestID()
+	require.NoError(t, storage.SetProgram(ctx, db, okStatusID, newStatusResultProgram(t, 0)))
+	require.NoError(t, storage.SetProgram(ctx, db, errorStatusID, newStatusResultProgram(t, 7)))
+	require.NoError(t, storage.SetProgram(ctx, db, negativeStatusID, newStatusResultProgram(t, -1)))
+
+	tests := []struct {
+		name          string
+		target        ids.ID
+		maxResultSize uint64
+		wantStatus    int32
+		wantResult    int64
+	}{
+		{
+			name:       "result equal to StatusError",
+			target:     errorResultID,
+			wantResult: -1,
+		},
+		{
+			name:       "result equal to StatusNotFound",
+			target:     notFoundResultID,
+			wantResult: -2,
+		},
+		{
+			name:       "status code ok",
+			target:     okStatusID,
+			wantResult: 42,
+		},
+		{
+			name:       "status code error",
+			target:     errorStatusID,
+			wantStatus: runtime.StatusError,
+		},
+		{
+			// the code is not truncated to a valid status code
+			name:       "status code exceeds uint32",
+			target:     negativeStatusID,
+			wantStatus: runtime.StatusError,
+		},
+		{
+			name:       "missing program",
+			target:     ids.GenerateTestID(),
+			wantStatus: runtime.StatusNotFound,
+		},
+		{
+			// the function name "run" read from the caller is bounded too
+			name:          "function name exceeds max result size",
+			target:        errorResultID,
+			maxResultSize: 2,
+			wantStatus:    runtime.StatusError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			supported := runtime.NewSupportedImports()
+			supported.Register(Name, func() runtime.Import {
+				return New(log, db)
+			})
+			cfg, err := runtime.NewConfigBuilder(100000).
+				WithMaxResultSize(tt.maxResultSize).
+				WithMultiValue(true).
+				Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, newCallProgram(t, tt.target, 10000)))
+			defer rt.Stop()
+
+			_, err = rt.Call(ctx, "run", 0)
+			require.NoError(err)
+
+			// the status and the result are returned separately
+			status, err := rt.Memory().Range(48, consts.Uint32Len)
+			require.NoError(err)
+			require.Equal(tt.wantStatus, int32(binary.LittleEndian.Uint32(status)))
+			result, err := rt.Memory().Range(64, consts.Uint64Len)
+			require.NoError(err)
+			require.Equal(tt.wantResult, int64(binary.LittleEndian.Uint64(result)))
+		})
+	}
+}
+
+// newStateProgram returns a program whose "run" function calls the state
+// import [fn] with the key "k" and, for put, the value "v".
+func newStateProgram(t *testing.T, fn string) []byte {
+	call := "(call $len (local.get 0) (i32.const 0) (i32.const 1))"
+	if fn == "put" {
+		call = "(call $put (local.get 0) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1))"
+	}
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (import "state" "len" (func $len (param i64 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kv")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    %s
+	    i64.extend_i32_s
+	  )
+	)
+	`, call))
+	require.NoError(t, err)
+	return wasm
+}
+
+// newPendingProgram returns a program whose "run" function puts "v" at "k"
+// and then executes [body].
+func newPendingProgram(t *testing.T, body string) []byte {
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kv")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    (drop (call $put (local.get 0) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1)))
+	    %s
+	  )
+	)
+	`, body))
+	require.NoError(t, err)
+	return wasm
+}
+
+func TestCallProgramPendingState(t *testing.T) {
+	ctx := context.Background()
+	log := logging.NoLog{}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantResult int64
+		wantWrite  bool
+	}{
+		{
+			name:       "writes committed on success",
+			body:       "i64.const 0",
+			wantResult: 0,
+			wantWrite:  true,
+		},
+		{
+			name:       "writes discarded on trap",
+			body:       "unreachable",
+			wantResult: runtime.StatusError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			db := utils.NewTestDB()
+			calleeID := ids.GenerateTestID()
+			require.NoError(storage.SetProgram(ctx, db, calleeID, newPendingProgram(t, tt.body)))
+
+			supported := runtime.NewSupportedImports()
+			supported.Register(pstate.Name, func() runtime.Import {
+				return pstate.New(log, db)
+			})
+			supported.Register(Name, func() runtime.Import {
+				return New(log, db)
+			})
+			cfg, err := runtime.NewConfigBuilder(100000).Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, newCallProgram(t, calleeID, 10000)))
+			defer rt.Stop()
+
+			resp, err := rt.Call(ctx, "run", 0)
+			require.NoError(err)
+			require.Equal(tt.wantResult, int64(resp[0]))
+
+			_, err = db.GetValue(ctx, storage.ProgramPrefixKey(calleeID[:], []byte("k")))
+			if tt.wantWrite {
+				require.NoError(err)
+			} else {
+				require.ErrorIs(err, database.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestCallProgramBoundState(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	log := logging.NoLog{}
+
+	db := utils.NewTestDB()
+	calleeID := ids.GenerateTestID()
+	otherID := ids.GenerateTestID()
+
+	// the callee writes to the namespace of [otherID] instead of its own
+	var escaped strings.Builder
+	for _, b := range otherID {
+		fmt.Fprintf(&escaped, "\\%02x", b)
+	}
+	wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kv")
+	  (data (i32.const 64) "%s")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "run_guest") (param i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $put (i64.const 64) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1)))
+	  )
+	)
+	`, escaped.String()))
+	require.NoError(err)
+	require.NoError(storage.SetProgram(ctx, db, calleeID, wasm))
+
+	supported := runtime.NewSupportedImports()
+	supported.Register(pstate.Name, func() runtime.Import {
+		return pstate.New(log, db)
+	})
+	supported.Register(Name, func() runtime.Import {
+		return New(log, db)
+	})
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt := runtime.New(log, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, newCallProgram(t, calleeID, 10000)))
+	defer rt.Stop()
+
+	resp, err := rt.Call(ctx, "run", 0)
+	require.NoError(err)
+	require.Equal(int64(runtime.StatusPermissionDenied), int64(resp[0]))
+
+	_, err = db.GetValue(ctx, storage.ProgramPrefixKey(otherID[:], []byte("k")))
+	require.ErrorIs(err, database.ErrNotFound)
+	size, err := storage.GetProgramStateSize(ctx, db, otherID)
+	require.NoError(err)
+	require.Zero(size)
+}
+
+func TestCallProgramReadonly(t *testing.T) {
+	ctx := context.Background()
+	log := logging.NoLog{}
+
+	var (
+		readerID  = ids.GenerateTestID()
+		writerID  = ids.GenerateTestID()
+		missingID = ids.GenerateTestID()
+	)
+
+	tests := []struct {
+		name       string
+		fn         string
+		target     ids.ID
+		readonly   bool
+		wantResult int64
+		wantWrite  bool
+	}{
+		{
+			name:       "write",
+			fn:         "call_program",
+			target:     writerID,
+			wantResult: 0,
+			wantWrite:  true,
+		},
+		{
+			name:       "readonly write denied",
+			fn:         "call_program_readonly",
+			target:     writerID,
+			readonly:   true,
+			wantResult: runtime.StatusPermissionDenied,
+		},
+		{
+			name:       "readonly read",
+			fn:         "call_program_readonly",
+			target:     readerID,
+			readonly:   true,
+			wantResult: 1,
+		},
+		{
+			name:       "readonly read missing key",
+			fn:         "call_program_readonly",
+			target:     missingID,
+			readonly:   true,
+			wantResult: runtime.StatusNotFound,
+		},
+		{
+			name:       "missing readonly imports",
+			fn:         "call_program_readonly",
+			target:     readerID,
+			wantResult: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			db := utils.NewTestDB()
+			require.NoError(storage.SetProgram(ctx, db, readerID, newStateProgram(t, "len")))
+			require.NoError(storage.SetProgram(ctx, db, writerID, newStateProgram(t, "put")))
+			require.NoError(storage.SetProgram(ctx, db, missingID, newStateProgram(t, "len")))
+			require.NoError(db.Insert(ctx, storage.ProgramPrefixKey(readerID[:], []byte("k")), []byte("v")))
+
+			readonlyDB := storage.NewReadOnlyState(db)
+			readonly := runtime.NewSupportedImports()
+			readonly.Register(pstate.Name, func() runtime.Import {
+				return pstate.New(log, readonlyDB)
+			})
+
+			supported := runtime.NewSupportedImports()
+			supported.Register(pstate.Name, func() runtime.Import {
+				return pstate.New(log, db)
+			})
+			supported.Register(Name, func() runtime.Import {
+				imp := New(log, db)
+				if tt.readonly {
+					imp.WithReadonlyImports(readonly.Imports())
+				}
+				return imp
+			})
+
+			cfg, err := runtime.NewConfigBuilder(100000).Build()
+			require.NoError(err)
+			rt := runtime.New(log, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, newCallProgramWith(t, tt.fn, tt.target, 10000)))
+			defer rt.Stop()
+
+			resp, err := rt.Call(ctx, "run", 0)
+			require.NoError(err)
+			require.Equal(tt.wantResult, int64(resp[0]))
+
+			_, err = db.GetValue(ctx, storage.ProgramPrefixKey(writerID[:], []byte("k")))
+			if tt.wantWrite {
+				require.NoError(err)
+			} else {
+				require.ErrorIs(err, database.ErrNotFound)
+			}
+		})
+	}
+}
+
+func FuzzGetCallArgs(f *testing.F) {
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 0
+	  )
+	)
+	`)
+	require.NoError(f, err)
+
+	cfg, err := runtime.NewConfigBuilder(runtime.NoUnits).Build()
+	require.NoError(f, err)
+	rt := runtime.New(logging.NoLog{}, cfg, runtime.NoSupportedImports)
+	require.NoError(f, rt.Initialize(context.Background(), wasm))
+
+	newArgs := func(size int64, isInt bool, value []byte) []byte {
+		p := codec.NewWriter(0, consts.MaxInt)
+		p.PackInt64(size)
+		p.PackBool(isInt)
+		p.PackFixedBytes(value)
+		return p.Bytes()
+	}
+
+	f.Add([]byte{})
+	f.Add(newArgs(8, true, []byte{0, 0, 0, 0, 0, 0, 0, 1}))
+	f.Add(newArgs(3, false, []byte("abc")))
+	f.Add(newArgs(-1, false, []byte("abc")))
+	f.Add(newArgs(1<<40, false, []byte("abc")))
+	f.Add(newArgs(3, false, []byte("a")))
+	f.Fuzz(func(t *testing.T, args []byte) {
+		// must never panic or loop on malformed input
+		scratch := runtime.NewScratch(rt.Memory())
+		params, err := getCallArgs(scratch, args, 0)
+		if err == nil {
+			require.NotEmpty(t, params)
+		}
+		require.NoError(t, scratch.Free())
+	})
+}
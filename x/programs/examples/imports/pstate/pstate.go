@@ -4,6 +4,7 @@
 package pstate
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -15,27 +16,81 @@ import (
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
+	smath "github.com/ava-labs/avalanchego/utils/math"
 
 	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/errs"
 	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
 	"github.com/ava-labs/hypersdk/x/programs/runtime"
 )
 
-const Name = "state"
+const (
+	Name = "state"
 
-var _ runtime.Import = &Import{}
+	putCost    = 1000
+	getCost    = 500
+	lenCost    = 250
+	deleteCost = 500
+)
+
+var (
+	_ runtime.Import = &Import{}
+
+	ErrProgramMismatch = errs.New(errs.ErrValidation, "program id does not match the bound program")
+)
 
 // New returns a program storage module capable of storing arbitrary bytes
 // in the program's namespace.
-func New(log logging.Logger, mu state.Mutable) runtime.Import {
+//
+// Writes are applied to [mu] immediately, so a program reads its own writes
+// within the same call and later calls through [mu] see them as well. Whether
+// the writes are persisted is up to the owner of [mu], for example by
+// committing a state.SimpleMutable once the call succeeds.
+//
+// The bytes stored by each program are accounted, see
+// storage.GetProgramStateSize.
+//
+// The program ID is read from guest memory unless the module is bound to a
+// program with ForProgram. Hosts must bind the module for programs they do
+// not trust, as call_program does for the programs it invokes.
+func New(log logging.Logger, mu state.Mutable) *Import {
 	return &Import{mu: mu, log: log}
 }
 
+// ForProgram returns an unregistered copy of the module over the same state
+// bound to the program [programID]. Calls passing the ID of another program
+// are denied with runtime.StatusPermissionDenied, so the program can only access its own namespace and its
+// writes are charged to its own state size.
+func (i *Import) ForProgram(programID ids.ID) *Import {
+	return &Import{
+		mu:          i.mu,
+		log:         i.log,
+		perByteCost: i.perByteCost,
+		programID:   programID,
+	}
+}
+
+// WithPerByteCost sets the units charged per byte of key and value written by
+// put in addition to the cost of the call, so storage heavy programs are
+// priced by the state they grow. Default is 0.
+func (i *Import) WithPerByteCost(units uint64) *Import {
+	i.perByteCost = units
+	return i
+}
+
+// SetState sets the state of the module, see program.StateImport.
+func (i *Import) SetState(mu state.Mutable) {
+	i.mu = mu
+}
+
 type Import struct {
-	mu         state.Mutable
-	log        logging.Logger
-	meter      runtime.Meter
-	registered bool
+	mu            state.Mutable
+	log           logging.Logger
+	meter         runtime.Meter
+	perByteCost   uint64
+	programID     ids.ID
+	maxResultSize uint64
+	registered    bool
 }
 
 func (i *Import) Name() string {
@@ -47,29 +102,39 @@ func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.Supp
 		return fmt.Errorf("import module already registered: %q", Name)
 	}
 	i.meter = meter
+	i.maxResultSize = link.MaxResultSize()
 	i.registered = true
 
-	if err := link.FuncWrap(Name, "put", i.putFn); err != nil {
+	if err := link.MeteredFuncWrap(meter, putCost, Name, "put", i.putFn); err != nil {
 		return err
 	}
-	if err := link.FuncWrap(Name, "get", i.getFn); err != nil {
+	if err := link.MeteredFuncWrap(meter, getCost, Name, "get", i.getFn); err != nil {
 		return err
 	}
-	if err := link.FuncWrap(Name, "len", i.getLenFn); err != nil {
+	if err := link.MeteredFuncWrap(meter, lenCost, Name, "len", i.getLenFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, deleteCost, Name, "delete", i.deleteFn); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (i *Import) putFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLength int32, valuePtr int32, valueLength int32) int32 {
+// putFn stores the value for the key in the namespace of the program. Returns
+// 0 on success or runtime.StatusPermissionDenied if the state is read-only
+// or the program is denied, see ForProgram. Traps if the meter has insufficient units for the per byte cost.
+func (i *Import) putFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLength int32, valuePtr int32, valueLength int32) (int32, *wasmtime.Trap) {
 	memory := runtime.NewMemory(runtime.NewExportClient(caller))
-	programIDBytes, err := memory.Range(uint64(idPtr), uint64(ids.IDLen))
+	programIDBytes, err := i.readProgramID(memory, idPtr)
+	if errors.Is(err, ErrProgramMismatch) {
+		return runtime.StatusPermissionDenied, nil
+	}
 	if err != nil {
 		i.log.Error("failed to read program id from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError, nil
 	}
 
 	keyBytes, err := memory.Range(uint64(keyPtr), uint64(keyLength))
@@ -77,7 +142,7 @@ func (i *Import) putFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLe
 		i.log.Error("failed to read key from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError, nil
 	}
 
 	valueBytes, err := memory.Range(uint64(valuePtr), uint64(valueLength))
@@ -85,97 +150,275 @@ func (i *Import) putFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLe
 		i.log.Error("failed to read value from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError, nil
+	}
+
+	size := int64(len(keyBytes) + len(valueBytes))
+	cost, err := smath.Mul64(i.perByteCost, uint64(size))
+	if err != nil {
+		return runtime.StatusError, wasmtime.NewTrap(fmt.Sprintf("%s: %s", err, Name))
+	}
+	if _, err := i.meter.Spend(cost); err != nil {
+		return runtime.StatusError, wasmtime.NewTrap(fmt.Sprintf("%s: %s", err, Name))
 	}
 
 	k := storage.ProgramPrefixKey(programIDBytes, keyBytes)
+	prevSize, err := i.entrySize(k, keyBytes)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		i.log.Error("failed to get value from storage",
+			zap.Error(err),
+		)
+		return runtime.StatusError, nil
+	}
+
+	// the state size is updated first, so a failed insert is the only change
+	// to revert
+	err = i.updateStateSize(programIDBytes, size-prevSize)
+	if errors.Is(err, storage.ErrReadOnly) {
+		return runtime.StatusPermissionDenied, nil
+	}
+	if err != nil {
+		i.log.Error("failed to update program state size",
+			zap.Error(err),
+		)
+		return runtime.StatusError, nil
+	}
+
 	err = i.mu.Insert(context.Background(), k, valueBytes)
+	if err != nil {
+		i.revertStateSize(programIDBytes, size-prevSize)
+	}
+	if errors.Is(err, storage.ErrReadOnly) {
+		return runtime.StatusPermissionDenied, nil
+	}
 	if err != nil {
 		i.log.Error("failed to insert into storage",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError, nil
+	}
+
+	return 0, nil
+}
+
+// deleteFn removes the key from the namespace of the program. Returns 0 on
+// success, runtime.StatusNotFound if the key does not exist or
+// runtime.StatusPermissionDenied if the state is read-only or the program is
+// denied, see ForProgram.
+func (i *Import) deleteFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLength int32) int32 {
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	programIDBytes, err := i.readProgramID(memory, idPtr)
+	if errors.Is(err, ErrProgramMismatch) {
+		return runtime.StatusPermissionDenied
+	}
+	if err != nil {
+		i.log.Error("failed to read program id from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	keyBytes, err := memory.Range(uint64(keyPtr), uint64(keyLength))
+	if err != nil {
+		i.log.Error("failed to read key from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	k := storage.ProgramPrefixKey(programIDBytes, keyBytes)
+	prevSize, err := i.entrySize(k, keyBytes)
+	if errors.Is(err, database.ErrNotFound) {
+		return runtime.StatusNotFound
+	}
+	if err != nil {
+		i.log.Error("failed to get value from storage",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	err = i.updateStateSize(programIDBytes, -prevSize)
+	if errors.Is(err, storage.ErrReadOnly) {
+		return runtime.StatusPermissionDenied
+	}
+	if err != nil {
+		i.log.Error("failed to update program state size",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	err = i.mu.Remove(context.Background(), k)
+	if err != nil {
+		i.revertStateSize(programIDBytes, -prevSize)
+	}
+	if errors.Is(err, storage.ErrReadOnly) {
+		return runtime.StatusPermissionDenied
+	}
+	if err != nil {
+		i.log.Error("failed to remove from storage",
+			zap.Error(err),
+		)
+		return runtime.StatusError
 	}
 
 	return 0
 }
 
+// readProgramID returns the ID of the program at [idPtr] in guest memory, or
+// ErrProgramMismatch if the module is bound to another program.
+func (i *Import) readProgramID(memory runtime.Memory, idPtr int64) ([]byte, error) {
+	programIDBytes, err := memory.Range(uint64(idPtr), uint64(ids.IDLen))
+	if err != nil {
+		return nil, err
+	}
+	if i.programID != ids.Empty && !bytes.Equal(programIDBytes, i.programID[:]) {
+		return nil, fmt.Errorf("%w: %s", ErrProgramMismatch, i.programID)
+	}
+	return programIDBytes, nil
+}
+
+// entrySize returns the accounted size of the entry stored at [k] for the
+// program key [keyBytes], or database.ErrNotFound if it does not exist.
+func (i *Import) entrySize(k []byte, keyBytes []byte) (int64, error) {
+	val, err := i.mu.GetValue(context.Background(), k)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(keyBytes) + len(val)), nil
+}
+
+// updateStateSize adds [delta] to the state size of the program.
+func (i *Import) updateStateSize(programIDBytes []byte, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	programID, err := ids.ToID(programIDBytes)
+	if err != nil {
+		return err
+	}
+	_, err = storage.UpdateProgramStateSize(context.Background(), i.mu, programID, delta)
+	return err
+}
+
+// revertStateSize reverts an update of the state size of the program by
+// [delta] whose write failed.
+func (i *Import) revertStateSize(programIDBytes []byte, delta int64) {
+	if err := i.updateStateSize(programIDBytes, -delta); err != nil {
+		i.log.Error("failed to revert program state size",
+			zap.Error(err),
+		)
+	}
+}
+
+// getLenFn returns the length of the value for the key in the namespace of
+// the program or runtime.StatusNotFound if the key does not exist. Values
+// larger than the max result size of the runtime are rejected with
+// runtime.StatusError.
 func (i *Import) getLenFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLength int32) int32 {
 	memory := runtime.NewMemory(runtime.NewExportClient(caller))
-	programIDBytes, err := memory.Range(uint64(idPtr), uint64(ids.IDLen))
+	programIDBytes, err := i.readProgramID(memory, idPtr)
+	if errors.Is(err, ErrProgramMismatch) {
+		return runtime.StatusPermissionDenied
+	}
 	if err != nil {
 		i.log.Error("failed to read program id from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
-	keyBytes, err := memory.Range(uint64(keyPtr), uint64(keyLength))
+	keyBytes, err := runtime.ReadResultBytes(memory, uint64(keyPtr), uint64(keyLength), i.maxResultSize)
 	if err != nil {
 		i.log.Error("failed to read key from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	k := storage.ProgramPrefixKey(programIDBytes, keyBytes)
 	val, err := i.mu.GetValue(context.Background(), k)
+	if errors.Is(err, database.ErrNotFound) {
+		return runtime.StatusNotFound
+	}
 	if err != nil {
-		if !errors.Is(err, database.ErrNotFound) {
-			i.log.Error("failed to get value from storage",
-				zap.Error(err),
-			)
-		}
-		return -1
+		i.log.Error("failed to get value from storage",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+	if uint64(len(val)) > i.maxResultSize {
+		i.log.Error("failed to get value from storage",
+			zap.Error(runtime.ErrResultTooLarge),
+			zap.Int("size", len(val)),
+			zap.Uint64("max", i.maxResultSize),
+		)
+		return runtime.StatusError
 	}
 
 	return int32(len(val))
 }
 
+// getFn writes the value for the key in the namespace of the program to
+// guest memory and returns its offset or runtime.StatusNotFound if the key
+// does not exist. Values larger than the max result size of the runtime are
+// rejected with runtime.StatusError.
 func (i *Import) getFn(caller *wasmtime.Caller, idPtr int64, keyPtr int32, keyLength int32, valLength int32) int32 {
 	memory := runtime.NewMemory(runtime.NewExportClient(caller))
-	programIDBytes, err := memory.Range(uint64(idPtr), uint64(ids.IDLen))
+	programIDBytes, err := i.readProgramID(memory, idPtr)
+	if errors.Is(err, ErrProgramMismatch) {
+		return runtime.StatusPermissionDenied
+	}
 	if err != nil {
 		i.log.Error("failed to read program id from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
-	keyBytes, err := memory.Range(uint64(keyPtr), uint64(keyLength))
+	keyBytes, err := runtime.ReadResultBytes(memory, uint64(keyPtr), uint64(keyLength), i.maxResultSize)
 	if err != nil {
 		i.log.Error("failed to read key from memory",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
 	}
 
 	k := storage.ProgramPrefixKey(programIDBytes, keyBytes)
 	val, err := i.mu.GetValue(context.Background(), k)
-	if err != nil {
-		if !errors.Is(err, database.ErrNotFound) {
-			i.log.Error("failed to get value from storage",
-				zap.Error(err),
-			)
-		}
-		return -1
+	if errors.Is(err, database.ErrNotFound) {
+		return runtime.StatusNotFound
 	}
-
 	if err != nil {
-		i.log.Error("failed to convert program id to id",
+		i.log.Error("failed to get value from storage",
 			zap.Error(err),
 		)
-		return -1
+		return runtime.StatusError
+	}
+	if uint64(len(val)) > i.maxResultSize {
+		i.log.Error("failed to get value from storage",
+			zap.Error(runtime.ErrResultTooLarge),
+			zap.Int("size", len(val)),
+			zap.Uint64("max", i.maxResultSize),
+		)
+		return runtime.StatusError
 	}
 
-	ptr, err := runtime.WriteBytes(memory, val)
+	// the guest takes ownership of the value, so it is only freed if it can
+	// not be written.
+	scratch := runtime.NewScratch(memory)
+	ptr, err := scratch.WriteBytes(val)
 	if err != nil {
-		{
-			i.log.Error("failed to write to memory",
+		i.log.Error("failed to write to memory",
+			zap.Error(err),
+		)
+		if err := scratch.Free(); err != nil {
+			i.log.Error("failed to free memory",
 				zap.Error(err),
 			)
 		}
-		return -1
+		return runtime.StatusError
 	}
 
 	return int32(ptr)
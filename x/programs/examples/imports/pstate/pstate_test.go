@@ -0,0 +1,447 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package pstate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/database/memdb"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/trace"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	smath "github.com/ava-labs/avalanchego/utils/math"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/x/merkledb"
+
+	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	programID := ids.GenerateTestID()
+	key := storage.ProgramPrefixKey(programID[:], []byte("k"))
+
+	tracer, err := trace.New(trace.Config{Enabled: false})
+	require.NoError(err)
+	db, err := merkledb.New(ctx, memdb.New(), merkledb.Config{
+		BranchFactor:              merkledb.BranchFactor16,
+		HistoryLength:             100,
+		EvictionBatchSize:         units.MiB,
+		IntermediateNodeCacheSize: units.MiB,
+		ValueNodeCacheSize:        units.MiB,
+		Tracer:                    tracer,
+	})
+	require.NoError(err)
+	require.NoError(db.Put(key, []byte("old")))
+
+	// writes "new!" to the key "k" and returns the pointer to the value read
+	// back in the same call.
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (import "state" "len" (func $len (param i64 i32 i32) (result i32)))
+	  (import "state" "get" (func $get (param i64 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (global $next (mut i32) (i32.const 1024))
+	  (data (i32.const 0) "knew!")
+	  (func (export "alloc") (param $len i32) (result i32)
+	    (global.get $next)
+	    (global.set $next (i32.add (global.get $next) (local.get $len)))
+	  )
+	  (func (export "read_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $get (local.get $id) (i32.const 0) (i32.const 1)
+	        (call $len (local.get $id) (i32.const 0) (i32.const 1))))
+	  )
+	  (func (export "write_read_guest") (param $id i64) (result i64)
+	    (if (i32.ne (call $put (local.get $id) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 4)) (i32.const 0))
+	      (then (return (i64.const -1))))
+	    (i64.extend_i32_s
+	      (call $get (local.get $id) (i32.const 0) (i32.const 1)
+	        (call $len (local.get $id) (i32.const 0) (i32.const 1))))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	mu := state.NewSimpleMutable(db)
+	call := func(fn string, wantLen uint64) []byte {
+		supported := runtime.NewSupportedImports()
+		supported.Register(Name, func() runtime.Import {
+			return New(logging.NoLog{}, mu)
+		})
+		cfg, err := runtime.NewConfigBuilder(100000).Build()
+		require.NoError(err)
+		rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+		require.NoError(rt.Initialize(ctx, wasm))
+		defer rt.Stop()
+
+		idPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+		require.NoError(err)
+		resp, err := rt.Call(ctx, fn, idPtr)
+		require.NoError(err)
+		require.Positive(int64(resp[0]))
+		val, err := rt.Memory().Range(resp[0], wantLen)
+		require.NoError(err)
+		return val
+	}
+
+	require.Equal([]byte("old"), call("read", 3))
+
+	// the write is visible within the same call
+	require.Equal([]byte("new!"), call("write_read", 4))
+
+	// and to later calls through the same mutable
+	require.Equal([]byte("new!"), call("read", 4))
+
+	// but is only persisted on commit
+	val, err := db.GetValue(ctx, key)
+	require.NoError(err)
+	require.Equal([]byte("old"), val)
+
+	require.NoError(mu.Commit(ctx))
+	val, err = db.GetValue(ctx, key)
+	require.NoError(err)
+	require.Equal([]byte("new!"), val)
+}
+
+func TestStateSize(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	programID := ids.GenerateTestID()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (import "state" "delete" (func $delete (param i64 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kabcd")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "put_guest") (param $id i64) (param $len i32) (result i64)
+	    (i64.extend_i32_s
+	      (call $put (local.get $id) (i32.const 0) (i32.const 1) (i32.const 1) (local.get $len)))
+	  )
+	  (func (export "delete_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $delete (local.get $id) (i32.const 0) (i32.const 1)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	db := utils.NewTestDB()
+	// call returns the result of [fn] and the units spent.
+	call := func(perByteCost uint64, fn string, params ...uint64) (int64, uint64) {
+		supported := runtime.NewSupportedImports()
+		supported.Register(Name, func() runtime.Import {
+			return New(logging.NoLog{}, db).WithPerByteCost(perByteCost)
+		})
+		maxUnits := uint64(100000)
+		cfg, err := runtime.NewConfigBuilder(maxUnits).Build()
+		require.NoError(err)
+		rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+		require.NoError(rt.Initialize(ctx, wasm))
+		defer rt.Stop()
+
+		idPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+		require.NoError(err)
+		resp, err := rt.Call(ctx, fn, append([]uint64{idPtr}, params...)...)
+		require.NoError(err)
+		return int64(resp[0]), maxUnits - rt.Meter().GetBalance()
+	}
+	requireSize := func(want uint64) {
+		size, err := storage.GetProgramStateSize(ctx, db, programID)
+		require.NoError(err)
+		require.Equal(want, size)
+	}
+
+	// insert "k" = "ab"
+	result, _ := call(0, "put", 2)
+	require.Zero(result)
+	requireSize(3)
+
+	// overwrite "k" = "abcd"
+	result, spent := call(0, "put", 4)
+	require.Zero(result)
+	requireSize(5)
+
+	// the key and value written are charged per byte
+	result, spentPerByte := call(100, "put", 4)
+	require.Zero(result)
+	require.Equal(spent+5*100, spentPerByte)
+	requireSize(5)
+
+	result, _ = call(0, "delete")
+	require.Zero(result)
+	requireSize(0)
+
+	result, _ = call(0, "delete")
+	require.Equal(int64(runtime.StatusNotFound), result)
+	requireSize(0)
+}
+
+func TestPutPerByteCostOverflow(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	programID := ids.GenerateTestID()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kv")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "put_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $put (local.get $id) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	db := utils.NewTestDB()
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		// the cost of the 2 bytes written overflows
+		return New(logging.NoLog{}, db).WithPerByteCost(math.MaxUint64/2 + 1)
+	})
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	idPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	require.NoError(err)
+	_, err = rt.Call(ctx, "put", idPtr)
+	require.ErrorContains(err, smath.ErrOverflow.Error())
+
+	_, err = db.GetValue(ctx, storage.ProgramPrefixKey(programID[:], []byte("k")))
+	require.ErrorIs(err, database.ErrNotFound)
+}
+
+// failInsertState fails to insert [key].
+type failInsertState struct {
+	state.Mutable
+	key []byte
+}
+
+func (s *failInsertState) Insert(ctx context.Context, key []byte, value []byte) error {
+	if bytes.Equal(key, s.key) {
+		return errors.New("insert failed")
+	}
+	return s.Mutable.Insert(ctx, key, value)
+}
+
+func TestPutInsertErrorStateSize(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	programID := ids.GenerateTestID()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kv")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "put_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $put (local.get $id) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	db := utils.NewTestDB()
+	mu := &failInsertState{
+		Mutable: db,
+		key:     storage.ProgramPrefixKey(programID[:], []byte("k")),
+	}
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{}, mu)
+	})
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	idPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	require.NoError(err)
+	resp, err := rt.Call(ctx, "put", idPtr)
+	require.NoError(err)
+	require.Equal(int64(runtime.StatusError), int64(resp[0]))
+
+	// the size of the failed write is not accounted
+	size, err := storage.GetProgramStateSize(ctx, db, programID)
+	require.NoError(err)
+	require.Zero(size)
+}
+
+func TestForProgram(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	programID := ids.GenerateTestID()
+	otherID := ids.GenerateTestID()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (import "state" "len" (func $len (param i64 i32 i32) (result i32)))
+	  (import "state" "delete" (func $delete (param i64 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "kv")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "put_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $put (local.get $id) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1)))
+	  )
+	  (func (export "len_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $len (local.get $id) (i32.const 0) (i32.const 1)))
+	  )
+	  (func (export "delete_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s
+	      (call $delete (local.get $id) (i32.const 0) (i32.const 1)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	db := utils.NewTestDB()
+	require.NoError(db.Insert(ctx, storage.ProgramPrefixKey(otherID[:], []byte("k")), []byte("v")))
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{}, db).ForProgram(programID)
+	})
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	// the bound program accesses its own namespace
+	idPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	require.NoError(err)
+	resp, err := rt.Call(ctx, "put", idPtr)
+	require.NoError(err)
+	require.Zero(resp[0])
+	size, err := storage.GetProgramStateSize(ctx, db, programID)
+	require.NoError(err)
+	require.Equal(uint64(2), size)
+
+	// the namespace of another program is denied
+	otherPtr, err := runtime.WriteBytes(rt.Memory(), otherID[:])
+	require.NoError(err)
+	for _, fn := range []string{"put", "len", "delete"} {
+		resp, err = rt.Call(ctx, fn, otherPtr)
+		require.NoError(err)
+		require.Equal(int64(runtime.StatusPermissionDenied), int64(resp[0]), fn)
+	}
+	v, err := db.GetValue(ctx, storage.ProgramPrefixKey(otherID[:], []byte("k")))
+	require.NoError(err)
+	require.Equal([]byte("v"), v)
+	size, err = storage.GetProgramStateSize(ctx, db, otherID)
+	require.NoError(err)
+	require.Zero(size)
+}
+
+func TestMaxResultSize(t *testing.T) {
+	ctx := context.Background()
+	programID := ids.GenerateTestID()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "len" (func $len (param i64 i32 i32) (result i32)))
+	  (import "state" "get" (func $get (param i64 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "k")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 1024
+	  )
+	  (func (export "len_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s (call $len (local.get $id) (i32.const 0) (i32.const 1)))
+	  )
+	  (func (export "get_guest") (param $id i64) (result i64)
+	    (i64.extend_i32_s (call $get (local.get $id) (i32.const 0) (i32.const 1) (i32.const 9)))
+	  )
+	)
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		maxSize uint64
+		wantLen int64
+		wantGet int64
+	}{
+		{
+			name:    "within limit",
+			maxSize: 9,
+			wantLen: 9,
+			wantGet: 1024,
+		},
+		{
+			name:    "exceeds limit",
+			maxSize: 8,
+			wantLen: runtime.StatusError,
+			wantGet: runtime.StatusError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			db := utils.NewTestDB()
+			require.NoError(db.Insert(ctx, storage.ProgramPrefixKey(programID[:], []byte("k")), []byte("123456789")))
+			supported := runtime.NewSupportedImports()
+			supported.Register(Name, func() runtime.Import {
+				return New(logging.NoLog{}, db)
+			})
+			cfg, err := runtime.NewConfigBuilder(100000).
+				WithMaxResultSize(tt.maxSize).
+				Build()
+			require.NoError(err)
+			rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+			require.NoError(rt.Initialize(ctx, wasm))
+			defer rt.Stop()
+
+			idPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+			require.NoError(err)
+			resp, err := rt.Call(ctx, "len", idPtr)
+			require.NoError(err)
+			require.Equal(tt.wantLen, int64(resp[0]))
+			resp, err = rt.Call(ctx, "get", idPtr)
+			require.NoError(err)
+			require.Equal(tt.wantGet, int64(resp[0]))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package random
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/consts"
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "random"
+
+	u64Cost   = 100
+	bytesCost = 500
+	// blockCost is charged by bytes for each block of [hashing.HashLen]
+	// bytes written, as each block is a separate hash of the seed.
+	blockCost = 100
+)
+
+var (
+	_ runtime.Import = &Import{}
+
+	ErrDisabled = errs.New(errs.ErrValidation, "random import is disabled")
+)
+
+// New returns a random module which supplies the guest with deterministic
+// pseudorandom values derived from [chainID], [height] and [actionID]. Every
+// node executing the same action will observe the same values. If [enabled]
+// is false any call to the module will trap.
+func New(log logging.Logger, chainID ids.ID, height uint64, actionID ids.ID, enabled bool) runtime.Import {
+	seed := make([]byte, 0, consts.IDLen*2+consts.Uint64Len)
+	seed = append(seed, chainID[:]...)
+	seed = binary.BigEndian.AppendUint64(seed, height)
+	seed = append(seed, actionID[:]...)
+	return &Import{
+		log:     log,
+		seed:    hashing.ComputeHash256(seed),
+		enabled: enabled,
+	}
+}
+
+type Import struct {
+	log        logging.Logger
+	seed       []byte
+	counter    uint64
+	enabled    bool
+	meter      runtime.Meter
+	registered bool
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.meter = meter
+	i.registered = true
+
+	if err := link.MeteredFuncWrap(meter, u64Cost, Name, "u64", i.u64Fn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, bytesCost, Name, "bytes", i.bytesFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// u64Fn returns the next pseudorandom uint64.
+func (i *Import) u64Fn(*wasmtime.Caller) (int64, *wasmtime.Trap) {
+	if !i.enabled {
+		return 0, wasmtime.NewTrap(ErrDisabled.Error())
+	}
+
+	return int64(binary.BigEndian.Uint64(i.next())), nil
+}
+
+// bytesFn fills [length] bytes of guest memory at [ptr] with pseudorandom
+// bytes. In addition to [bytesCost], [blockCost] is charged for each started
+// block of [hashing.HashLen] bytes. Returns 0 on success and -1 if the length
+// is invalid or the memory could not be written.
+func (i *Import) bytesFn(caller *wasmtime.Caller, ptr int32, length int32) (int32, *wasmtime.Trap) {
+	if !i.enabled {
+		return 0, wasmtime.NewTrap(ErrDisabled.Error())
+	}
+
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	size, err := memory.Len()
+	if err != nil || ptr < 0 || length < 0 || uint64(ptr)+uint64(length) > size {
+		i.log.Error("invalid random bytes range",
+			zap.Int32("ptr", ptr),
+			zap.Int32("length", length),
+		)
+		return -1, nil
+	}
+
+	blocks := (uint64(length) + hashing.HashLen - 1) / hashing.HashLen
+	if _, err := i.meter.Spend(blocks * blockCost); err != nil {
+		return -1, wasmtime.NewTrap(fmt.Sprintf("%s: %s", err, Name))
+	}
+
+	buf := make([]byte, 0, length)
+	for len(buf) < int(length) {
+		buf = append(buf, i.next()...)
+	}
+
+	err = memory.Write(uint64(ptr), buf[:length])
+	if err != nil {
+		i.log.Error("failed to write random bytes to memory",
+			zap.Error(err),
+		)
+		return -1, nil
+	}
+
+	return 0, nil
+}
+
+// next returns the next 32 pseudorandom bytes.
+func (i *Import) next() []byte {
+	buf := make([]byte, 0, len(i.seed)+consts.Uint64Len)
+	buf = append(buf, i.seed...)
+	buf = binary.BigEndian.AppendUint64(buf, i.counter)
+	i.counter++
+	return hashing.ComputeHash256(buf)
+}
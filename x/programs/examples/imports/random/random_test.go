@@ -0,0 +1,98 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package random
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+func TestRandom(t *testing.T) {
+	require := require.New(t)
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "random" "u64" (func $u64 (result i64)))
+	  (import "random" "bytes" (func $bytes (param i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "u64_guest") (result i64)
+	    (call $u64)
+	  )
+	  (func (export "bytes_guest") (param i32 i32) (result i32)
+	    (call $bytes (local.get 0) (local.get 1))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	chainID := ids.GenerateTestID()
+	actionID := ids.GenerateTestID()
+
+	newRuntime := func(height uint64, enabled bool) runtime.Runtime {
+		cfg, err := runtime.NewConfigBuilder(10000).Build()
+		require.NoError(err)
+		supported := runtime.NewSupportedImports()
+		supported.Register(Name, func() runtime.Import {
+			return New(logging.NoLog{}, chainID, height, actionID, enabled)
+		})
+		rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+		require.NoError(rt.Initialize(context.Background(), wasm))
+		return rt
+	}
+
+	call := func(rt runtime.Runtime) uint64 {
+		resp, err := rt.Call(context.Background(), "u64")
+		require.NoError(err)
+		return resp[0]
+	}
+
+	rt1 := newRuntime(1, true)
+	rt2 := newRuntime(1, true)
+	first := call(rt1)
+	// same context produces the same sequence
+	require.Equal(first, call(rt2))
+	require.NotEqual(first, call(rt1))
+
+	// different height produces a different sequence
+	require.NotEqual(first, call(newRuntime(2, true)))
+
+	resp, err := rt1.Call(context.Background(), "bytes", 0, 40)
+	require.NoError(err)
+	require.Equal(uint64(0), resp[0])
+	b1, err := rt1.Memory().Range(0, 40)
+	require.NoError(err)
+	require.NotEqual(make([]byte, 40), b1)
+
+	// invalid length
+	resp, err = rt1.Call(context.Background(), "bytes", 0, uint64(0xffffffff))
+	require.NoError(err)
+	require.Equal(uint64(0xffffffffffffffff), resp[0])
+
+	// the charge scales with the number of blocks written
+	spent := func(length uint64) uint64 {
+		rt := newRuntime(1, true)
+		before := rt.Meter().GetBalance()
+		resp, err := rt.Call(context.Background(), "bytes", 0, length)
+		require.NoError(err)
+		require.Equal(uint64(0), resp[0])
+		return before - rt.Meter().GetBalance()
+	}
+	oneBlock := spent(1)
+	require.Equal(oneBlock, spent(32))
+	require.Equal(oneBlock+blockCost, spent(33))
+	require.Equal(oneBlock+9*blockCost, spent(10*32))
+
+	// disabled module traps
+	_, err = newRuntime(1, false).Call(context.Background(), "u64")
+	require.ErrorContains(err, ErrDisabled.Error())
+}
@@ -0,0 +1,381 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package token
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	smath "github.com/ava-labs/avalanchego/utils/math"
+
+	"github.com/ava-labs/hypersdk/consts"
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "token"
+
+	mintCost        = 2000
+	burnCost        = 2000
+	transferCost    = 3000
+	balanceOfCost   = 500
+	totalSupplyCost = 500
+
+	totalSupplyPrefix = 0x0
+	balancePrefix     = 0x1
+
+	// recordKeyOffset is the length of the namespace of a record key,
+	// [tokenPrefix|programID], see storage.ProgramTokenKey.
+	recordKeyOffset = 1 + ids.IDLen
+)
+
+var (
+	_ runtime.Import = &Import{}
+
+	ErrInsufficientBalance = errs.New(errs.ErrValidation, "insufficient balance")
+	ErrInvalidAmount       = errs.New(errs.ErrValidation, "invalid amount")
+)
+
+// New returns a token module which implements a standard fungible token for
+// the program [programID]. The records of the token are stored under
+// storage.ProgramTokenKey, which the state import can not write, and the
+// program is set by the host, see ForProgram, so a program can only operate
+// on its own token. Authorization of the operations is the responsibility of
+// the calling program.
+func New(log logging.Logger, mu state.Mutable, programID ids.ID) *Import {
+	return &Import{mu: mu, log: log, programID: programID}
+}
+
+type Import struct {
+	mu         state.Mutable
+	log        logging.Logger
+	programID  ids.ID
+	meter      runtime.Meter
+	registered bool
+}
+
+// ForProgram returns an unregistered copy of the module over the same state
+// in the namespace of the program [programID].
+func (i *Import) ForProgram(programID ids.ID) *Import {
+	return New(i.log, i.mu, programID)
+}
+
+// SetState sets the state of the module, see program.StateImport.
+func (i *Import) SetState(mu state.Mutable) {
+	i.mu = mu
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.meter = meter
+	i.registered = true
+
+	if err := link.MeteredFuncWrap(meter, mintCost, Name, "mint", i.mintFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, burnCost, Name, "burn", i.burnFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, transferCost, Name, "transfer", i.transferFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, balanceOfCost, Name, "balance_of", i.balanceOfFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, totalSupplyCost, Name, "total_supply", i.totalSupplyFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TotalSupplyKey returns the state key of the total supply of the token
+// program [programID].
+func TotalSupplyKey(programID []byte) []byte {
+	return storage.ProgramTokenKey(programID, []byte{totalSupplyPrefix})
+}
+
+// BalanceKey returns the state key of the balance of [owner] in the token
+// program [programID].
+func BalanceKey(programID []byte, owner ed25519.PublicKey) []byte {
+	k := make([]byte, 1+ed25519.PublicKeyLen)
+	k[0] = balancePrefix
+	copy(k[1:], owner[:])
+	return storage.ProgramTokenKey(programID, k)
+}
+
+// mintFn credits [amount] to the balance of [to]. Returns 0 on success,
+// runtime.StatusPermissionDenied if the state is read-only or
+// runtime.StatusError on failure.
+func (i *Import) mintFn(caller *wasmtime.Caller, toPtr int32, amount int64) int32 {
+	if amount < 0 {
+		i.log.Error("failed to update balance",
+			zap.Error(ErrInvalidAmount),
+		)
+		return runtime.StatusError
+	}
+	ctx := context.Background()
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	to, err := readPublicKey(memory, toPtr)
+	if err != nil {
+		i.log.Error("failed to read recipient from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	if err := i.add(ctx, TotalSupplyKey(i.programID[:]), uint64(amount)); err != nil {
+		i.log.Error("failed to update total supply",
+			zap.Error(err),
+		)
+		return writeStatus(err)
+	}
+	if err := i.add(ctx, BalanceKey(i.programID[:], to), uint64(amount)); err != nil {
+		i.log.Error("failed to update balance",
+			zap.Error(err),
+		)
+		return writeStatus(err)
+	}
+
+	return 0
+}
+
+// burnFn debits [amount] from the balance of [from]. Returns 0 on success,
+// runtime.StatusPermissionDenied if the state is read-only or
+// runtime.StatusError on failure.
+func (i *Import) burnFn(caller *wasmtime.Caller, fromPtr int32, amount int64) int32 {
+	if amount < 0 {
+		i.log.Error("failed to update balance",
+			zap.Error(ErrInvalidAmount),
+		)
+		return runtime.StatusError
+	}
+	ctx := context.Background()
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	from, err := readPublicKey(memory, fromPtr)
+	if err != nil {
+		i.log.Error("failed to read owner from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	if err := i.sub(ctx, BalanceKey(i.programID[:], from), uint64(amount)); err != nil {
+		i.log.Error("failed to update balance",
+			zap.Error(err),
+		)
+		return writeStatus(err)
+	}
+	if err := i.sub(ctx, TotalSupplyKey(i.programID[:]), uint64(amount)); err != nil {
+		i.log.Error("failed to update total supply",
+			zap.Error(err),
+		)
+		return writeStatus(err)
+	}
+
+	return 0
+}
+
+// transferFn moves [amount] from the balance of [from] to the balance of
+// [to]. Returns 0 on success, runtime.StatusPermissionDenied if the state is
+// read-only or runtime.StatusError on failure.
+func (i *Import) transferFn(caller *wasmtime.Caller, fromPtr int32, toPtr int32, amount int64) int32 {
+	if amount < 0 {
+		i.log.Error("failed to update balance",
+			zap.Error(ErrInvalidAmount),
+		)
+		return runtime.StatusError
+	}
+	ctx := context.Background()
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	from, err := readPublicKey(memory, fromPtr)
+	if err != nil {
+		i.log.Error("failed to read sender from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+	to, err := readPublicKey(memory, toPtr)
+	if err != nil {
+		i.log.Error("failed to read recipient from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	// verify the credit before debiting so a failure leaves state untouched
+	toKey := BalanceKey(i.programID[:], to)
+	toBalance, err := i.get(ctx, toKey)
+	if err != nil {
+		i.log.Error("failed to get balance",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+	if from != to {
+		if _, err := smath.Add64(toBalance, uint64(amount)); err != nil {
+			i.log.Error("failed to credit balance",
+				zap.Error(err),
+			)
+			return runtime.StatusError
+		}
+	}
+
+	if err := i.sub(ctx, BalanceKey(i.programID[:], from), uint64(amount)); err != nil {
+		i.log.Error("failed to debit balance",
+			zap.Error(err),
+		)
+		return writeStatus(err)
+	}
+	if err := i.add(ctx, toKey, uint64(amount)); err != nil {
+		i.log.Error("failed to credit balance",
+			zap.Error(err),
+		)
+		return writeStatus(err)
+	}
+
+	return 0
+}
+
+// balanceOfFn returns the balance of [owner] or runtime.StatusError on
+// failure.
+func (i *Import) balanceOfFn(caller *wasmtime.Caller, ownerPtr int32) int64 {
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	owner, err := readPublicKey(memory, ownerPtr)
+	if err != nil {
+		i.log.Error("failed to read owner from memory",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	balance, err := i.get(context.Background(), BalanceKey(i.programID[:], owner))
+	if err != nil {
+		i.log.Error("failed to get balance",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	return int64(balance)
+}
+
+// totalSupplyFn returns the total supply or runtime.StatusError on failure.
+func (i *Import) totalSupplyFn(_ *wasmtime.Caller) int64 {
+	supply, err := i.get(context.Background(), TotalSupplyKey(i.programID[:]))
+	if err != nil {
+		i.log.Error("failed to get total supply",
+			zap.Error(err),
+		)
+		return runtime.StatusError
+	}
+
+	return int64(supply)
+}
+
+func (i *Import) get(ctx context.Context, k []byte) (uint64, error) {
+	v, err := i.mu.GetValue(ctx, k)
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(v) != consts.Uint64Len {
+		return 0, fmt.Errorf("invalid value length: %d", len(v))
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// put stores [value] at [k]. New records are accounted to the state size of
+// the program as the writes of the state import are, see
+// storage.GetProgramStateSize.
+func (i *Import) put(ctx context.Context, k []byte, value uint64) error {
+	_, err := i.mu.GetValue(ctx, k)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+	var size int64
+	if errors.Is(err, database.ErrNotFound) {
+		// like the keys of the state import, the key is accounted without
+		// its namespace
+		size = int64(len(k) - recordKeyOffset + consts.Uint64Len)
+		if _, err := storage.UpdateProgramStateSize(ctx, i.mu, i.programID, size); err != nil {
+			return err
+		}
+	}
+
+	v := make([]byte, consts.Uint64Len)
+	binary.BigEndian.PutUint64(v, value)
+	err = i.mu.Insert(ctx, k, v)
+	if err != nil && size != 0 {
+		if _, err := storage.UpdateProgramStateSize(ctx, i.mu, i.programID, -size); err != nil {
+			i.log.Error("failed to revert program state size",
+				zap.Error(err),
+			)
+		}
+	}
+	return err
+}
+
+func (i *Import) add(ctx context.Context, k []byte, amount uint64) error {
+	value, err := i.get(ctx, k)
+	if err != nil {
+		return err
+	}
+	value, err = smath.Add64(value, amount)
+	if err != nil {
+		return err
+	}
+	return i.put(ctx, k, value)
+}
+
+func (i *Import) sub(ctx context.Context, k []byte, amount uint64) error {
+	value, err := i.get(ctx, k)
+	if err != nil {
+		return err
+	}
+	remaining, err := smath.Sub(value, amount)
+	if err != nil {
+		return fmt.Errorf("%w: %d < %d", ErrInsufficientBalance, value, amount)
+	}
+	return i.put(ctx, k, remaining)
+}
+
+// writeStatus returns the status of a failed write to state,
+// runtime.StatusPermissionDenied if the state is read-only.
+func writeStatus(err error) int32 {
+	if errors.Is(err, storage.ErrReadOnly) {
+		return runtime.StatusPermissionDenied
+	}
+	return runtime.StatusError
+}
+
+func readPublicKey(memory runtime.Memory, ptr int32) (ed25519.PublicKey, error) {
+	b, err := memory.Range(uint64(ptr), ed25519.PublicKeyLen)
+	if err != nil {
+		return ed25519.EmptyPublicKey, err
+	}
+	return ed25519.PublicKey(b), nil
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package token
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+func TestToken(t *testing.T) {
+	require := require.New(t)
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "token" "mint" (func $mint (param i32 i64) (result i32)))
+	  (import "token" "burn" (func $burn (param i32 i64) (result i32)))
+	  (import "token" "transfer" (func $transfer (param i32 i32 i64) (result i32)))
+	  (import "token" "balance_of" (func $balance_of (param i32) (result i64)))
+	  (import "token" "total_supply" (func $total_supply (result i64)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "mint_guest") (param i32 i64) (result i32)
+	    (call $mint (local.get 0) (local.get 1))
+	  )
+	  (func (export "burn_guest") (param i32 i64) (result i32)
+	    (call $burn (local.get 0) (local.get 1))
+	  )
+	  (func (export "transfer_guest") (param i32 i32 i64) (result i32)
+	    (call $transfer (local.get 0) (local.get 1) (local.get 2))
+	  )
+	  (func (export "balance_of_guest") (param i32) (result i64)
+	    (call $balance_of (local.get 0))
+	  )
+	  (func (export "total_supply_guest") (result i64)
+	    (call $total_supply)
+	  )
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 0
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	db := utils.NewTestDB()
+	log := logging.NoLog{}
+	programID := ids.GenerateTestID()
+
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(log, db, programID)
+	})
+
+	cfg, err := runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt := runtime.New(log, cfg, supported.Imports())
+	err = rt.Initialize(context.Background(), wasm)
+	require.NoError(err)
+
+	mem := rt.Memory()
+	alice := ed25519.PublicKey{1}
+	bob := ed25519.PublicKey{2}
+	require.NoError(mem.Write(0, alice[:]))
+	require.NoError(mem.Write(ed25519.PublicKeyLen, bob[:]))
+
+	alicePtr := uint64(0)
+	bobPtr := uint64(ed25519.PublicKeyLen)
+
+	balanceOf := func(ptr uint64) uint64 {
+		result, err := rt.Call(context.Background(), "balance_of", ptr)
+		require.NoError(err)
+		return result[0]
+	}
+
+	// mint
+	result, err := rt.Call(context.Background(), "mint", alicePtr, 100)
+	require.NoError(err)
+	require.Equal(uint64(0), result[0])
+	require.Equal(uint64(100), balanceOf(alicePtr))
+
+	// transfer
+	result, err = rt.Call(context.Background(), "transfer", alicePtr, bobPtr, 30)
+	require.NoError(err)
+	require.Equal(uint64(0), result[0])
+	require.Equal(uint64(70), balanceOf(alicePtr))
+	require.Equal(uint64(30), balanceOf(bobPtr))
+
+	// transfer more than the balance fails and leaves state untouched
+	result, err = rt.Call(context.Background(), "transfer", bobPtr, alicePtr, 31)
+	require.NoError(err)
+	require.Equal(uint64(0xffffffffffffffff), result[0])
+	require.Equal(uint64(70), balanceOf(alicePtr))
+	require.Equal(uint64(30), balanceOf(bobPtr))
+
+	// negative amounts are rejected
+	result, err = rt.Call(context.Background(), "mint", alicePtr, 0xffffffffffffffff)
+	require.NoError(err)
+	require.Equal(uint64(0xffffffffffffffff), result[0])
+
+	// burn
+	result, err = rt.Call(context.Background(), "burn", bobPtr, 10)
+	require.NoError(err)
+	require.Equal(uint64(0), result[0])
+	require.Equal(uint64(20), balanceOf(bobPtr))
+
+	result, err = rt.Call(context.Background(), "total_supply")
+	require.NoError(err)
+	require.Equal(uint64(90), result[0])
+
+	// balances are stored at the standard layout
+	v, err := db.GetValue(context.Background(), BalanceKey(programID[:], alice))
+	require.NoError(err)
+	require.Equal([]byte{0, 0, 0, 0, 0, 0, 0, 70}, v)
+
+	// the records are accounted to the state size of the program, the
+	// balances of alice and bob and the total supply
+	size, err := storage.GetProgramStateSize(context.Background(), db, programID)
+	require.NoError(err)
+	require.Equal(uint64(2*(1+ed25519.PublicKeyLen+8)+1+8), size)
+
+	// a program can not reach the token of another program
+	other := runtime.NewSupportedImports()
+	other.Register(Name, func() runtime.Import {
+		return New(log, db, programID).ForProgram(ids.GenerateTestID())
+	})
+	cfg, err = runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt = runtime.New(log, cfg, other.Imports())
+	err = rt.Initialize(context.Background(), wasm)
+	require.NoError(err)
+	require.NoError(rt.Memory().Write(0, alice[:]))
+	require.Equal(uint64(0), balanceOf(alicePtr))
+
+	// writes to read-only state are denied
+	readonly := runtime.NewSupportedImports()
+	readonly.Register(Name, func() runtime.Import {
+		return New(log, storage.NewReadOnlyState(db), programID)
+	})
+	cfg, err = runtime.NewConfigBuilder(100000).Build()
+	require.NoError(err)
+	rt = runtime.New(log, cfg, readonly.Imports())
+	err = rt.Initialize(context.Background(), wasm)
+	require.NoError(err)
+	require.NoError(rt.Memory().Write(0, alice[:]))
+	result, err = rt.Call(context.Background(), "mint", alicePtr, 1)
+	require.NoError(err)
+	require.Equal(int64(runtime.StatusPermissionDenied), int64(result[0]))
+	require.Equal(uint64(70), balanceOf(alicePtr))
+}
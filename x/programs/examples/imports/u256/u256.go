@@ -0,0 +1,203 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package u256
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const (
+	Name = "u256"
+
+	// Len is the length of a 32 byte big-endian encoded value.
+	Len = 32
+
+	addCost = 100
+	subCost = 100
+	mulCost = 300
+	divCost = 500
+	cmpCost = 50
+)
+
+var (
+	_ runtime.Import = &Import{}
+
+	ErrOverflow     = errs.New(errs.ErrValidation, "overflow")
+	ErrDivideByZero = errs.New(errs.ErrValidation, "divide by zero")
+
+	// maxValue is 2^256
+	maxValue = new(big.Int).Lsh(big.NewInt(1), 8*Len)
+)
+
+// New returns a module which performs 256-bit unsigned integer arithmetic
+// over 32 byte big-endian values in guest memory.
+func New(log logging.Logger) runtime.Import {
+	return &Import{log: log}
+}
+
+type Import struct {
+	log        logging.Logger
+	meter      runtime.Meter
+	registered bool
+}
+
+func (i *Import) Name() string {
+	return Name
+}
+
+func (i *Import) Register(link runtime.Link, meter runtime.Meter, _ runtime.SupportedImports) error {
+	if i.registered {
+		return fmt.Errorf("import module already registered: %q", Name)
+	}
+	i.meter = meter
+	i.registered = true
+
+	if err := link.MeteredFuncWrap(meter, addCost, Name, "add", i.addFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, subCost, Name, "sub", i.subFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, mulCost, Name, "mul", i.mulFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, divCost, Name, "div", i.divFn); err != nil {
+		return err
+	}
+	if err := link.MeteredFuncWrap(meter, cmpCost, Name, "cmp", i.cmpFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addFn writes a + b to [outPtr]. Returns 0 on success and -1 on overflow or
+// failure.
+func (i *Import) addFn(caller *wasmtime.Caller, aPtr, bPtr, outPtr int32) int32 {
+	return i.binaryOp(caller, "add", aPtr, bPtr, outPtr, func(a, b *big.Int) (*big.Int, error) {
+		return a.Add(a, b), nil
+	})
+}
+
+// subFn writes a - b to [outPtr]. Returns 0 on success and -1 on underflow or
+// failure.
+func (i *Import) subFn(caller *wasmtime.Caller, aPtr, bPtr, outPtr int32) int32 {
+	return i.binaryOp(caller, "sub", aPtr, bPtr, outPtr, func(a, b *big.Int) (*big.Int, error) {
+		return a.Sub(a, b), nil
+	})
+}
+
+// mulFn writes a * b to [outPtr]. Returns 0 on success and -1 on overflow or
+// failure.
+func (i *Import) mulFn(caller *wasmtime.Caller, aPtr, bPtr, outPtr int32) int32 {
+	return i.binaryOp(caller, "mul", aPtr, bPtr, outPtr, func(a, b *big.Int) (*big.Int, error) {
+		return a.Mul(a, b), nil
+	})
+}
+
+// divFn writes a / b rounded down to [outPtr]. Returns 0 on success and -1 on
+// division by zero or failure.
+func (i *Import) divFn(caller *wasmtime.Caller, aPtr, bPtr, outPtr int32) int32 {
+	return i.binaryOp(caller, "div", aPtr, bPtr, outPtr, func(a, b *big.Int) (*big.Int, error) {
+		if b.Sign() == 0 {
+			return nil, ErrDivideByZero
+		}
+		return a.Quo(a, b), nil
+	})
+}
+
+// cmpFn returns -1 if a < b, 0 if a == b and 1 if a > b. As every result is
+// valid the call traps if the values can not be read from memory.
+func (i *Import) cmpFn(caller *wasmtime.Caller, aPtr, bPtr int32) (int32, *wasmtime.Trap) {
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	a, err := read(memory, aPtr)
+	if err != nil {
+		return 0, wasmtime.NewTrap(fmt.Sprintf("failed to read value from memory: %s", err))
+	}
+	b, err := read(memory, bPtr)
+	if err != nil {
+		return 0, wasmtime.NewTrap(fmt.Sprintf("failed to read value from memory: %s", err))
+	}
+	return int32(a.Cmp(b)), nil
+}
+
+func (i *Import) binaryOp(
+	caller *wasmtime.Caller,
+	name string,
+	aPtr,
+	bPtr,
+	outPtr int32,
+	op func(a, b *big.Int) (*big.Int, error),
+) int32 {
+	memory := runtime.NewMemory(runtime.NewExportClient(caller))
+	a, err := read(memory, aPtr)
+	if err != nil {
+		i.log.Error("failed to read value from memory",
+			zap.String("op", name),
+			zap.Error(err),
+		)
+		return -1
+	}
+	b, err := read(memory, bPtr)
+	if err != nil {
+		i.log.Error("failed to read value from memory",
+			zap.String("op", name),
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	result, err := op(a, b)
+	if err == nil && (result.Sign() < 0 || result.Cmp(maxValue) >= 0) {
+		err = ErrOverflow
+	}
+	if err != nil {
+		i.log.Debug("u256 operation failed",
+			zap.String("op", name),
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	buf := make([]byte, Len)
+	result.FillBytes(buf)
+	err = write(memory, outPtr, buf)
+	if err != nil {
+		i.log.Error("failed to write result to memory",
+			zap.String("op", name),
+			zap.Error(err),
+		)
+		return -1
+	}
+
+	return 0
+}
+
+func write(memory runtime.Memory, ptr int32, buf []byte) error {
+	if ptr < 0 {
+		return runtime.ErrInvalidMemoryAddress
+	}
+	return memory.Write(uint64(ptr), buf)
+}
+
+func read(memory runtime.Memory, ptr int32) (*big.Int, error) {
+	if ptr < 0 {
+		return nil, runtime.ErrInvalidMemoryAddress
+	}
+	buf, err := memory.Range(uint64(ptr), Len)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package u256
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+func TestU256(t *testing.T) {
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "u256" "add" (func $add (param i32 i32 i32) (result i32)))
+	  (import "u256" "sub" (func $sub (param i32 i32 i32) (result i32)))
+	  (import "u256" "mul" (func $mul (param i32 i32 i32) (result i32)))
+	  (import "u256" "div" (func $div (param i32 i32 i32) (result i32)))
+	  (import "u256" "cmp" (func $cmp (param i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "add_guest") (param i32 i32 i32) (result i32)
+	    (call $add (local.get 0) (local.get 1) (local.get 2))
+	  )
+	  (func (export "sub_guest") (param i32 i32 i32) (result i32)
+	    (call $sub (local.get 0) (local.get 1) (local.get 2))
+	  )
+	  (func (export "mul_guest") (param i32 i32 i32) (result i32)
+	    (call $mul (local.get 0) (local.get 1) (local.get 2))
+	  )
+	  (func (export "div_guest") (param i32 i32 i32) (result i32)
+	    (call $div (local.get 0) (local.get 1) (local.get 2))
+	  )
+	  (func (export "cmp_guest") (param i32 i32) (result i32)
+	    (call $cmp (local.get 0) (local.get 1))
+	  )
+	)
+	`)
+	require.NoError(t, err)
+
+	max := new(big.Int).Sub(maxValue, big.NewInt(1))
+	tests := []struct {
+		name    string
+		fn      string
+		a, b    *big.Int
+		want    *big.Int
+		wantErr bool
+	}{
+		{name: "add", fn: "add", a: big.NewInt(2), b: big.NewInt(3), want: big.NewInt(5)},
+		{name: "add overflow", fn: "add", a: max, b: big.NewInt(1), wantErr: true},
+		{name: "sub", fn: "sub", a: big.NewInt(3), b: big.NewInt(2), want: big.NewInt(1)},
+		{name: "sub underflow", fn: "sub", a: big.NewInt(2), b: big.NewInt(3), wantErr: true},
+		{name: "mul", fn: "mul", a: new(big.Int).Lsh(big.NewInt(1), 128), b: big.NewInt(3), want: new(big.Int).Lsh(big.NewInt(3), 128)},
+		{name: "mul overflow", fn: "mul", a: max, b: big.NewInt(2), wantErr: true},
+		{name: "div", fn: "div", a: big.NewInt(7), b: big.NewInt(2), want: big.NewInt(3)},
+		{name: "div by zero", fn: "div", a: big.NewInt(7), b: big.NewInt(0), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			rt := newRuntime(t, wasm)
+			defer rt.Stop()
+
+			mem := rt.Memory()
+			writeValue(t, mem, 0, tt.a)
+			writeValue(t, mem, Len, tt.b)
+			resp, err := rt.Call(context.Background(), tt.fn, 0, Len, 2*Len)
+			require.NoError(err)
+			if tt.wantErr {
+				require.Equal(int32(-1), int32(resp[0]))
+				return
+			}
+			require.Equal(int32(0), int32(resp[0]))
+			out, err := mem.Range(2*Len, Len)
+			require.NoError(err)
+			require.Equal(tt.want, new(big.Int).SetBytes(out))
+		})
+	}
+
+	t.Run("cmp", func(t *testing.T) {
+		require := require.New(t)
+		rt := newRuntime(t, wasm)
+		defer rt.Stop()
+
+		mem := rt.Memory()
+		writeValue(t, mem, 0, big.NewInt(1))
+		writeValue(t, mem, Len, max)
+		for _, c := range []struct {
+			a, b uint64
+			want int32
+		}{{0, Len, -1}, {Len, 0, 1}, {Len, Len, 0}} {
+			resp, err := rt.Call(context.Background(), "cmp", c.a, c.b)
+			require.NoError(err)
+			require.Equal(c.want, int32(resp[0]))
+		}
+
+		// invalid memory traps
+		_, err := rt.Call(context.Background(), "cmp", 0, runtime.MemoryPageSize)
+		require.ErrorContains(err, "failed to read value from memory")
+	})
+}
+
+func newRuntime(t *testing.T, wasm []byte) runtime.Runtime {
+	supported := runtime.NewSupportedImports()
+	supported.Register(Name, func() runtime.Import {
+		return New(logging.NoLog{})
+	})
+	cfg, err := runtime.NewConfigBuilder(10000).Build()
+	require.NoError(t, err)
+	rt := runtime.New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(t, rt.Initialize(context.Background(), wasm))
+	return rt
+}
+
+func writeValue(t *testing.T, mem runtime.Memory, offset uint64, v *big.Int) {
+	buf := make([]byte, Len)
+	v.FillBytes(buf)
+	require.NoError(t, mem.Write(offset, buf))
+}
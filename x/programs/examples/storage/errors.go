@@ -0,0 +1,15 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package storage
+
+import "github.com/ava-labs/hypersdk/x/programs/errs"
+
+var (
+	ErrInvalidVersion  = errs.New(errs.ErrState, "invalid program version")
+	ErrInvalidChunk    = errs.New(errs.ErrState, "invalid chunk")
+	ErrProgramTooLarge = errs.New(errs.ErrValidation, "program too large")
+	ErrReadOnly        = errs.New(errs.ErrState, "state is read-only")
+	ErrKeyNotDeclared  = errs.New(errs.ErrState, "key not declared")
+	ErrInvalidSize     = errs.New(errs.ErrState, "invalid program state size")
+)
@@ -0,0 +1,135 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+
+	"github.com/ava-labs/hypersdk/state"
+)
+
+var _ state.Mutable = (*RecordingState)(nil)
+
+// KeyAccess is a key accessed during execution.
+type KeyAccess struct {
+	Key []byte
+	// ValueHash is the hash of the value read or written. It is empty if
+	// the key did not exist when read or was removed.
+	ValueHash ids.ID
+	// Exists is false if the key did not exist when read or was removed.
+	Exists bool
+}
+
+// Receipt summarizes the state accessed during execution. Each key is
+// listed once per access kind ordered by key.
+type Receipt struct {
+	// Reads are the first value read of each key.
+	Reads []KeyAccess
+	// Writes are the last value written of each key, including removals.
+	Writes []KeyAccess
+}
+
+// RecordingState records the keys read and written through it so a receipt
+// of the state accessed by a program can be built, for example to declare
+// the state keys of an action.
+type RecordingState struct {
+	mu     state.Mutable
+	reads  map[string]KeyAccess
+	writes map[string]KeyAccess
+}
+
+// NewRecordingState returns a state recording the keys accessed in [mu].
+func NewRecordingState(mu state.Mutable) *RecordingState {
+	return &RecordingState{
+		mu:     mu,
+		reads:  make(map[string]KeyAccess),
+		writes: make(map[string]KeyAccess),
+	}
+}
+
+func (r *RecordingState) GetValue(ctx context.Context, key []byte) ([]byte, error) {
+	value, err := r.mu.GetValue(ctx, key)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, err
+	}
+	if _, ok := r.reads[string(key)]; !ok {
+		r.reads[string(key)] = newKeyAccess(key, value, err == nil)
+	}
+	return value, err
+}
+
+func (r *RecordingState) Insert(ctx context.Context, key []byte, value []byte) error {
+	err := r.mu.Insert(ctx, key, value)
+	if err != nil {
+		return err
+	}
+	r.writes[string(key)] = newKeyAccess(key, value, true)
+	return nil
+}
+
+func (r *RecordingState) Remove(ctx context.Context, key []byte) error {
+	err := r.mu.Remove(ctx, key)
+	if err != nil {
+		return err
+	}
+	r.writes[string(key)] = newKeyAccess(key, nil, false)
+	return nil
+}
+
+// Receipt returns the keys accessed so far.
+func (r *RecordingState) Receipt() Receipt {
+	return Receipt{
+		Reads:  sortedAccesses(r.reads),
+		Writes: sortedAccesses(r.writes),
+	}
+}
+
+// StateKeys returns the keys read or written ordered by key. Executing a
+// program against a RecordingState before submitting it derives the state
+// keys to declare for its execution.
+func (r Receipt) StateKeys() []string {
+	keys := make([]string, 0, len(r.Reads)+len(r.Writes))
+	seen := make(map[string]struct{}, len(r.Reads)+len(r.Writes))
+	for _, accesses := range [][]KeyAccess{r.Reads, r.Writes} {
+		for _, access := range accesses {
+			if _, ok := seen[string(access.Key)]; ok {
+				continue
+			}
+			seen[string(access.Key)] = struct{}{}
+			keys = append(keys, string(access.Key))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newKeyAccess(key []byte, value []byte, exists bool) KeyAccess {
+	access := KeyAccess{
+		Key:    make([]byte, len(key)),
+		Exists: exists,
+	}
+	copy(access.Key, key)
+	if exists {
+		access.ValueHash = hashing.ComputeHash256Array(value)
+	}
+	return access
+}
+
+func sortedAccesses(accesses map[string]KeyAccess) []KeyAccess {
+	sorted := make([]KeyAccess, 0, len(accesses))
+	for _, access := range accesses {
+		sorted = append(sorted, access)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Key, sorted[j].Key) < 0
+	})
+	return sorted
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+func TestRecordingState(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	require.NoError(db.Insert(ctx, []byte("a"), []byte("1")))
+	require.NoError(db.Insert(ctx, []byte("c"), []byte("3")))
+
+	rs := NewRecordingState(db)
+	value, err := rs.GetValue(ctx, []byte("c"))
+	require.NoError(err)
+	require.Equal([]byte("3"), value)
+	_, err = rs.GetValue(ctx, []byte("b"))
+	require.ErrorIs(err, database.ErrNotFound)
+	require.NoError(rs.Insert(ctx, []byte("c"), []byte("4")))
+	// only the first read of a key is recorded
+	_, err = rs.GetValue(ctx, []byte("c"))
+	require.NoError(err)
+	require.NoError(rs.Insert(ctx, []byte("b"), []byte("x")))
+	require.NoError(rs.Insert(ctx, []byte("b"), []byte("2")))
+	require.NoError(rs.Remove(ctx, []byte("a")))
+
+	hash := func(v string) ids.ID {
+		return hashing.ComputeHash256Array([]byte(v))
+	}
+	require.Equal(Receipt{
+		Reads: []KeyAccess{
+			{Key: []byte("b")},
+			{Key: []byte("c"), ValueHash: hash("3"), Exists: true},
+		},
+		Writes: []KeyAccess{
+			{Key: []byte("a")},
+			{Key: []byte("b"), ValueHash: hash("2"), Exists: true},
+			{Key: []byte("c"), ValueHash: hash("4"), Exists: true},
+		},
+	}, rs.Receipt())
+
+	// writes are applied to the underlying state
+	value, err = db.GetValue(ctx, []byte("b"))
+	require.NoError(err)
+	require.Equal([]byte("2"), value)
+	_, err = db.GetValue(ctx, []byte("a"))
+	require.ErrorIs(err, database.ErrNotFound)
+}
+
+func TestReceiptStateKeys(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+
+	// dry run derives the keys to declare
+	rs := NewRecordingState(db)
+	_, err := rs.GetValue(ctx, []byte("b"))
+	require.ErrorIs(err, database.ErrNotFound)
+	require.NoError(rs.Insert(ctx, []byte("b"), []byte("1")))
+	require.NoError(rs.Insert(ctx, []byte("a"), []byte("2")))
+	stateKeys := rs.Receipt().StateKeys()
+	require.Equal([]string{"a", "b"}, stateKeys)
+
+	// execution of the declared keys succeeds
+	declared := NewDeclaredState(utils.NewTestDB(), stateKeys)
+	_, err = declared.GetValue(ctx, []byte("b"))
+	require.ErrorIs(err, database.ErrNotFound)
+	require.NoError(declared.Insert(ctx, []byte("b"), []byte("1")))
+	require.NoError(declared.Insert(ctx, []byte("a"), []byte("2")))
+	require.NoError(declared.Remove(ctx, []byte("a")))
+
+	// access to an undeclared key fails
+	_, err = declared.GetValue(ctx, []byte("c"))
+	require.ErrorIs(err, ErrKeyNotDeclared)
+	require.ErrorIs(declared.Insert(ctx, []byte("c"), []byte("3")), ErrKeyNotDeclared)
+	require.ErrorIs(declared.Remove(ctx, []byte("c")), ErrKeyNotDeclared)
+}
+
+func TestProgramStateKeys(t *testing.T) {
+	require := require.New(t)
+	programID := ids.GenerateTestID()
+
+	stateKeys := ProgramStateKeys(programID, []byte("a"), []byte("b"))
+	require.Equal([]string{
+		string(ProgramPrefixKey(programID[:], []byte("a"))),
+		string(ProgramPrefixKey(programID[:], []byte("b"))),
+	}, stateKeys)
+}
@@ -4,24 +4,71 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/exp/maps"
 
 	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/maybe"
+	"github.com/ava-labs/avalanchego/utils/units"
 	"github.com/ava-labs/hypersdk/consts"
 	"github.com/ava-labs/hypersdk/state"
 )
 
 const (
-	programPrefix = 0x0
+	programPrefix         = 0x0
+	programVersionPrefix  = 0x1
+	programArtifactPrefix = 0x2
+	programChunkPrefix    = 0x3
+	programCodePrefix     = 0x4
+	artifactVersionPrefix = 0x5
+	stateSizePrefix       = 0x6
+	tokenPrefix           = 0x7
+
+	// The record at ProgramKey starts with a format tag. Programs stored
+	// before the record was introduced are the raw wasm bytes, whose magic
+	// starts with a zero byte, so they never collide with a tag.
+	programFormatCode   = 0x1
+	programFormatChunks = 0x2
+
+	// ChunkSize is the maximum size of a single chunk of a chunked record.
+	ChunkSize = 64 * units.KiB
+	// DefaultMaxProgramSize is the maximum size of a program stored by
+	// SetProgram.
+	DefaultMaxProgramSize = 4 * units.MiB
+	// MaxChunkedSize is the maximum size of a chunked record. It bounds the
+	// number of chunks read for a stored header.
+	MaxChunkedSize = 64 * units.MiB
 )
 
+// wasmMagic is the preamble of a wasm binary.
+var wasmMagic = []byte{0x00, 'a', 's', 'm'}
+
+// ProgramPrefixKey returns [programPrefix|id|key], the state key of [key] in
+// the namespace of the program [id] written by the state import.
 func ProgramPrefixKey(id []byte, key []byte) (k []byte) {
-	k = make([]byte, consts.IDLen+1+len(key))
-	k[0] = programPrefix
-	copy(k, id[:])
-	copy(k[consts.IDLen:], (key[:]))
+	return prefixKey(programPrefix, id, key)
+}
+
+// ProgramTokenKey returns [tokenPrefix|id|key], the state key of [key] in the
+// token records of the program [id]. The state import can not write these
+// keys, so a program can only change its token through the token import.
+func ProgramTokenKey(id []byte, key []byte) (k []byte) {
+	return prefixKey(tokenPrefix, id, key)
+}
+
+func prefixKey(prefix byte, id []byte, key []byte) (k []byte) {
+	k = make([]byte, 1+consts.IDLen+len(key))
+	k[0] = prefix
+	copy(k[1:], id[:])
+	copy(k[1+consts.IDLen:], key[:])
 	return
 }
 
@@ -35,6 +82,15 @@ func ProgramKey(id ids.ID) (k []byte) {
 	return
 }
 
+// [programID] -> [programFormatCode|codeHash]
+// [programCodePrefix|codeHash|0] -> [numChunks]
+// [programCodePrefix|codeHash|chunk] -> [programBytes]
+//
+// Programs stored before code deduplication are read from:
+// [programID] -> [programFormatChunks|numChunks]
+// [programChunkPrefix|programID|chunk] -> [programBytes]
+//
+// Programs stored before chunking are read from:
 // [programID] -> [programBytes]
 func GetProgram(
 	ctx context.Context,
@@ -45,24 +101,783 @@ func GetProgram(
 	bool, // exists
 	error,
 ) {
-	k := ProgramKey(programID)
-	v, err := db.GetValue(ctx, k)
+	record, exists, err := getProgramRecord(ctx, db, programID)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	program, err := record.program(ctx, db, programID)
+	if err != nil {
+		return nil, false, err
+	}
+	return program, true, nil
+}
+
+// GetProgramCodeHash returns the hash of the code of [programID]. Programs
+// deployed with the same bytes share the same code.
+func GetProgramCodeHash(
+	ctx context.Context,
+	db state.Immutable,
+	programID ids.ID,
+) (
+	ids.ID, // code hash
+	bool, // exists
+	error,
+) {
+	record, exists, err := getProgramRecord(ctx, db, programID)
+	if err != nil || !exists {
+		return ids.Empty, false, err
+	}
+	if record.format == programFormatCode {
+		return record.codeHash, true, nil
+	}
+	program, err := record.program(ctx, db, programID)
+	if err != nil {
+		return ids.Empty, false, err
+	}
+	return hashing.ComputeHash256Array(program), true, nil
+}
+
+// SetProgram stores [program] at [programID]. Programs larger than
+// DefaultMaxProgramSize are rejected.
+func SetProgram(
+	ctx context.Context,
+	mu state.Mutable,
+	programID ids.ID,
+	program []byte,
+) error {
+	return SetProgramWithMaxSize(ctx, mu, programID, program, DefaultMaxProgramSize)
+}
+
+// SetProgramWithMaxSize stores [program] at [programID]. The code is stored
+// once by its hash in chunks of at most ChunkSize bytes and shared by all
+// programs deployed with the same bytes. Programs larger than [maxSize] are
+// rejected.
+func SetProgramWithMaxSize(
+	ctx context.Context,
+	mu state.Mutable,
+	programID ids.ID,
+	program []byte,
+	maxSize int,
+) error {
+	if len(program) > maxSize {
+		return fmt.Errorf("%w: %d > %d", ErrProgramTooLarge, len(program), maxSize)
+	}
+
+	record, _, err := getProgramRecord(ctx, mu, programID)
+	if err != nil {
+		return err
+	}
+	codeHash := hashing.ComputeHash256Array(program)
+	err = setProgramCode(ctx, mu, codeHash, program)
+	if err != nil {
+		return err
+	}
+	// remove the chunks of a program stored before code deduplication
+	for i := uint32(1); record.format == programFormatChunks && i <= record.numChunks; i++ {
+		err = mu.Remove(ctx, indexKey(programChunkPrefix, programID, i))
+		if err != nil {
+			return err
+		}
+	}
+	// the artifact was compiled from the previous code
+	if record.format != programFormatCode || record.codeHash != codeHash {
+		err = removeProgramArtifact(ctx, mu, programID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return mu.Insert(ctx, ProgramKey(programID), codeRecord(codeHash))
+}
+
+// MigrateProgram moves the bytes of [programID] and of its versions stored as
+// raw bytes or chunked by program ID to their hash addressed code. Returns
+// true if the program or any of its versions was migrated.
+func MigrateProgram(ctx context.Context, mu state.Mutable, programID ids.ID) (bool, error) {
+	migrated, err := migrateVersions(ctx, mu, programID)
+	if err != nil {
+		return false, err
+	}
+	record, exists, err := getProgramRecord(ctx, mu, programID)
+	if err != nil || !exists || record.format == programFormatCode {
+		return migrated, err
+	}
+	program, err := record.program(ctx, mu, programID)
+	if err != nil {
+		return false, err
+	}
+	// migrated programs are not subject to the max size of new programs
+	err = SetProgramWithMaxSize(ctx, mu, programID, program, len(program))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// migrateVersions moves the versions of [programID] stored as raw bytes to
+// their hash addressed code. Returns true if any version was migrated.
+func migrateVersions(ctx context.Context, mu state.Mutable, programID ids.ID) (bool, error) {
+	latest, _, err := GetProgramLatestVersion(ctx, mu, programID)
+	if err != nil {
+		return false, err
+	}
+	migrated := false
+	for version := uint32(1); version <= latest; version++ {
+		k := ProgramVersionKey(programID, version)
+		v, err := mu.GetValue(ctx, k)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		record, err := parseProgramRecord(v)
+		if err != nil {
+			return false, err
+		}
+		if record.raw == nil {
+			continue
+		}
+		codeHash := hashing.ComputeHash256Array(record.raw)
+		if err := setProgramCode(ctx, mu, codeHash, record.raw); err != nil {
+			return false, err
+		}
+		if err := mu.Insert(ctx, k, codeRecord(codeHash)); err != nil {
+			return false, err
+		}
+		migrated = true
+	}
+	return migrated, nil
+}
+
+// programRecord is the value stored at the ProgramKey.
+type programRecord struct {
+	// format is the tag of the record, or zero for the raw program bytes.
+	format byte
+	// codeHash is set for programFormatCode.
+	codeHash ids.ID
+	// numChunks is set for programFormatChunks.
+	numChunks uint32
+	// raw is set for the raw program bytes.
+	raw []byte
+}
+
+// program returns the bytes of the program [programID] stored as [r].
+func (r programRecord) program(ctx context.Context, db state.Immutable, programID ids.ID) ([]byte, error) {
+	switch r.format {
+	case programFormatCode:
+		program, exists, err := getProgramCode(ctx, db, r.codeHash)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: missing code %s", ErrInvalidChunk, r.codeHash)
+		}
+		return program, nil
+	case programFormatChunks:
+		return getChunks(ctx, db, programChunkPrefix, programID, r.numChunks)
+	default:
+		return r.raw, nil
+	}
+}
+
+func getProgramRecord(ctx context.Context, db state.Immutable, programID ids.ID) (programRecord, bool, error) {
+	v, err := db.GetValue(ctx, ProgramKey(programID))
+	if errors.Is(err, database.ErrNotFound) {
+		return programRecord{}, false, nil
+	}
+	if err != nil {
+		return programRecord{}, false, err
+	}
+	record, err := parseProgramRecord(v)
+	if err != nil {
+		return programRecord{}, false, err
+	}
+	return record, true, nil
+}
+
+func parseProgramRecord(v []byte) (programRecord, error) {
+	switch {
+	case bytes.HasPrefix(v, wasmMagic):
+		return programRecord{raw: v}, nil
+	case len(v) == 1+ids.IDLen && v[0] == programFormatCode:
+		codeHash, err := ids.ToID(v[1:])
+		if err != nil {
+			return programRecord{}, err
+		}
+		return programRecord{format: programFormatCode, codeHash: codeHash}, nil
+	case len(v) == 1+consts.Uint32Len && v[0] == programFormatChunks:
+		return programRecord{format: programFormatChunks, numChunks: binary.BigEndian.Uint32(v[1:])}, nil
+	default:
+		return programRecord{}, fmt.Errorf("%w: invalid program record", ErrInvalidChunk)
+	}
+}
+
+// codeRecord returns the record of a program with the code [codeHash].
+func codeRecord(codeHash ids.ID) []byte {
+	v := make([]byte, 1+ids.IDLen)
+	v[0] = programFormatCode
+	copy(v[1:], codeHash[:])
+	return v
+}
+
+//
+// Program Code
+//
+
+// ProgramCodeKey returns the key of [chunk] of the code with [codeHash]. The
+// zero chunk stores the number of chunks.
+func ProgramCodeKey(codeHash ids.ID, chunk uint32) (k []byte) {
+	return indexKey(programCodePrefix, codeHash, chunk)
+}
+
+func getProgramCode(ctx context.Context, db state.Immutable, codeHash ids.ID) ([]byte, bool, error) {
+	header, err := db.GetValue(ctx, ProgramCodeKey(codeHash, 0))
 	if errors.Is(err, database.ErrNotFound) {
 		return nil, false, nil
 	}
 	if err != nil {
 		return nil, false, err
 	}
-	return v, true, nil
+	if len(header) != consts.Uint32Len {
+		return nil, false, ErrInvalidChunk
+	}
+	code, err := getChunks(ctx, db, programCodePrefix, codeHash, binary.BigEndian.Uint32(header))
+	if err != nil {
+		return nil, false, err
+	}
+	return code, true, nil
 }
 
-// SetProgram stores [program] at [programID]
-func SetProgram(
+// setProgramCode stores [code] at [codeHash] unless it is already stored.
+func setProgramCode(ctx context.Context, mu state.Mutable, codeHash ids.ID, code []byte) error {
+	_, err := mu.GetValue(ctx, ProgramCodeKey(codeHash, 0))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+	numChunks, err := setChunks(ctx, mu, programCodePrefix, codeHash, code)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, consts.Uint32Len)
+	binary.BigEndian.PutUint32(header, numChunks)
+	return mu.Insert(ctx, ProgramCodeKey(codeHash, 0), header)
+}
+
+//
+// Program Versions
+//
+
+// ProgramVersionKey returns the key of [version] of [id]. The zero version
+// key stores the latest version number of the program.
+func ProgramVersionKey(id ids.ID, version uint32) (k []byte) {
+	return indexKey(programVersionPrefix, id, version)
+}
+
+// [programVersionPrefix|programID|0] -> [latestVersion]
+func GetProgramLatestVersion(
+	ctx context.Context,
+	db state.Immutable,
+	programID ids.ID,
+) (
+	uint32, // latest version
+	bool, // exists
+	error,
+) {
+	k := ProgramVersionKey(programID, 0)
+	v, err := db.GetValue(ctx, k)
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if len(v) != consts.Uint32Len {
+		return 0, false, ErrInvalidVersion
+	}
+	return binary.BigEndian.Uint32(v), true, nil
+}
+
+// [programVersionPrefix|programID|version] -> [programFormatCode|codeHash]
+//
+// Versions stored before code deduplication are read from:
+// [programVersionPrefix|programID|version] -> [programBytes]
+func GetProgramVersion(
+	ctx context.Context,
+	db state.Immutable,
+	programID ids.ID,
+	version uint32,
+) (
+	[]byte, // program bytes
+	bool, // exists
+	error,
+) {
+	record, exists, err := getProgramVersionRecord(ctx, db, programID, version)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	program, err := record.program(ctx, db, programID)
+	if err != nil {
+		return nil, false, err
+	}
+	return program, true, nil
+}
+
+// GetProgramVersionCodeHash returns the hash of the code of [version] of
+// [programID].
+func GetProgramVersionCodeHash(
+	ctx context.Context,
+	db state.Immutable,
+	programID ids.ID,
+	version uint32,
+) (
+	ids.ID, // code hash
+	bool, // exists
+	error,
+) {
+	record, exists, err := getProgramVersionRecord(ctx, db, programID, version)
+	if err != nil || !exists {
+		return ids.Empty, false, err
+	}
+	if record.format == programFormatCode {
+		return record.codeHash, true, nil
+	}
+	return hashing.ComputeHash256Array(record.raw), true, nil
+}
+
+// getProgramVersionRecord returns the record stored for [version] of
+// [programID]. Versions are never stored as chunks.
+func getProgramVersionRecord(ctx context.Context, db state.Immutable, programID ids.ID, version uint32) (programRecord, bool, error) {
+	if version == 0 {
+		return programRecord{}, false, ErrInvalidVersion
+	}
+	v, err := db.GetValue(ctx, ProgramVersionKey(programID, version))
+	if errors.Is(err, database.ErrNotFound) {
+		return programRecord{}, false, nil
+	}
+	if err != nil {
+		return programRecord{}, false, err
+	}
+	record, err := parseProgramRecord(v)
+	if err != nil {
+		return programRecord{}, false, err
+	}
+	if record.format == programFormatChunks {
+		return programRecord{}, false, fmt.Errorf("%w: invalid version record", ErrInvalidChunk)
+	}
+	return record, true, nil
+}
+
+// SetProgramVersion stores [program] as the next version of [programID] and
+// makes it the program returned by GetProgram. Versions start at 1 and share
+// the code stored by its hash with the program and with each other.
+func SetProgramVersion(
 	ctx context.Context,
 	mu state.Mutable,
 	programID ids.ID,
 	program []byte,
+) (uint32, error) {
+	if len(program) > DefaultMaxProgramSize {
+		return 0, fmt.Errorf("%w: %d > %d", ErrProgramTooLarge, len(program), DefaultMaxProgramSize)
+	}
+
+	latest, _, err := GetProgramLatestVersion(ctx, mu, programID)
+	if err != nil {
+		return 0, err
+	}
+	version := latest + 1
+
+	codeHash := hashing.ComputeHash256Array(program)
+	err = setProgramCode(ctx, mu, codeHash, program)
+	if err != nil {
+		return 0, err
+	}
+	err = mu.Insert(ctx, ProgramVersionKey(programID, version), codeRecord(codeHash))
+	if err != nil {
+		return 0, err
+	}
+	v := make([]byte, consts.Uint32Len)
+	binary.BigEndian.PutUint32(v, version)
+	err = mu.Insert(ctx, ProgramVersionKey(programID, 0), v)
+	if err != nil {
+		return 0, err
+	}
+	return version, SetProgram(ctx, mu, programID, program)
+}
+
+//
+// Program Artifacts
+//
+
+// ProgramArtifactKey returns the key of [chunk] of the compiled artifact of
+// [id]. The zero chunk stores the artifact header.
+func ProgramArtifactKey(id ids.ID, chunk uint32) (k []byte) {
+	return indexKey(programArtifactPrefix, id, chunk)
+}
+
+// [programArtifactPrefix|programID|0] -> [numChunks|wasmtimeVersion]
+// [programArtifactPrefix|programID|chunk] -> [artifactBytes]
+//
+// GetProgramArtifact returns the compiled artifact of [programID]. An artifact
+// produced by a different wasmtime version than [version] is treated as
+// missing as it can not be deserialized.
+func GetProgramArtifact(
+	ctx context.Context,
+	db state.Immutable,
+	programID ids.ID,
+	version string,
+) (
+	[]byte, // artifact bytes
+	bool, // exists
+	error,
+) {
+	header, err := db.GetValue(ctx, ProgramArtifactKey(programID, 0))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(header) < consts.Uint32Len {
+		return nil, false, ErrInvalidChunk
+	}
+	if string(header[consts.Uint32Len:]) != version {
+		return nil, false, nil
+	}
+
+	numChunks := binary.BigEndian.Uint32(header)
+	artifact, err := getChunks(ctx, db, programArtifactPrefix, programID, numChunks)
+	if err != nil {
+		return nil, false, err
+	}
+	return artifact, true, nil
+}
+
+// SetProgramArtifact stores [artifact] compiled by wasmtime [version] for
+// [programID] in chunks of at most ChunkSize bytes. The chunks of a previous
+// larger artifact are removed.
+func SetProgramArtifact(
+	ctx context.Context,
+	mu state.Mutable,
+	programID ids.ID,
+	version string,
+	artifact []byte,
 ) error {
-	k := ProgramKey(programID)
-	return mu.Insert(ctx, k, program)
+	prevChunks := uint32(0)
+	prev, err := mu.GetValue(ctx, ProgramArtifactKey(programID, 0))
+	switch {
+	case err == nil && len(prev) >= consts.Uint32Len:
+		prevChunks = binary.BigEndian.Uint32(prev)
+	case err != nil && !errors.Is(err, database.ErrNotFound):
+		return err
+	}
+
+	numChunks, err := setChunks(ctx, mu, programArtifactPrefix, programID, artifact)
+	if err != nil {
+		return err
+	}
+	for i := numChunks + 1; i <= prevChunks; i++ {
+		err = mu.Remove(ctx, ProgramArtifactKey(programID, i))
+		if err != nil {
+			return err
+		}
+	}
+
+	header := make([]byte, consts.Uint32Len, consts.Uint32Len+len(version))
+	binary.BigEndian.PutUint32(header, numChunks)
+	header = append(header, version...)
+	return mu.Insert(ctx, ProgramArtifactKey(programID, 0), header)
+}
+
+// removeProgramArtifact removes the artifact of [programID], if any.
+func removeProgramArtifact(ctx context.Context, mu state.Mutable, programID ids.ID) error {
+	header, err := mu.GetValue(ctx, ProgramArtifactKey(programID, 0))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(header) < consts.Uint32Len {
+		return ErrInvalidChunk
+	}
+	numChunks := binary.BigEndian.Uint32(header)
+	if numChunks > MaxChunkedSize/ChunkSize {
+		return fmt.Errorf("%w: %d chunks exceeds %d", ErrInvalidChunk, numChunks, MaxChunkedSize/ChunkSize)
+	}
+	for i := uint32(0); i <= numChunks; i++ {
+		err = mu.Remove(ctx, ProgramArtifactKey(programID, i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// [artifactVersionPrefix] -> [version]
+//
+// SetArtifactVersion records [version] as the version of the engine loading
+// program artifacts, see runtime.Config.ArtifactVersion. Returns true if no
+// version was recorded or it differs from [version], in which case the
+// artifacts stored by the previous engine will be ignored by
+// GetProgramArtifact. Called at startup, this detects an upgrade of wasmtime
+// or a change of the engine config.
+func SetArtifactVersion(ctx context.Context, mu state.Mutable, version string) (bool, error) {
+	key := []byte{artifactVersionPrefix}
+	recorded, err := mu.GetValue(ctx, key)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return false, err
+	}
+	if err == nil && string(recorded) == version {
+		return false, nil
+	}
+	return true, mu.Insert(ctx, key, []byte(version))
+}
+
+//
+// State size
+//
+
+func ProgramStateSizeKey(id ids.ID) (k []byte) {
+	k = make([]byte, 1+consts.IDLen)
+	k[0] = stateSizePrefix
+	copy(k[1:], id[:])
+	return
+}
+
+// [stateSizePrefix|programID] -> [size]
+//
+// GetProgramStateSize returns the number of bytes, keys and values, stored
+// by [programID] through the state and token imports. Returns 0 if the program has not
+// stored any state.
+func GetProgramStateSize(ctx context.Context, db state.Immutable, programID ids.ID) (uint64, error) {
+	v, err := db.GetValue(ctx, ProgramStateSizeKey(programID))
+	if errors.Is(err, database.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(v) != consts.Uint64Len {
+		return 0, fmt.Errorf("%w: invalid length %d", ErrInvalidSize, len(v))
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// UpdateProgramStateSize adds [delta] to the state size of [programID] and
+// returns the new size. Returns ErrInvalidSize if the size would be negative.
+func UpdateProgramStateSize(ctx context.Context, mu state.Mutable, programID ids.ID, delta int64) (uint64, error) {
+	size, err := GetProgramStateSize(ctx, mu, programID)
+	if err != nil {
+		return 0, err
+	}
+	if delta < 0 && uint64(-delta) > size {
+		return 0, fmt.Errorf("%w: %d is less than %d", ErrInvalidSize, size, -delta)
+	}
+	size = uint64(int64(size) + delta)
+
+	v := make([]byte, consts.Uint64Len)
+	binary.BigEndian.PutUint64(v, size)
+	return size, mu.Insert(ctx, ProgramStateSizeKey(programID), v)
+}
+
+//
+// Chunks
+//
+
+// indexKey returns [prefix|id|index].
+func indexKey(prefix byte, id ids.ID, index uint32) (k []byte) {
+	k = make([]byte, 1+consts.IDLen+consts.Uint32Len)
+	k[0] = prefix
+	copy(k[1:], id[:])
+	binary.BigEndian.PutUint32(k[1+consts.IDLen:], index)
+	return
+}
+
+// setChunks stores [value] in chunks starting at index 1 and returns the
+// number of chunks written. Values larger than MaxChunkedSize are rejected.
+func setChunks(ctx context.Context, mu state.Mutable, prefix byte, id ids.ID, value []byte) (uint32, error) {
+	if len(value) > MaxChunkedSize {
+		return 0, fmt.Errorf("%w: %d > %d", ErrProgramTooLarge, len(value), MaxChunkedSize)
+	}
+	numChunks := uint32(0)
+	for start := 0; start < len(value); start += ChunkSize {
+		end := start + ChunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		numChunks++
+		err := mu.Insert(ctx, indexKey(prefix, id, numChunks), value[start:end])
+		if err != nil {
+			return 0, err
+		}
+	}
+	return numChunks, nil
+}
+
+// getChunks reassembles [numChunks] chunks starting at index 1. The count is
+// read from state, so it is checked against MaxChunkedSize and the value grows
+// with the chunks read rather than being allocated upfront.
+func getChunks(ctx context.Context, db state.Immutable, prefix byte, id ids.ID, numChunks uint32) ([]byte, error) {
+	if numChunks > MaxChunkedSize/ChunkSize {
+		return nil, fmt.Errorf("%w: %d chunks exceeds %d", ErrInvalidChunk, numChunks, MaxChunkedSize/ChunkSize)
+	}
+	value := []byte{}
+	for i := uint32(1); i <= numChunks; i++ {
+		chunk, err := db.GetValue(ctx, indexKey(prefix, id, i))
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, fmt.Errorf("%w: missing chunk %d of %d", ErrInvalidChunk, i, numChunks)
+		}
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, chunk...)
+	}
+	return value, nil
+}
+
+//
+// Read-only state
+//
+
+var _ state.Mutable = (*readOnlyState)(nil)
+
+type readOnlyState struct {
+	state.Immutable
+}
+
+// NewReadOnlyState returns a view of [db] which returns ErrReadOnly on any
+// write.
+func NewReadOnlyState(db state.Immutable) state.Mutable {
+	return &readOnlyState{db}
+}
+
+func (*readOnlyState) Insert(context.Context, []byte, []byte) error {
+	return ErrReadOnly
+}
+
+func (*readOnlyState) Remove(context.Context, []byte) error {
+	return ErrReadOnly
+}
+
+//
+// Declared state
+//
+
+var _ state.Mutable = (*declaredState)(nil)
+
+type declaredState struct {
+	mu   state.Mutable
+	keys map[string]struct{}
+}
+
+// NewDeclaredState returns a view of [mu] which returns ErrKeyNotDeclared on
+// any access to a key not in [stateKeys]. This ensures a program only
+// touches the state keys declared for its execution.
+func NewDeclaredState(mu state.Mutable, stateKeys []string) state.Mutable {
+	keys := make(map[string]struct{}, len(stateKeys))
+	for _, k := range stateKeys {
+		keys[k] = struct{}{}
+	}
+	return &declaredState{mu: mu, keys: keys}
+}
+
+func (d *declaredState) check(key []byte) error {
+	if _, ok := d.keys[string(key)]; !ok {
+		return fmt.Errorf("%w: %x", ErrKeyNotDeclared, key)
+	}
+	return nil
+}
+
+func (d *declaredState) GetValue(ctx context.Context, key []byte) ([]byte, error) {
+	if err := d.check(key); err != nil {
+		return nil, err
+	}
+	return d.mu.GetValue(ctx, key)
+}
+
+func (d *declaredState) Insert(ctx context.Context, key []byte, value []byte) error {
+	if err := d.check(key); err != nil {
+		return err
+	}
+	return d.mu.Insert(ctx, key, value)
+}
+
+func (d *declaredState) Remove(ctx context.Context, key []byte) error {
+	if err := d.check(key); err != nil {
+		return err
+	}
+	return d.mu.Remove(ctx, key)
+}
+
+//
+// Pending state
+//
+
+var _ state.Mutable = (*PendingState)(nil)
+
+// PendingState is a view of state which buffers its writes until Commit.
+type PendingState struct {
+	mu      state.Mutable
+	changes map[string]maybe.Maybe[[]byte]
+}
+
+// NewPendingState returns a view of [mu] whose writes are only applied to
+// [mu] by Commit, so the writes of a call can be discarded if it fails.
+func NewPendingState(mu state.Mutable) *PendingState {
+	return &PendingState{mu: mu, changes: make(map[string]maybe.Maybe[[]byte])}
+}
+
+func (p *PendingState) GetValue(ctx context.Context, key []byte) ([]byte, error) {
+	if v, ok := p.changes[string(key)]; ok {
+		if v.IsNothing() {
+			return nil, database.ErrNotFound
+		}
+		return v.Value(), nil
+	}
+	return p.mu.GetValue(ctx, key)
+}
+
+func (p *PendingState) Insert(_ context.Context, key []byte, value []byte) error {
+	p.changes[string(key)] = maybe.Some(value)
+	return nil
+}
+
+func (p *PendingState) Remove(_ context.Context, key []byte) error {
+	p.changes[string(key)] = maybe.Nothing[[]byte]()
+	return nil
+}
+
+// Commit applies the buffered writes to the underlying state in key order.
+func (p *PendingState) Commit(ctx context.Context) error {
+	keys := maps.Keys(p.changes)
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := p.changes[k]
+		var err error
+		if v.IsNothing() {
+			err = p.mu.Remove(ctx, []byte(k))
+		} else {
+			err = p.mu.Insert(ctx, []byte(k), v.Value())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.changes = make(map[string]maybe.Maybe[[]byte])
+	return nil
+}
+
+// ProgramStateKeys returns the state keys of [keys] in the namespace of
+// [programID], as accessed by the state import. Writes of the state import
+// also access ProgramStateSizeKey, which must be declared as well.
+func ProgramStateKeys(programID ids.ID, keys ...[]byte) []string {
+	stateKeys := make([]string, len(keys))
+	for i, key := range keys {
+		stateKeys[i] = string(ProgramPrefixKey(programID[:], key))
+	}
+	return stateKeys
 }
@@ -0,0 +1,452 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+func TestProgramArtifact(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "get_guest") (result i32)
+	    i32.const 1
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	newConfig := func() *runtime.Config {
+		cfg, err := runtime.NewConfigBuilder(10000).
+			WithCompileStrategy(runtime.PrecompiledWasm).
+			Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	artifact, err := runtime.PreCompileWasmBytes(wasm, newConfig())
+	require.NoError(err)
+
+	_, exists, err := GetProgramArtifact(ctx, db, programID, runtime.WasmtimeVersion())
+	require.NoError(err)
+	require.False(exists)
+
+	err = SetProgramArtifact(ctx, db, programID, runtime.WasmtimeVersion(), artifact)
+	require.NoError(err)
+
+	stored, exists, err := GetProgramArtifact(ctx, db, programID, runtime.WasmtimeVersion())
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(artifact, stored)
+
+	// a fresh runtime can deserialize the stored artifact
+	rt := runtime.New(logging.NoLog{}, newConfig(), runtime.NoSupportedImports)
+	require.NoError(rt.Initialize(ctx, stored))
+	resp, err := rt.Call(ctx, "get")
+	require.NoError(err)
+	require.Equal(uint64(1), resp[0])
+
+	// artifacts from a different wasmtime version are invalidated
+	_, exists, err = GetProgramArtifact(ctx, db, programID, "v0.0.0")
+	require.NoError(err)
+	require.False(exists)
+}
+
+func TestArtifactVersion(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	defaultCfg, err := runtime.NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	simdCfg, err := runtime.NewConfigBuilder(10000).
+		WithSIMD(true).
+		Build()
+	require.NoError(err)
+	require.NotEqual(defaultCfg.ArtifactVersion(), simdCfg.ArtifactVersion())
+
+	changed, err := SetArtifactVersion(ctx, db, defaultCfg.ArtifactVersion())
+	require.NoError(err)
+	require.True(changed)
+	changed, err = SetArtifactVersion(ctx, db, defaultCfg.ArtifactVersion())
+	require.NoError(err)
+	require.False(changed)
+
+	require.NoError(SetProgramArtifact(ctx, db, programID, defaultCfg.ArtifactVersion(), []byte{1}))
+
+	// the engine config changed
+	changed, err = SetArtifactVersion(ctx, db, simdCfg.ArtifactVersion())
+	require.NoError(err)
+	require.True(changed)
+
+	// artifacts of a different engine config are not loaded
+	_, exists, err := GetProgramArtifact(ctx, db, programID, simdCfg.ArtifactVersion())
+	require.NoError(err)
+	require.False(exists)
+}
+
+func TestChunks(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	id := ids.GenerateTestID()
+
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3 * ChunkSize} {
+		value := make([]byte, size)
+		for i := range value {
+			value[i] = byte(i)
+		}
+		numChunks, err := setChunks(ctx, db, programArtifactPrefix, id, value)
+		require.NoError(err)
+		require.Equal(uint32((size+ChunkSize-1)/ChunkSize), numChunks)
+
+		stored, err := getChunks(ctx, db, programArtifactPrefix, id, numChunks)
+		require.NoError(err)
+		require.Equal(value, stored)
+	}
+
+	// missing chunk
+	_, err := getChunks(ctx, db, programArtifactPrefix, ids.GenerateTestID(), 1)
+	require.ErrorIs(err, ErrInvalidChunk)
+
+	// a corrupt count is rejected before reading any chunk
+	_, err = getChunks(ctx, db, programArtifactPrefix, id, MaxChunkedSize/ChunkSize+1)
+	require.ErrorIs(err, ErrInvalidChunk)
+}
+
+func TestProgramArtifactOverwrite(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	require.NoError(SetProgramArtifact(ctx, db, programID, "v1", make([]byte, 3*ChunkSize)))
+	require.NoError(SetProgramArtifact(ctx, db, programID, "v1", []byte{1}))
+
+	stored, exists, err := GetProgramArtifact(ctx, db, programID, "v1")
+	require.NoError(err)
+	require.True(exists)
+	require.Equal([]byte{1}, stored)
+
+	// chunks of the previous larger artifact are removed
+	for i := uint32(2); i <= 3; i++ {
+		_, err = db.GetValue(ctx, ProgramArtifactKey(programID, i))
+		require.ErrorIs(err, database.ErrNotFound)
+	}
+}
+
+func TestProgramChunks(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	_, exists, err := GetProgram(ctx, db, programID)
+	require.NoError(err)
+	require.False(exists)
+
+	for _, size := range []int{1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3 * ChunkSize, 1} {
+		program := make([]byte, size)
+		for i := range program {
+			program[i] = byte(i)
+		}
+		require.NoError(SetProgram(ctx, db, programID, program))
+
+		stored, exists, err := GetProgram(ctx, db, programID)
+		require.NoError(err)
+		require.True(exists)
+		require.Equal(program, stored)
+	}
+
+	// chunks of the previous larger program are removed
+	_, err = db.GetValue(ctx, indexKey(programChunkPrefix, programID, 2))
+	require.ErrorIs(err, database.ErrNotFound)
+
+	// program size is validated
+	err = SetProgramWithMaxSize(ctx, db, programID, make([]byte, ChunkSize+1), ChunkSize)
+	require.ErrorIs(err, ErrProgramTooLarge)
+	err = SetProgram(ctx, db, programID, make([]byte, DefaultMaxProgramSize+1))
+	require.ErrorIs(err, ErrProgramTooLarge)
+	_, err = SetProgramVersion(ctx, db, programID, make([]byte, DefaultMaxProgramSize+1))
+	require.ErrorIs(err, ErrProgramTooLarge)
+}
+
+func TestProgramDeduplication(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+
+	program := make([]byte, ChunkSize+1)
+	for i := range program {
+		program[i] = byte(i)
+	}
+	programID := ids.GenerateTestID()
+	otherID := ids.GenerateTestID()
+	require.NoError(SetProgram(ctx, db, programID, program))
+	require.NoError(SetProgram(ctx, db, otherID, program))
+
+	// programs with the same bytes share the code
+	codeHash, exists, err := GetProgramCodeHash(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	otherHash, exists, err := GetProgramCodeHash(ctx, db, otherID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(codeHash, otherHash)
+	chunk, err := db.GetValue(ctx, ProgramCodeKey(codeHash, 2))
+	require.NoError(err)
+	require.Equal(program[ChunkSize:], chunk)
+
+	// updating a program does not change the programs sharing its code
+	require.NoError(SetProgram(ctx, db, programID, []byte{1}))
+	stored, exists, err := GetProgram(ctx, db, otherID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(program, stored)
+
+	_, exists, err = GetProgramCodeHash(ctx, db, ids.GenerateTestID())
+	require.NoError(err)
+	require.False(exists)
+}
+
+func TestMigrateProgram(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	// program stored chunked by program id
+	program := make([]byte, 2*ChunkSize)
+	for i := range program {
+		program[i] = byte(i)
+	}
+	numChunks, err := setChunks(ctx, db, programChunkPrefix, programID, program)
+	require.NoError(err)
+	require.Equal(uint32(2), numChunks)
+	require.NoError(db.Insert(ctx, ProgramKey(programID), []byte{programFormatChunks, 0, 0, 0, 2}))
+
+	stored, exists, err := GetProgram(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(program, stored)
+
+	migrated, err := MigrateProgram(ctx, db, programID)
+	require.NoError(err)
+	require.True(migrated)
+
+	stored, exists, err = GetProgram(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(program, stored)
+	codeHash, exists, err := GetProgramCodeHash(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(ids.ID(hashing.ComputeHash256Array(program)), codeHash)
+	for i := uint32(1); i <= numChunks; i++ {
+		_, err = db.GetValue(ctx, indexKey(programChunkPrefix, programID, i))
+		require.ErrorIs(err, database.ErrNotFound)
+	}
+
+	// programs are only migrated once
+	migrated, err = MigrateProgram(ctx, db, programID)
+	require.NoError(err)
+	require.False(migrated)
+
+	migrated, err = MigrateProgram(ctx, db, ids.GenerateTestID())
+	require.NoError(err)
+	require.False(migrated)
+
+	// program stored as raw bytes
+	rawID := ids.GenerateTestID()
+	raw, err := wasmtime.Wat2Wasm(`(module)`)
+	require.NoError(err)
+	require.NoError(db.Insert(ctx, ProgramKey(rawID), raw))
+
+	stored, exists, err = GetProgram(ctx, db, rawID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(raw, stored)
+
+	migrated, err = MigrateProgram(ctx, db, rawID)
+	require.NoError(err)
+	require.True(migrated)
+
+	stored, exists, err = GetProgram(ctx, db, rawID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(raw, stored)
+	codeHash, exists, err = GetProgramCodeHash(ctx, db, rawID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(ids.ID(hashing.ComputeHash256Array(raw)), codeHash)
+}
+
+func TestProgramVersion(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	program := make([]byte, ChunkSize+1)
+	for i := range program {
+		program[i] = byte(i)
+	}
+	version, err := SetProgramVersion(ctx, db, programID, program)
+	require.NoError(err)
+	require.Equal(uint32(1), version)
+
+	// the version refers to the code stored by its hash
+	codeHash := ids.ID(hashing.ComputeHash256Array(program))
+	v, err := db.GetValue(ctx, ProgramVersionKey(programID, version))
+	require.NoError(err)
+	require.Equal(codeRecord(codeHash), v)
+	stored, exists, err := GetProgramVersion(ctx, db, programID, version)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(program, stored)
+	storedHash, exists, err := GetProgramVersionCodeHash(ctx, db, programID, version)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(codeHash, storedHash)
+	_, exists, err = GetProgramVersionCodeHash(ctx, db, programID, version+1)
+	require.NoError(err)
+	require.False(exists)
+
+	// a version stored as raw bytes is read and migrated
+	raw, err := wasmtime.Wat2Wasm(`(module)`)
+	require.NoError(err)
+	require.NoError(db.Insert(ctx, ProgramVersionKey(programID, version), raw))
+	stored, exists, err = GetProgramVersion(ctx, db, programID, version)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(raw, stored)
+	storedHash, _, err = GetProgramVersionCodeHash(ctx, db, programID, version)
+	require.NoError(err)
+	require.Equal(ids.ID(hashing.ComputeHash256Array(raw)), storedHash)
+
+	migrated, err := MigrateProgram(ctx, db, programID)
+	require.NoError(err)
+	require.True(migrated)
+	v, err = db.GetValue(ctx, ProgramVersionKey(programID, version))
+	require.NoError(err)
+	require.Equal(codeRecord(hashing.ComputeHash256Array(raw)), v)
+	stored, exists, err = GetProgramVersion(ctx, db, programID, version)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(raw, stored)
+
+	migrated, err = MigrateProgram(ctx, db, programID)
+	require.NoError(err)
+	require.False(migrated)
+}
+
+func TestProgramRecord(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+
+	// values which are neither a tagged record nor a wasm binary are
+	// rejected, whatever their length
+	for _, v := range [][]byte{{0, 0, 0, 2}, make([]byte, ids.IDLen), {programFormatCode, 1}} {
+		programID := ids.GenerateTestID()
+		require.NoError(db.Insert(ctx, ProgramKey(programID), v))
+		_, _, err := GetProgram(ctx, db, programID)
+		require.ErrorIs(err, ErrInvalidChunk)
+	}
+}
+
+func TestProgramPrefixKey(t *testing.T) {
+	require := require.New(t)
+
+	programID := ids.GenerateTestID()
+	key := []byte("k")
+	require.Equal(append(append([]byte{programPrefix}, programID[:]...), key...), ProgramPrefixKey(programID[:], key))
+	require.Equal(append(append([]byte{tokenPrefix}, programID[:]...), key...), ProgramTokenKey(programID[:], key))
+
+	// the state import can not write the token records of any program
+	tokenKey := ProgramTokenKey(programID[:], key)
+	require.NotEqual(tokenKey[0], ProgramPrefixKey(tokenKey[1:1+ids.IDLen], tokenKey[1+ids.IDLen:])[0])
+}
+
+func TestProgramStateSize(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	size, err := GetProgramStateSize(ctx, db, programID)
+	require.NoError(err)
+	require.Zero(size)
+
+	size, err = UpdateProgramStateSize(ctx, db, programID, 10)
+	require.NoError(err)
+	require.Equal(uint64(10), size)
+
+	size, err = UpdateProgramStateSize(ctx, db, programID, -4)
+	require.NoError(err)
+	require.Equal(uint64(6), size)
+
+	// the size of other programs is not affected
+	size, err = GetProgramStateSize(ctx, db, ids.GenerateTestID())
+	require.NoError(err)
+	require.Zero(size)
+
+	// the size can not be negative
+	_, err = UpdateProgramStateSize(ctx, db, programID, -7)
+	require.ErrorIs(err, ErrInvalidSize)
+	size, err = GetProgramStateSize(ctx, db, programID)
+	require.NoError(err)
+	require.Equal(uint64(6), size)
+}
+
+func TestPendingState(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	require.NoError(db.Insert(ctx, []byte("a"), []byte{1}))
+	require.NoError(db.Insert(ctx, []byte("b"), []byte{2}))
+
+	pending := NewPendingState(db)
+	require.NoError(pending.Insert(ctx, []byte("a"), []byte{3}))
+	require.NoError(pending.Remove(ctx, []byte("b")))
+	require.NoError(pending.Insert(ctx, []byte("c"), []byte{4}))
+
+	// writes are read back but not applied
+	v, err := pending.GetValue(ctx, []byte("a"))
+	require.NoError(err)
+	require.Equal([]byte{3}, v)
+	_, err = pending.GetValue(ctx, []byte("b"))
+	require.ErrorIs(err, database.ErrNotFound)
+	v, err = db.GetValue(ctx, []byte("a"))
+	require.NoError(err)
+	require.Equal([]byte{1}, v)
+
+	require.NoError(pending.Commit(ctx))
+	v, err = db.GetValue(ctx, []byte("a"))
+	require.NoError(err)
+	require.Equal([]byte{3}, v)
+	_, err = db.GetValue(ctx, []byte("b"))
+	require.ErrorIs(err, database.ErrNotFound)
+	v, err = db.GetValue(ctx, []byte("c"))
+	require.NoError(err)
+	require.Equal([]byte{4}, v)
+}
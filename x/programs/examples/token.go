@@ -12,7 +12,9 @@ import (
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
 
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/program"
 	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
 	"github.com/ava-labs/hypersdk/x/programs/runtime"
 )
@@ -36,34 +38,29 @@ type Token struct {
 }
 
 func (t *Token) Run(ctx context.Context) error {
-	rt := runtime.New(t.log, t.cfg, t.imports)
-	err := rt.Initialize(ctx, t.programBytes)
-	if err != nil {
-		return err
-	}
-
-	t.log.Debug("initial meter",
-		zap.Uint64("balance", rt.Meter().GetBalance()),
-	)
-
 	// simulate create program transaction
 	programID := ids.GenerateTestID()
-	err = storage.SetProgram(ctx, t.db, programID, t.programBytes)
+	err := storage.SetProgram(ctx, t.db, programID, t.programBytes)
 	if err != nil {
 		return err
 	}
 
-	programIDPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	t.log.Debug("new token program created",
+		zap.String("id", programID.String()),
+	)
+
+	rt := runtime.New(t.log, t.cfg, program.BindImports(t.imports, programID))
+	err = rt.Initialize(ctx, t.programBytes)
 	if err != nil {
 		return err
 	}
 
-	t.log.Debug("new token program created",
-		zap.String("id", programID.String()),
+	t.log.Debug("initial meter",
+		zap.Uint64("balance", rt.Meter().GetBalance()),
 	)
 
 	// initialize program
-	resp, err := rt.Call(ctx, "init", programIDPtr)
+	resp, err := callWithParams(ctx, rt, "init", programParams{ProgramID: programID})
 	if err != nil {
 		return fmt.Errorf("failed to initialize program: %w", err)
 	}
@@ -72,7 +69,7 @@ func (t *Token) Run(ctx context.Context) error {
 		zap.Uint64("init", resp[0]),
 	)
 
-	result, err := rt.Call(ctx, "get_total_supply", programIDPtr)
+	result, err := callWithParams(ctx, rt, "get_total_supply", programParams{ProgramID: programID})
 	if err != nil {
 		return err
 	}
@@ -86,26 +83,14 @@ func (t *Token) Run(ctx context.Context) error {
 		return err
 	}
 
-	// write alice's key to stack and get pointer
-	alicePtr, err := newKeyPtr(ctx, aliceKey, rt)
-	if err != nil {
-		return err
-	}
-
 	// generate bob keys
 	_, bobKey, err := newKey()
 	if err != nil {
 		return err
 	}
 
-	// write bob's key to stack and get pointer
-	bobPtr, err := newKeyPtr(ctx, bobKey, rt)
-	if err != nil {
-		return err
-	}
-
 	// check balance of bob
-	result, err = rt.Call(ctx, "get_balance", programIDPtr, bobPtr)
+	result, err = callWithParams(ctx, rt, "get_balance", accountParams{ProgramID: programID, Account: bobKey})
 	if err != nil {
 		return err
 	}
@@ -115,7 +100,7 @@ func (t *Token) Run(ctx context.Context) error {
 
 	// mint 100 tokens to alice
 	mintAlice := uint64(1000)
-	_, err = rt.Call(ctx, "mint_to", programIDPtr, alicePtr, mintAlice)
+	_, err = callWithParams(ctx, rt, "mint_to", amountParams{ProgramID: programID, Account: aliceKey, Amount: mintAlice})
 	if err != nil {
 		return err
 	}
@@ -124,7 +109,7 @@ func (t *Token) Run(ctx context.Context) error {
 	)
 
 	// check balance of alice
-	result, err = rt.Call(ctx, "get_balance", programIDPtr, alicePtr)
+	result, err = callWithParams(ctx, rt, "get_balance", accountParams{ProgramID: programID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
@@ -133,7 +118,7 @@ func (t *Token) Run(ctx context.Context) error {
 	)
 
 	// check balance of bob
-	result, err = rt.Call(ctx, "get_balance", programIDPtr, bobPtr)
+	result, err = callWithParams(ctx, rt, "get_balance", accountParams{ProgramID: programID, Account: bobKey})
 	if err != nil {
 		return err
 	}
@@ -143,7 +128,7 @@ func (t *Token) Run(ctx context.Context) error {
 
 	// transfer 50 from alice to bob
 	transferToBob := uint64(50)
-	_, err = rt.Call(ctx, "transfer", programIDPtr, alicePtr, bobPtr, transferToBob)
+	_, err = callWithParams(ctx, rt, "transfer", transferParams{ProgramID: programID, From: aliceKey, To: bobKey, Amount: transferToBob})
 	if err != nil {
 		return err
 	}
@@ -152,7 +137,7 @@ func (t *Token) Run(ctx context.Context) error {
 		zap.Uint64("to bob", transferToBob),
 	)
 
-	_, err = rt.Call(ctx, "transfer", programIDPtr, alicePtr, bobPtr, 1)
+	_, err = callWithParams(ctx, rt, "transfer", transferParams{ProgramID: programID, From: aliceKey, To: bobKey, Amount: 1})
 	if err != nil {
 		return err
 	}
@@ -162,7 +147,7 @@ func (t *Token) Run(ctx context.Context) error {
 	)
 
 	// get balance alice
-	result, err = rt.Call(ctx, "get_balance", programIDPtr, alicePtr)
+	result, err = callWithParams(ctx, rt, "get_balance", accountParams{ProgramID: programID, Account: aliceKey})
 	if err != nil {
 		return err
 	}
@@ -171,7 +156,7 @@ func (t *Token) Run(ctx context.Context) error {
 	)
 
 	// get balance bob
-	result, err = rt.Call(ctx, "get_balance", programIDPtr, bobPtr)
+	result, err = callWithParams(ctx, rt, "get_balance", accountParams{ProgramID: programID, Account: bobKey})
 	if err != nil {
 		return err
 	}
@@ -184,36 +169,39 @@ func (t *Token) Run(ctx context.Context) error {
 	return nil
 }
 
+// transferParams are the params of transfer.
+type transferParams struct {
+	ProgramID ids.ID            `program:"ptr"`
+	From      ed25519.PublicKey `program:"ptr"`
+	To        ed25519.PublicKey `program:"ptr"`
+	Amount    uint64            `program:"u64"`
+}
+
 // RunShort performs the steps of initialization only, used for benchmarking.
 func (t *Token) RunShort(ctx context.Context) error {
-	rt := runtime.New(t.log, t.cfg, t.imports)
-	err := rt.Initialize(ctx, t.programBytes)
-	if err != nil {
-		return err
-	}
-
-	t.log.Debug("initial meter",
-		zap.Uint64("balance", rt.Meter().GetBalance()),
-	)
-
 	// simulate create program transaction
 	programID := ids.GenerateTestID()
-	err = storage.SetProgram(ctx, t.db, programID, t.programBytes)
+	err := storage.SetProgram(ctx, t.db, programID, t.programBytes)
 	if err != nil {
 		return err
 	}
 
-	programIDPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	t.log.Debug("new token program created",
+		zap.String("id", programID.String()),
+	)
+
+	rt := runtime.New(t.log, t.cfg, program.BindImports(t.imports, programID))
+	err = rt.Initialize(ctx, t.programBytes)
 	if err != nil {
 		return err
 	}
 
-	t.log.Debug("new token program created",
-		zap.String("id", programID.String()),
+	t.log.Debug("initial meter",
+		zap.Uint64("balance", rt.Meter().GetBalance()),
 	)
 
 	// initialize program
-	resp, err := rt.Call(ctx, "init", programIDPtr)
+	resp, err := callWithParams(ctx, rt, "init", programParams{ProgramID: programID})
 	if err != nil {
 		return fmt.Errorf("failed to initialize program: %w", err)
 	}
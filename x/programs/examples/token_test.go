@@ -9,10 +9,15 @@ import (
 	"os"
 	"testing"
 
+	"github.com/bytecodealliance/wasmtime-go/v13"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
 
+	"github.com/ava-labs/hypersdk/crypto/ed25519"
+	"github.com/ava-labs/hypersdk/state"
+	pcontext "github.com/ava-labs/hypersdk/x/programs/examples/imports/context"
 	"github.com/ava-labs/hypersdk/x/programs/examples/imports/pstate"
 	"github.com/ava-labs/hypersdk/x/programs/runtime"
 	"github.com/ava-labs/hypersdk/x/programs/utils"
@@ -41,6 +46,141 @@ func TestTokenProgram(t *testing.T) {
 	require.NoError(err)
 }
 
+// go test -v -timeout 30s -run ^TestTokenProgramDifferential$ github.com/ava-labs/hypersdk/x/programs/examples
+func TestTokenProgramDifferential(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	maxUnits := uint64(40000)
+
+	newConfig := func(strategy runtime.EngineCompileStrategy) (*runtime.Config, error) {
+		return runtime.NewConfigBuilder(maxUnits).
+			WithLimitMaxMemory(18 * runtime.MemoryPageSize). // 18 pages
+			WithCompileStrategy(strategy).
+			Build()
+	}
+	newImports := func(db state.Mutable) runtime.SupportedImports {
+		supported := runtime.NewSupportedImports()
+		supported.Register("state", func() runtime.Import {
+			return pstate.New(log, db)
+		})
+		return supported.Imports()
+	}
+
+	rt := utils.NewDifferentialRuntime(log, newConfig, newImports)
+	require.NoError(rt.Initialize(ctx, tokenProgramBytes))
+	defer rt.Stop()
+
+	programID := ids.GenerateTestID()
+	programIDPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+	require.NoError(err)
+	_, alice, err := newKey()
+	require.NoError(err)
+	alicePtr, err := newKeyPtr(ctx, alice, rt)
+	require.NoError(err)
+	_, bob, err := newKey()
+	require.NoError(err)
+	bobPtr, err := newKeyPtr(ctx, bob, rt)
+	require.NoError(err)
+
+	_, err = rt.Call(ctx, "init", programIDPtr)
+	require.NoError(err)
+	_, err = rt.Call(ctx, "mint_to", programIDPtr, alicePtr, 1000)
+	require.NoError(err)
+	_, err = rt.Call(ctx, "transfer", programIDPtr, alicePtr, bobPtr, 50)
+	require.NoError(err)
+	result, err := rt.Call(ctx, "get_balance", programIDPtr, bobPtr)
+	require.NoError(err)
+	require.Equal(uint64(50), result[0])
+}
+
+// go test -v -timeout 30s -run ^TestTokenProgramAllowance$ github.com/ava-labs/hypersdk/x/programs/examples
+func TestTokenProgramAllowance(t *testing.T) {
+	require := require.New(t)
+	if !hasExport(t, tokenProgramBytes, "approve_guest") {
+		t.Skip("testdata/token.wasm predates approve, rebuild it with rust/scripts/build_testdata.sh")
+	}
+
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+	_, alice, err := newKey()
+	require.NoError(err)
+	_, bob, err := newKey()
+	require.NoError(err)
+
+	// newRuntime returns a runtime executing the token program on behalf of
+	// [actor] and pointers to the program ID, alice and bob in its memory.
+	newRuntime := func(actor ed25519.PublicKey) (runtime.Runtime, uint64, uint64, uint64) {
+		cfg, err := runtime.NewConfigBuilder(1_000_000).
+			WithLimitMaxMemory(18 * runtime.MemoryPageSize). // 18 pages
+			Build()
+		require.NoError(err)
+		supported := runtime.NewSupportedImports()
+		supported.Register("state", func() runtime.Import {
+			return pstate.New(log, db)
+		})
+		supported.Register("context", func() runtime.Import {
+			return pcontext.New(log, programID, ids.Empty).WithActor(actor)
+		})
+		rt := runtime.New(log, cfg, supported.Imports())
+		require.NoError(rt.Initialize(ctx, tokenProgramBytes))
+
+		programIDPtr, err := runtime.WriteBytes(rt.Memory(), programID[:])
+		require.NoError(err)
+		alicePtr, err := newKeyPtr(ctx, alice, rt)
+		require.NoError(err)
+		bobPtr, err := newKeyPtr(ctx, bob, rt)
+		require.NoError(err)
+		return rt, programIDPtr, alicePtr, bobPtr
+	}
+
+	// alice approves bob to spend 100 of her balance
+	rt, programIDPtr, alicePtr, bobPtr := newRuntime(alice)
+	defer rt.Stop()
+	_, err = rt.Call(ctx, "init", programIDPtr)
+	require.NoError(err)
+	_, err = rt.Call(ctx, "mint_to", programIDPtr, alicePtr, 1000)
+	require.NoError(err)
+	_, err = rt.Call(ctx, "approve", programIDPtr, bobPtr, 100)
+	require.NoError(err)
+	result, err := rt.Call(ctx, "allowance", programIDPtr, alicePtr, bobPtr)
+	require.NoError(err)
+	require.Equal(uint64(100), result[0])
+
+	// bob can only spend the allowance granted by alice
+	rt, programIDPtr, alicePtr, bobPtr = newRuntime(bob)
+	defer rt.Stop()
+	_, err = rt.Call(ctx, "transfer_from", programIDPtr, alicePtr, bobPtr, 60)
+	require.NoError(err)
+	result, err = rt.Call(ctx, "get_balance", programIDPtr, bobPtr)
+	require.NoError(err)
+	require.Equal(uint64(60), result[0])
+	result, err = rt.Call(ctx, "allowance", programIDPtr, alicePtr, bobPtr)
+	require.NoError(err)
+	require.Equal(uint64(40), result[0])
+	_, err = rt.Call(ctx, "transfer_from", programIDPtr, alicePtr, bobPtr, 41)
+	require.Error(err)
+
+	// approving as bob grants an allowance over the balance of bob, not alice
+	_, err = rt.Call(ctx, "approve", programIDPtr, bobPtr, 1000)
+	require.NoError(err)
+	result, err = rt.Call(ctx, "allowance", programIDPtr, alicePtr, bobPtr)
+	require.NoError(err)
+	require.Equal(uint64(40), result[0])
+}
+
+// hasExport reports whether the wasm module [programBytes] exports [name].
+func hasExport(t *testing.T, programBytes []byte, name string) bool {
+	mod, err := wasmtime.NewModule(wasmtime.NewEngine(), programBytes)
+	require.NoError(t, err)
+	for _, export := range mod.Exports() {
+		if export.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
 // go test -v -benchmem -run=^$ -bench ^BenchmarkTokenProgram$ github.com/ava-labs/hypersdk/x/programs/examples -memprofile benchvset.mem -cpuprofile benchvset.cpu
 func BenchmarkTokenProgram(b *testing.B) {
 	require := require.New(b)
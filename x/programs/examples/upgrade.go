@@ -0,0 +1,97 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package examples
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/program"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+const migrateFnName = "migrate"
+
+// migrateParams are the params of the migrate function.
+type migrateParams struct {
+	ProgramID   ids.ID `program:"ptr"`
+	PrevVersion uint32 `program:"u64"`
+}
+
+// Upgrade simulates an upgrade program transaction. [programBytes] are stored
+// as the next version of [programID]. A program stored by storage.SetProgram
+// has no versions, its code is saved as version 1 first. If the new module
+// exports a `migrate` function it is called with the program ID and the
+// previous version before the new version is stored, giving the program access
+// to the state namespace of the previous version. The writes of migrate and
+// the new version are only committed if migrate succeeds. Returns the new
+// version.
+func Upgrade(
+	ctx context.Context,
+	log logging.Logger,
+	db state.Mutable,
+	cfg *runtime.Config,
+	imports runtime.SupportedImports,
+	programID ids.ID,
+	programBytes []byte,
+) (uint32, error) {
+	pending := storage.NewPendingState(db)
+	prevVersion, exists, err := storage.GetProgramLatestVersion(ctx, pending, programID)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		prevBytes, exists, err := storage.GetProgram(ctx, pending, programID)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			prevVersion, err = storage.SetProgramVersion(ctx, pending, programID, prevBytes)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	rt := runtime.New(log, cfg, program.WithState(program.BindImports(imports, programID), pending))
+	err = rt.Initialize(ctx, programBytes)
+	if err != nil {
+		return 0, err
+	}
+	defer rt.Stop()
+
+	_, err = callWithParams(ctx, rt, migrateFnName, migrateParams{ProgramID: programID, PrevVersion: prevVersion})
+	switch {
+	case errors.Is(err, runtime.ErrMissingExportedFunction):
+		log.Debug("program does not export migrate function",
+			zap.String("id", programID.String()),
+		)
+	case err != nil:
+		return 0, fmt.Errorf("failed to migrate program: %w", err)
+	}
+
+	version, err := storage.SetProgramVersion(ctx, pending, programID, programBytes)
+	if err != nil {
+		return 0, err
+	}
+	err = pending.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Debug("program upgraded",
+		zap.String("id", programID.String()),
+		zap.Uint32("version", version),
+	)
+
+	return version, nil
+}
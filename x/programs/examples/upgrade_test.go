@@ -0,0 +1,176 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package examples
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/pstate"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+func TestUpgrade(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	// no migrate function
+	v1, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 0
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	// migrate only succeeds from version 1
+	v2, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 0
+	  )
+	  (func (export "migrate_guest") (param i64 i64) (result i64)
+	    (if (i64.ne (local.get 1) (i64.const 1))
+	      (then unreachable))
+	    i64.const 0
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	newConfig := func() *runtime.Config {
+		cfg, err := runtime.NewConfigBuilder(10000).Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	version, err := Upgrade(ctx, log, db, newConfig(), runtime.NoSupportedImports, programID, v1)
+	require.NoError(err)
+	require.Equal(uint32(1), version)
+
+	version, err = Upgrade(ctx, log, db, newConfig(), runtime.NoSupportedImports, programID, v2)
+	require.NoError(err)
+	require.Equal(uint32(2), version)
+
+	// migrate traps when upgrading from version 2
+	_, err = Upgrade(ctx, log, db, newConfig(), runtime.NoSupportedImports, programID, v2)
+	require.ErrorContains(err, "failed to migrate program")
+
+	latest, exists, err := storage.GetProgramLatestVersion(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(uint32(2), latest)
+
+	bytes, exists, err := storage.GetProgramVersion(ctx, db, programID, 1)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(v1, bytes)
+
+	bytes, exists, err = storage.GetProgram(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(v2, bytes)
+}
+
+func TestUpgradeStoredProgram(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := utils.NewTestDB()
+	programID := ids.GenerateTestID()
+
+	original, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 0
+	  )
+	)
+	`)
+	require.NoError(err)
+	require.NoError(storage.SetProgram(ctx, db, programID, original))
+
+	// writes "v" to the key "k" then traps unless migrating from version 1
+	migrate := func(trap bool) []byte {
+		body := "i64.const 0"
+		if trap {
+			body = "unreachable"
+		}
+		wasm, err := wasmtime.Wat2Wasm(fmt.Sprintf(`
+		(module
+		  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+		  (memory 1)
+		  (export "memory" (memory 0))
+		  (data (i32.const 0) "kv")
+		  (func (export "alloc") (param i32) (result i32)
+		    i32.const 1024
+		  )
+		  (func (export "migrate_guest") (param i64 i64) (result i64)
+		    (drop (call $put (local.get 0) (i32.const 0) (i32.const 1) (i32.const 1) (i32.const 1)))
+		    (if (i64.ne (local.get 1) (i64.const 1))
+		      (then unreachable))
+		    %s
+		  )
+		)
+		`, body))
+		require.NoError(err)
+		return wasm
+	}
+
+	supported := runtime.NewSupportedImports()
+	supported.Register(pstate.Name, func() runtime.Import {
+		return pstate.New(log, db)
+	})
+	newConfig := func() *runtime.Config {
+		cfg, err := runtime.NewConfigBuilder(100000).Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	// a failed migrate leaves state untouched, the original code is not
+	// saved either
+	_, err = Upgrade(ctx, log, db, newConfig(), supported.Imports(), programID, migrate(true))
+	require.ErrorContains(err, "failed to migrate program")
+	_, err = db.GetValue(ctx, storage.ProgramPrefixKey(programID[:], []byte("k")))
+	require.ErrorIs(err, database.ErrNotFound)
+	_, exists, err := storage.GetProgramLatestVersion(ctx, db, programID)
+	require.NoError(err)
+	require.False(exists)
+
+	// the original code is saved as version 1 before it is upgraded
+	v2 := migrate(false)
+	version, err := Upgrade(ctx, log, db, newConfig(), supported.Imports(), programID, v2)
+	require.NoError(err)
+	require.Equal(uint32(2), version)
+
+	bytes, exists, err := storage.GetProgramVersion(ctx, db, programID, 1)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(original, bytes)
+
+	bytes, exists, err = storage.GetProgram(ctx, db, programID)
+	require.NoError(err)
+	require.True(exists)
+	require.Equal(v2, bytes)
+
+	v, err := db.GetValue(ctx, storage.ProgramPrefixKey(programID[:], []byte("k")))
+	require.NoError(err)
+	require.Equal([]byte("v"), v)
+}
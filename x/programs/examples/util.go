@@ -6,10 +6,42 @@ package examples
 import (
 	"context"
 
+	"github.com/ava-labs/avalanchego/ids"
+
 	"github.com/ava-labs/hypersdk/crypto/ed25519"
 	"github.com/ava-labs/hypersdk/x/programs/runtime"
 )
 
+// programParams are the params of a function taking the program.
+type programParams struct {
+	ProgramID ids.ID `program:"ptr"`
+}
+
+// accountParams are the params of a function taking the program and an
+// account.
+type accountParams struct {
+	ProgramID ids.ID            `program:"ptr"`
+	Account   ed25519.PublicKey `program:"ptr"`
+}
+
+// amountParams are the params of a function taking the program, an account
+// and an amount.
+type amountParams struct {
+	ProgramID ids.ID            `program:"ptr"`
+	Account   ed25519.PublicKey `program:"ptr"`
+	Amount    uint64            `program:"u64"`
+}
+
+// callWithParams calls the function [name] of [rt] with the params described
+// by [v], see runtime.WriteParams.
+func callWithParams(ctx context.Context, rt runtime.Runtime, name string, v interface{}) ([]uint64, error) {
+	params, err := runtime.WriteParams(rt.Memory(), v)
+	if err != nil {
+		return nil, err
+	}
+	return rt.Call(ctx, name, params...)
+}
+
 func newKeyPtr(ctx context.Context, key ed25519.PublicKey, runtime runtime.Runtime) (uint64, error) {
 	ptr, err := runtime.Memory().Alloc(ed25519.PublicKeyLen)
 	if err != nil {
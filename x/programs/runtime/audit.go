@@ -0,0 +1,81 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+)
+
+var callerType = reflect.TypeOf((*wasmtime.Caller)(nil))
+
+// ImportCall is an invocation of a host function by the guest recorded in
+// import audit mode.
+type ImportCall struct {
+	Module   string
+	Function string
+	// Args and Results summarize the values passed to and returned by the
+	// host function.
+	Args    string
+	Results string
+	// Units is the number of units charged by the invocation.
+	Units uint64
+	// Trap is the message of the trap of the invocation, if any.
+	Trap string
+}
+
+func (c ImportCall) String() string {
+	s := fmt.Sprintf("%s.%s(%s) = (%s) units=%d", c.Module, c.Function, c.Args, c.Results, c.Units)
+	if c.Trap != "" {
+		s += " trap=" + c.Trap
+	}
+	return s
+}
+
+// auditLog is a ring buffer of the most recent import calls.
+type auditLog struct {
+	calls []ImportCall
+	next  int
+	full  bool
+}
+
+func newAuditLog(size int) *auditLog {
+	return &auditLog{calls: make([]ImportCall, size)}
+}
+
+func (a *auditLog) add(call ImportCall) {
+	a.calls[a.next] = call
+	a.next = (a.next + 1) % len(a.calls)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// list returns the recorded calls from oldest to newest.
+func (a *auditLog) list() []ImportCall {
+	if !a.full {
+		return append([]ImportCall{}, a.calls[:a.next]...)
+	}
+	return append(append([]ImportCall{}, a.calls[a.next:]...), a.calls[:a.next]...)
+}
+
+func (a *auditLog) reset() {
+	a.next = 0
+	a.full = false
+}
+
+// summarize formats [values], skipping the caller and the trap.
+func summarize(values []reflect.Value) string {
+	s := make([]string, 0, len(values))
+	for _, v := range values {
+		if v.Type() == callerType || v.Type() == trapType {
+			continue
+		}
+		s = append(s, fmt.Sprint(v.Interface()))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,78 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportAudit(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// calls inc [n] times on the param
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "test" "inc" (func $inc (param i32) (result i32)))
+	  (func (export "inc_guest") (param $v i32) (param $n i32) (result i32)
+	    (loop $l
+	      (local.set $v (call $inc (local.get $v)))
+	      (local.set $n (i32.sub (local.get $n) (i32.const 1)))
+	      (br_if $l (i32.gt_s (local.get $n) (i32.const 0)))
+	    )
+	    (local.get $v)
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	imp := &testImport{units: 300}
+	supported := NewSupportedImports()
+	supported.Register("test", func() Import {
+		return imp
+	})
+
+	cfg, err := NewConfigBuilder(1000).
+		WithImportAudit(2).
+		Build()
+	require.NoError(err)
+	rt := New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	resp, err := rt.Call(ctx, "inc", 1, 1)
+	require.NoError(err)
+	require.Equal([]uint64{2}, resp)
+	require.Equal([]ImportCall{
+		{Module: "test", Function: "inc", Args: "1", Results: "2", Units: 300},
+	}, rt.(*WasmRuntime).ImportCalls())
+
+	// only the most recent calls are kept and the last call has
+	// insufficient units
+	_, err = rt.Call(ctx, "inc", 1, 4)
+	require.ErrorContains(err, ErrInsufficientUnits.Error())
+	calls := rt.(*WasmRuntime).ImportCalls()
+	require.Len(calls, 2)
+	require.Equal(ImportCall{Module: "test", Function: "inc", Args: "2", Results: "3", Units: 300}, calls[0])
+	require.Equal("3", calls[1].Args)
+	require.Equal("0", calls[1].Results)
+	require.Zero(calls[1].Units)
+	require.Contains(calls[1].Trap, "insufficient units: test.inc")
+	require.Equal("test.inc(2) = (3) units=300", calls[0].String())
+
+	// disabled by default
+	cfg, err = NewConfigBuilder(1000).Build()
+	require.NoError(err)
+	rt = New(logging.NoLog{}, cfg, supported.Imports())
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+	_, err = rt.Call(ctx, "inc", 1, 1)
+	require.NoError(err)
+	require.Nil(rt.(*WasmRuntime).ImportCalls())
+}
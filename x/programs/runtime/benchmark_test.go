@@ -0,0 +1,251 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/program"
+	"github.com/ava-labs/hypersdk/x/programs/examples/imports/pstate"
+	"github.com/ava-labs/hypersdk/x/programs/examples/storage"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+	"github.com/ava-labs/hypersdk/x/programs/utils"
+)
+
+const benchMaxUnits = uint64(1_000_000_000)
+
+// go test -v -benchmem -run=^$ -bench ^BenchmarkInitialize$ github.com/ava-labs/hypersdk/x/programs/runtime
+func BenchmarkInitialize(b *testing.B) {
+	for _, size := range []int{1, 100, 1000} {
+		wasm := newBenchModule(b, size)
+		for _, strategy := range []runtime.EngineCompileStrategy{runtime.CompileWasm, runtime.PrecompiledWasm} {
+			programBytes := compileForStrategy(b, wasm, strategy)
+			b.Run(fmt.Sprintf("%s_%d_fns", strategyName(strategy), size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					cfg := newBenchConfig(b, strategy)
+					rt := runtime.New(logging.NoLog{}, cfg, runtime.NoSupportedImports)
+					b.StartTimer()
+					require.NoError(b, rt.Initialize(context.Background(), programBytes))
+					b.StopTimer()
+					rt.Stop()
+					b.StartTimer()
+				}
+			})
+		}
+	}
+}
+
+// go test -v -benchmem -run=^$ -bench ^BenchmarkCallNoop$ github.com/ava-labs/hypersdk/x/programs/runtime
+func BenchmarkCallNoop(b *testing.B) {
+	for _, size := range []int{1, 100, 1000} {
+		wasm := newBenchModule(b, size)
+		for _, strategy := range []runtime.EngineCompileStrategy{runtime.CompileWasm, runtime.PrecompiledWasm} {
+			programBytes := compileForStrategy(b, wasm, strategy)
+			b.Run(fmt.Sprintf("%s_%d_fns", strategyName(strategy), size), func(b *testing.B) {
+				rt := runtime.New(logging.NoLog{}, newBenchConfig(b, strategy), runtime.NoSupportedImports)
+				require.NoError(b, rt.Initialize(context.Background(), programBytes))
+				defer rt.Stop()
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := rt.Call(context.Background(), "noop", 0)
+					require.NoError(b, err)
+				}
+			})
+		}
+	}
+}
+
+// go test -v -benchmem -run=^$ -bench ^BenchmarkOptLevel$ github.com/ava-labs/hypersdk/x/programs/runtime
+func BenchmarkOptLevel(b *testing.B) {
+	wasm := newBenchModule(b, 1000)
+	levels := []struct {
+		name  string
+		level wasmtime.OptLevel
+	}{
+		{"none", wasmtime.OptLevelNone},
+		{"speed", wasmtime.OptLevelSpeed},
+		{"speed_and_size", wasmtime.OptLevelSpeedAndSize},
+	}
+	for _, l := range levels {
+		newConfig := func(b *testing.B) *runtime.Config {
+			cfg, err := runtime.NewConfigBuilder(benchMaxUnits).
+				WithOptLevel(l.level).
+				Build()
+			require.NoError(b, err)
+			return cfg
+		}
+
+		b.Run(l.name+"_initialize", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				rt := runtime.New(logging.NoLog{}, newConfig(b), runtime.NoSupportedImports)
+				b.StartTimer()
+				require.NoError(b, rt.Initialize(context.Background(), wasm))
+				b.StopTimer()
+				rt.Stop()
+				b.StartTimer()
+			}
+		})
+		b.Run(l.name+"_call", func(b *testing.B) {
+			rt := runtime.New(logging.NoLog{}, newConfig(b), runtime.NoSupportedImports)
+			require.NoError(b, rt.Initialize(context.Background(), wasm))
+			defer rt.Stop()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := rt.Call(context.Background(), "noop", 0)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+// go test -v -benchmem -run=^$ -bench ^BenchmarkCallWithStateAccess$ github.com/ava-labs/hypersdk/x/programs/runtime
+func BenchmarkCallWithStateAccess(b *testing.B) {
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "state" "put" (func $put (param i64 i32 i32 i32 i32) (result i32)))
+	  (import "state" "len" (func $len (param i64 i32 i32) (result i32)))
+	  (import "state" "get" (func $get (param i64 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 32) "key")
+	  (data (i32.const 64) "value")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 128
+	  )
+	  (func (export "access_guest") (param $id i64) (result i32)
+	    (drop (call $put (local.get $id) (i32.const 32) (i32.const 3) (i32.const 64) (i32.const 5)))
+	    (call $get (local.get $id) (i32.const 32) (i32.const 3)
+	      (call $len (local.get $id) (i32.const 32) (i32.const 3)))
+	  )
+	)
+	`)
+	require.NoError(b, err)
+
+	for _, strategy := range []runtime.EngineCompileStrategy{runtime.CompileWasm, runtime.PrecompiledWasm} {
+		programBytes := compileForStrategy(b, wasm, strategy)
+		b.Run(strategyName(strategy), func(b *testing.B) {
+			db := utils.NewTestDB()
+			supported := runtime.NewSupportedImports()
+			supported.Register(pstate.Name, func() runtime.Import {
+				return pstate.New(logging.NoLog{}, db)
+			})
+			rt := runtime.New(logging.NoLog{}, newBenchConfig(b, strategy), supported.Imports())
+			require.NoError(b, rt.Initialize(context.Background(), programBytes))
+			defer rt.Stop()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resp, err := rt.Call(context.Background(), "access", 0)
+				require.NoError(b, err)
+				require.Equal(b, uint64(128), resp[0])
+			}
+		})
+	}
+}
+
+// go test -v -benchmem -run=^$ -bench ^BenchmarkNestedCall$ github.com/ava-labs/hypersdk/x/programs/runtime
+func BenchmarkNestedCall(b *testing.B) {
+	caller, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "program" "call_program" (func $call_program (param i64 i64 i64 i32 i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 64) "noop")
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 128
+	  )
+	  (func (export "call_guest") (param $id i64) (param $target i64) (param $units i64) (result i32)
+	    (call $call_program (local.get $id) (local.get $target) (local.get $units)
+	      (i32.const 64) (i32.const 4) (i32.const 0) (i32.const 0) (i32.const 96))
+	  )
+	)
+	`)
+	require.NoError(b, err)
+	callee := newBenchModule(b, 1)
+
+	db := utils.NewTestDB()
+	calleeID := ids.GenerateTestID()
+	require.NoError(b, storage.SetProgram(context.Background(), db, calleeID, callee))
+
+	supported := runtime.NewSupportedImports()
+	supported.Register(program.Name, func() runtime.Import {
+		return program.New(logging.NoLog{}, db)
+	})
+	rt := runtime.New(logging.NoLog{}, newBenchConfig(b, runtime.CompileWasm), supported.Imports())
+	require.NoError(b, rt.Initialize(context.Background(), caller))
+	defer rt.Stop()
+
+	// callee id is read from the caller memory
+	require.NoError(b, rt.Memory().Write(0, calleeID[:]))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := rt.Call(context.Background(), "call", 0, 0, 10_000)
+		require.NoError(b, err)
+		require.Equal(b, uint64(0), resp[0])
+	}
+}
+
+// newBenchModule returns a module exporting a noop function padded with [fns]
+// additional functions to vary the module size.
+func newBenchModule(b *testing.B, fns int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    i32.const 0
+	  )
+	  (func (export "noop_guest") (param i64) (result i64)
+	    i64.const 0
+	  )
+	`)
+	for i := 0; i < fns; i++ {
+		fmt.Fprintf(&sb, "(func (export \"fn_%d\") (param i64) (result i64) (i64.add (local.get 0) (i64.const %d)))\n", i, i)
+	}
+	sb.WriteString(")")
+
+	wasm, err := wasmtime.Wat2Wasm(sb.String())
+	require.NoError(b, err)
+	return wasm
+}
+
+func newBenchConfig(b *testing.B, strategy runtime.EngineCompileStrategy) *runtime.Config {
+	cfg, err := runtime.NewConfigBuilder(benchMaxUnits).
+		WithCompileStrategy(strategy).
+		Build()
+	require.NoError(b, err)
+	return cfg
+}
+
+func compileForStrategy(b *testing.B, wasm []byte, strategy runtime.EngineCompileStrategy) []byte {
+	if strategy != runtime.PrecompiledWasm {
+		return wasm
+	}
+	programBytes, err := runtime.PreCompileWasmBytes(wasm, newBenchConfig(b, strategy))
+	require.NoError(b, err)
+	return programBytes
+}
+
+func strategyName(strategy runtime.EngineCompileStrategy) string {
+	switch strategy {
+	case runtime.PrecompiledWasm:
+		return "precompiled"
+	default:
+		return "compile"
+	}
+}
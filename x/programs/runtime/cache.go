@@ -0,0 +1,62 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+
+	"github.com/ava-labs/avalanchego/cache"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// ResultKey identifies the result of a call to a pure function.
+type ResultKey struct {
+	ProgramID ids.ID
+	Function  string
+	// Args is the encoding of the arguments of the call. The params of a
+	// call are pointers to guest memory so do not identify its arguments.
+	Args string
+	// StateRoot is the root of the state the call reads from.
+	StateRoot ids.ID
+}
+
+// ResultCache memoizes the results of calls to pure functions. A guest marks
+// the function [name] as pure by exporting [name] with the pure suffix in
+// addition to the function, for example "get_balance_pure". A pure function
+// must only read state, its result depending only on its arguments and the
+// state.
+type ResultCache struct {
+	results *cache.LRU[ResultKey, []uint64]
+}
+
+// NewResultCache returns a cache holding the results of up to [size] calls.
+func NewResultCache(size int) *ResultCache {
+	return &ResultCache{
+		results: &cache.LRU[ResultKey, []uint64]{Size: size},
+	}
+}
+
+// Call returns the cached result of [key] if its function is pure and has
+// been called before. Otherwise the function is called on [rt] with [params]
+// and the result of a pure function is cached. Errors are not cached.
+func (c *ResultCache) Call(ctx context.Context, rt Runtime, key ResultKey, params ...uint64) ([]uint64, error) {
+	p, ok := rt.(interface{ IsPure(string) bool })
+	if !ok || !p.IsPure(key.Function) {
+		return rt.Call(ctx, key.Function, params...)
+	}
+
+	if result, ok := c.results.Get(key); ok {
+		return copyResult(result), nil
+	}
+	result, err := rt.Call(ctx, key.Function, params...)
+	if err != nil {
+		return nil, err
+	}
+	c.results.Put(key, copyResult(result))
+	return result, nil
+}
+
+func copyResult(result []uint64) []uint64 {
+	return append([]uint64{}, result...)
+}
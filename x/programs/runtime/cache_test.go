@@ -0,0 +1,98 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultCache(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// each call returns the number of calls made so far
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (global $count (mut i32) (i32.const 0))
+	  (func $inc (result i32)
+	    (global.set $count (i32.add (global.get $count) (i32.const 1)))
+	    (global.get $count)
+	  )
+	  (func (export "get_guest") (param i32) (result i32)
+	    (if (i32.eqz (local.get 0)) (then unreachable))
+	    (call $inc)
+	  )
+	  (func (export "get_pure"))
+	  (func (export "set_guest") (param i32) (result i32)
+	    (call $inc)
+	  )
+	)
+	`)
+	require.NoError(err)
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	rt := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	require.True(rt.(*WasmRuntime).IsPure("get"))
+	require.False(rt.(*WasmRuntime).IsPure("set"))
+
+	cache := NewResultCache(10)
+	key := ResultKey{ProgramID: ids.ID{1}, Function: "get", Args: "a", StateRoot: ids.ID{1}}
+
+	resp, err := cache.Call(ctx, rt, key, 1)
+	require.NoError(err)
+	require.Equal([]uint64{1}, resp)
+
+	// cached result is returned without calling the guest
+	resp, err = cache.Call(ctx, rt, key, 1)
+	require.NoError(err)
+	require.Equal([]uint64{1}, resp)
+
+	// modifying the result does not modify the cache
+	resp[0] = 0
+	resp, err = cache.Call(ctx, rt, key, 1)
+	require.NoError(err)
+	require.Equal([]uint64{1}, resp)
+
+	// different args miss the cache
+	argsKey := key
+	argsKey.Args = "b"
+	resp, err = cache.Call(ctx, rt, argsKey, 1)
+	require.NoError(err)
+	require.Equal([]uint64{2}, resp)
+
+	// different state root misses the cache
+	rootKey := key
+	rootKey.StateRoot = ids.ID{2}
+	resp, err = cache.Call(ctx, rt, rootKey, 1)
+	require.NoError(err)
+	require.Equal([]uint64{3}, resp)
+
+	// errors are not cached
+	errKey := key
+	errKey.Args = "err"
+	_, err = cache.Call(ctx, rt, errKey, 0)
+	require.Error(err)
+	resp, err = cache.Call(ctx, rt, errKey, 1)
+	require.NoError(err)
+	require.Equal([]uint64{4}, resp)
+
+	// functions which are not pure are always called
+	setKey := key
+	setKey.Function = "set"
+	resp, err = cache.Call(ctx, rt, setKey, 1)
+	require.NoError(err)
+	require.Equal([]uint64{5}, resp)
+	resp, err = cache.Call(ctx, rt, setKey, 1)
+	require.NoError(err)
+	require.Equal([]uint64{6}, resp)
+}
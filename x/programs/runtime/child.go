@@ -0,0 +1,52 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import "fmt"
+
+// NewChildRuntime returns a runtime for a call made on behalf of this runtime,
+// such as a program to program call. The child shares the engine of this
+// runtime, so stopping this runtime interrupts the child, and the engine
+// settings of [cfg] are ignored.
+//
+// The child must be configured with NoUnits: it can only spend units
+// transferred from the meter of this runtime, so calls made by the host, such
+// as allocating params, are never free. Once the calls of the child return,
+// the caller returns its remaining balance to this runtime with ReturnUnits.
+// Stop does not, as it may be called from another goroutine while the child
+// is running.
+//
+// The child has no state of its own, any state is accessed through
+// [imports]. Callers must pass imports backed by a view of state which is only
+// committed once the call of the child succeeds, or a read-only view, as the
+// program import does for call_program and call_program_readonly.
+func (r *WasmRuntime) NewChildRuntime(cfg *Config, imports SupportedImports) (Runtime, error) {
+	if r.engine == nil || r.meter == nil {
+		return nil, fmt.Errorf("%w: parent runtime not initialized", ErrInvalidChildRuntime)
+	}
+	if cfg.meterMaxUnits != NoUnits {
+		return nil, fmt.Errorf("%w: max units must be %d: %d", ErrInvalidChildRuntime, NoUnits, cfg.meterMaxUnits)
+	}
+
+	return &WasmRuntime{
+		engine:       r.engine,
+		sharedEngine: true,
+		parent:       r,
+		imports:      imports,
+		log:          r.log,
+		cfg:          cfg,
+	}, nil
+}
+
+// ReturnUnits transfers the remaining balance of [child] to [parent]. It must
+// be called on the goroutine running the calls of the child, after they
+// return. A child without a meter, such as one which failed to initialize,
+// has no units to return.
+func ReturnUnits(child, parent Runtime) error {
+	if child.Meter() == nil {
+		return nil
+	}
+	_, err := child.Meter().TransferUnits(parent.Meter(), child.Meter().GetBalance())
+	return err
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+)
+
+func TestNewChildRuntime(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "get_guest") (result i64)
+	    (i64.add (i64.const 1) (i64.const 2))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	newConfig := func(units uint64) *Config {
+		cfg, err := NewConfigBuilder(units).Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	parent := New(logging.NoLog{}, newConfig(10000), NoSupportedImports).(*WasmRuntime)
+	_, err = parent.NewChildRuntime(newConfig(NoUnits), NoSupportedImports)
+	require.ErrorIs(err, ErrInvalidChildRuntime)
+
+	require.NoError(parent.Initialize(ctx, wasm))
+	defer parent.Stop()
+
+	// a child can only spend units transferred by the parent
+	_, err = parent.NewChildRuntime(newConfig(1000), NoSupportedImports)
+	require.ErrorIs(err, ErrInvalidChildRuntime)
+
+	child, err := parent.NewChildRuntime(newConfig(NoUnits), NoSupportedImports)
+	require.NoError(err)
+	require.NoError(child.Initialize(ctx, wasm))
+	require.Equal(parent.engine, child.(*WasmRuntime).engine)
+	require.Zero(child.Meter().GetBalance())
+
+	_, err = child.Call(ctx, "get")
+	require.ErrorIs(err, errs.ErrOutOfFuel)
+
+	_, err = parent.Meter().TransferUnits(child.Meter(), 5000)
+	require.NoError(err)
+	resp, err := child.Call(ctx, "get")
+	require.NoError(err)
+	require.Equal(uint64(3), resp[0])
+
+	// stop leaves the balance of the child untouched, it is returned by the
+	// caller once the calls of the child return
+	parentBalance := parent.Meter().GetBalance()
+	childBalance := child.Meter().GetBalance()
+	require.Less(childBalance, uint64(5000))
+	child.Stop()
+	require.Equal(parentBalance, parent.Meter().GetBalance())
+	require.Equal(childBalance, child.Meter().GetBalance())
+	require.NoError(ReturnUnits(child, parent))
+	require.Equal(parentBalance+childBalance, parent.Meter().GetBalance())
+	require.Zero(child.Meter().GetBalance())
+
+	// a child which failed to initialize has no units to return
+	child, err = parent.NewChildRuntime(newConfig(NoUnits), NoSupportedImports)
+	require.NoError(err)
+	require.Error(child.Initialize(ctx, []byte("invalid")))
+	require.NoError(ReturnUnits(child, parent))
+}
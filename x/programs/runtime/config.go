@@ -3,10 +3,23 @@
 
 package runtime
 
-import "github.com/bytecodealliance/wasmtime-go/v13"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"golang.org/x/exp/maps"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/hashing"
+	"github.com/ava-labs/avalanchego/utils/units"
+)
 
 const (
-	defaultMaxWasmStack                 = 256 * 1024 * 1024 // 256 MiB
 	defaultWasmThreads                  = false
 	defaultFuelMetering                 = true
 	defaultWasmMultiMemory              = false
@@ -23,18 +36,29 @@ const (
 	defaultMultiValue                   = false
 	defaultEnableCraneliftDebugVerifier = false
 	defaultEnableDebugInfo              = false
+	defaultMaxResultSize                = 64 * units.KiB
+	defaultMaxParamsSize                = 64 * units.KiB
+	// defaultMaxWasmStack is the same on every platform so a call overflows
+	// its stack on all validators or none, and fits under the smallest
+	// native stack of a supported platform, the 512 KiB of non-main threads
+	// on darwin.
+	defaultMaxWasmStack = 256 * units.KiB
 
 	defaultLimitMaxTableElements = 4096
 	defaultLimitMaxTables        = 1
 	defaultLimitMaxInstances     = 32
 	defaultLimitMaxMemories      = 1
+	// used in place of defaultLimitMaxMemories when multi-memory is enabled
+	defaultLimitMaxMultiMemories = 4
 )
 
 func NewConfigBuilder(meterMaxUnits uint64) *builder {
 	cfg := defaultWasmtimeConfig()
 	return &builder{
-		cfg:           cfg,
-		meterMaxUnits: meterMaxUnits,
+		cfg:            cfg,
+		meterMaxUnits:  meterMaxUnits,
+		maxWasmStack:   defaultMaxWasmStack,
+		engineSettings: defaultEngineSettings(),
 	}
 }
 
@@ -44,10 +68,34 @@ type builder struct {
 	// engine
 	compileStrategy EngineCompileStrategy
 	defaultCache    bool
+	cacheDir        string
+	cacheSizeLimit  uint64
 	meterMaxUnits   uint64
+	multiMemory     bool
+	referenceTypes  bool
+	bulkMemory      bool
+	testingOnlyMode bool
+	newMeter        NewMeterFn
+	subsidyPolicy   SubsidyPolicy
+	maxWasmStack    int
+	memoryReset     bool
+	coverage        bool
+	noMetering      bool
+	importAuditSize int
+	// engineSettings are the settings of the engine config which determine
+	// the code compiled by the engine.
+	engineSettings map[string]string
 
 	// limit
-	limitMaxMemory int64
+	limitMaxMemory        int64
+	limitMaxTableElements int64
+	limitMaxTables        int64
+	limitMaxInstances     int64
+	limitMaxMemories      int64
+	maxResultSize         uint64
+	maxParamsSize         uint64
+
+	programID ids.ID
 }
 
 type Config struct {
@@ -63,6 +111,47 @@ type Config struct {
 
 	compileStrategy EngineCompileStrategy
 	meterMaxUnits   uint64
+	maxResultSize   uint64
+	maxParamsSize   uint64
+	testingOnlyMode bool
+	programID       ids.ID
+	newMeter        NewMeterFn
+	subsidyPolicy   SubsidyPolicy
+	memoryReset     bool
+	coverage        bool
+	noMetering      bool
+	importAuditSize int
+	engineHash      ids.ID
+}
+
+// EngineHash returns the hash of the engine settings of the config and the
+// version of wasmtime. Precompiled modules can only be loaded by an engine
+// with the same hash.
+func (c *Config) EngineHash() ids.ID {
+	return c.engineHash
+}
+
+// ArtifactVersion returns the version of the precompiled modules produced by
+// an engine of the config, see storage.SetProgramArtifact.
+func (c *Config) ArtifactVersion() string {
+	return artifactVersion(c.engineHash)
+}
+
+// artifactVersion returns the artifact version of an engine with [hash].
+func artifactVersion(hash ids.ID) string {
+	return WasmtimeVersion() + "+" + hash.Hex()
+}
+
+// MaxResultSize returns the maximum number of bytes which can be read from
+// guest memory as the result of a call.
+func (c *Config) MaxResultSize() uint64 {
+	return c.maxResultSize
+}
+
+// MaxParamsSize returns the maximum number of bytes which can be written to
+// guest memory as the params of a call.
+func (c *Config) MaxParamsSize() uint64 {
+	return c.maxParamsSize
 }
 
 // WithCompileStrategy defines the EngineCompileStrategy.
@@ -73,11 +162,15 @@ func (b *builder) WithCompileStrategy(strategy EngineCompileStrategy) *builder {
 }
 
 // WithMaxWasmStack defines the maximum amount of stack space available for
-// executing WebAssembly code.
+// executing WebAssembly code. Must be smaller than the native stack of the
+// calling thread, otherwise unbounded recursion crashes the process instead
+// of trapping.
 //
-// Default is 256 MiB.
+// Default is 256 KiB on every platform.
 func (b *builder) WithMaxWasmStack(max int) *builder {
 	b.cfg.SetMaxWasmStack(max)
+	b.maxWasmStack = max
+	b.engineSetting("max_wasm_stack", max)
 	return b
 }
 
@@ -87,6 +180,31 @@ func (b *builder) WithMaxWasmStack(max int) *builder {
 // Default is false.
 func (b *builder) WithMultiValue(enable bool) *builder {
 	b.cfg.SetWasmMultiValue(enable)
+	b.engineSetting("multi_value", enable)
+	return b
+}
+
+// WithMemory64 enables modules to define 64-bit memories which are indexed
+// with i64 instead of i32.
+//
+// ref. https://github.com/WebAssembly/memory64
+// Default is false.
+func (b *builder) WithMemory64(enable bool) *builder {
+	b.cfg.SetWasmMemory64(enable)
+	b.engineSetting("memory64", enable)
+	return b
+}
+
+// WithMultiMemory enables modules to define and import more than one memory.
+// When enabled the store limits the number of memories per module to 4, each
+// memory is still subject to the limit defined by WithLimitMaxMemory.
+//
+// ref. https://github.com/WebAssembly/multi-memory
+// Default is false.
+func (b *builder) WithMultiMemory(enable bool) *builder {
+	b.cfg.SetWasmMultiMemory(enable)
+	b.multiMemory = enable
+	b.engineSetting("multi_memory", enable)
 	return b
 }
 
@@ -96,6 +214,8 @@ func (b *builder) WithMultiValue(enable bool) *builder {
 // Default is false.
 func (b *builder) WithBulkMemory(enable bool) *builder {
 	b.cfg.SetWasmBulkMemory(enable)
+	b.bulkMemory = enable
+	b.engineSetting("bulk_memory", enable)
 	return b
 }
 
@@ -108,6 +228,8 @@ func (b *builder) WithBulkMemory(enable bool) *builder {
 // Default is false.
 func (b *builder) WithReferenceTypes(enable bool) *builder {
 	b.cfg.SetWasmReferenceTypes(enable)
+	b.referenceTypes = enable
+	b.engineSetting("reference_types", enable)
 	return b
 }
 
@@ -117,6 +239,7 @@ func (b *builder) WithReferenceTypes(enable bool) *builder {
 // Default is false.
 func (b *builder) WithSIMD(enable bool) *builder {
 	b.cfg.SetWasmSIMD(enable)
+	b.engineSetting("simd", enable)
 	return b
 }
 
@@ -126,6 +249,38 @@ func (b *builder) WithSIMD(enable bool) *builder {
 // Default is `wasmtime.ProfilingStrategyNone`.
 func (b *builder) WithProfilingStrategy(strategy wasmtime.ProfilingStrategy) *builder {
 	b.cfg.SetProfiler(strategy)
+	b.engineSetting("profiler", strategy)
+	return b
+}
+
+// WithOptLevel defines the optimization level of the Cranelift compiler,
+// trading compile time, and thus the latency of the first call of a module,
+// against execution speed.
+//
+// Default is `wasmtime.OptLevelSpeed`.
+func (b *builder) WithOptLevel(level wasmtime.OptLevel) *builder {
+	b.cfg.SetCraneliftOptLevel(level)
+	b.engineSetting("cranelift_opt_level", level)
+	return b
+}
+
+// WithDebugInfo enables the generation of DWARF debug information for
+// compiled modules, providing symbolicated traps.
+//
+// Default is false.
+func (b *builder) WithDebugInfo(enable bool) *builder {
+	b.cfg.SetDebugInfo(enable)
+	b.engineSetting("debug_info", enable)
+	return b
+}
+
+// WithCraneliftDebugVerifier enables the Cranelift IR verifier which checks
+// generated code at the cost of compile time.
+//
+// Default is false.
+func (b *builder) WithCraneliftDebugVerifier(enable bool) *builder {
+	b.cfg.SetCraneliftDebugVerifier(enable)
+	b.engineSetting("cranelift_debug_verifier", enable)
 	return b
 }
 
@@ -138,6 +293,145 @@ func (b *builder) WithLimitMaxMemory(max int64) *builder {
 	return b
 }
 
+// WithLimitMaxTableElements defines the maximum number of elements of a
+// single table.
+//
+// Default is 4096.
+func (b *builder) WithLimitMaxTableElements(max int64) *builder {
+	b.limitMaxTableElements = max
+	return b
+}
+
+// WithLimitMaxTables defines the maximum number of tables which can be
+// created by a module. More than 1 table requires reference types.
+//
+// Default is 1.
+func (b *builder) WithLimitMaxTables(max int64) *builder {
+	b.limitMaxTables = max
+	return b
+}
+
+// WithLimitMaxInstances defines the maximum number of instances which can be
+// created in a store.
+//
+// Default is 32.
+func (b *builder) WithLimitMaxInstances(max int64) *builder {
+	b.limitMaxInstances = max
+	return b
+}
+
+// WithLimitMaxMemories defines the maximum number of linear memories which can
+// be created by a module. More than 1 memory requires multi-memory.
+//
+// Default is 1, or 4 if multi-memory is enabled.
+func (b *builder) WithLimitMaxMemories(max int64) *builder {
+	b.limitMaxMemories = max
+	return b
+}
+
+// WithMaxResultSize defines the maximum number of bytes which can be read from
+// guest memory as the result of a call.
+//
+// Default is 64 KiB.
+func (b *builder) WithMaxResultSize(max uint64) *builder {
+	b.maxResultSize = max
+	return b
+}
+
+// WithMaxParamsSize defines the maximum number of bytes which can be written
+// to guest memory as the params of a call, the sum of all blocks allocated
+// through Runtime.Memory before the call.
+//
+// Default is 64 KiB.
+func (b *builder) WithMaxParamsSize(max uint64) *builder {
+	b.maxParamsSize = max
+	return b
+}
+
+// WithEnableTestingOnlyMode enables WASI imports so guests can print to
+// stdout and stderr. Output of each call is captured and logged instead of
+// being written to the process. The wall time and CPU time of each call, and
+// the maximum RSS of the process, are also measured and logged at debug level.
+//
+// Note: WASI is not deterministic and must never be enabled in production.
+// Default is false.
+func (b *builder) WithEnableTestingOnlyMode(enabled bool) *builder {
+	b.testingOnlyMode = enabled
+	return b
+}
+
+// WithProgramID sets the ID of the program executed by the runtime, which is
+// attached to the logs of the guest output in testing only mode.
+//
+// Default is ids.Empty.
+func (b *builder) WithProgramID(id ids.ID) *builder {
+	b.programID = id
+	return b
+}
+
+// WithMemoryReset keeps a copy of the exported memory of the guest at
+// instantiation so WasmRuntime.ResetMemory can restore it between calls.
+// This prevents data written by one call from being read by a later call of
+// a reused runtime, for example by calls of different transactions. Globals
+// of the guest are not reset.
+//
+// Default is false.
+func (b *builder) WithMemoryReset(enabled bool) *builder {
+	b.memoryReset = enabled
+	return b
+}
+
+// WithCoverage instruments the guest to count the entries of each of its
+// functions, read by WasmRuntime.Coverage. The units consumed by the
+// instrumentation are refunded after each call, but a call may still run out
+// of units which would not otherwise. Requires testing only mode and the
+// CompileWasm strategy.
+//
+// Default is false.
+func (b *builder) WithCoverage(enabled bool) *builder {
+	b.coverage = enabled
+	return b
+}
+
+// WithNoMetering disables fuel consumption so the raw execution speed of a
+// guest can be profiled without the overhead of metering. Calls never run
+// out of units and imports are not charged. Requires testing only mode.
+//
+// Default is false.
+func (b *builder) WithNoMetering(enabled bool) *builder {
+	b.noMetering = enabled
+	return b
+}
+
+// WithImportAudit records the last [size] invocations of host functions
+// during a call, retrievable by WasmRuntime.ImportCalls. The recorded
+// invocations are logged if the call fails. A size of 0 disables the audit.
+//
+// Default is 0.
+func (b *builder) WithImportAudit(size int) *builder {
+	b.importAuditSize = size
+	return b
+}
+
+// WithMeter defines the function used to create the meter of the runtime
+// from the default fuel backed meter.
+//
+// Default is the fuel backed meter.
+func (b *builder) WithMeter(fn NewMeterFn) *builder {
+	b.newMeter = fn
+	return b
+}
+
+// WithSubsidyPolicy defines the policy deciding which imports may add units
+// to the meter of a call in excess of the units they spent, for example a
+// system sponsored callback. An import can always refund units it spent.
+//
+// Default is nil, no import may subsidize a call.
+func (b *builder) WithSubsidyPolicy(policy SubsidyPolicy) *builder {
+	b.subsidyPolicy = policy
+	return b
+}
+
 // WithDefaultCache enables the default caching strategy.
 //
 // Default is false.
@@ -146,35 +440,250 @@ func (b *builder) WithDefaultCache(enabled bool) *builder {
 	return b
 }
 
+// WithCacheDir enables the cache of compiled modules in [dir] instead of the
+// default location of wasmtime, so multi-user machines and CI containers can
+// control where compiled modules are stored.
+//
+// Default is "", the default location if WithDefaultCache is enabled.
+func (b *builder) WithCacheDir(dir string) *builder {
+	b.cacheDir = dir
+	return b
+}
+
+// WithCacheSizeLimit enables the cache of compiled modules and defines the
+// soft limit of its total size in bytes, the least recently used modules are
+// evicted once the limit is exceeded.
+//
+// Default is 0, the default limit of wasmtime (512 MiB).
+func (b *builder) WithCacheSizeLimit(limit uint64) *builder {
+	b.cacheSizeLimit = limit
+	return b
+}
+
 func (b *builder) Build() (*Config, error) {
-	if b.defaultCache {
-		err := b.cfg.CacheConfigLoadDefault()
-		if err != nil {
-			return nil, err
-		}
+	if err := b.loadCache(); err != nil {
+		return nil, err
 	}
 
 	if b.limitMaxMemory == 0 {
 		b.limitMaxMemory = defaultLimitMaxMemory
 	}
 
+	if b.maxResultSize == 0 {
+		b.maxResultSize = defaultMaxResultSize
+	}
+
+	if b.maxParamsSize == 0 {
+		b.maxParamsSize = defaultMaxParamsSize
+	}
+
+	if b.limitMaxTableElements == 0 {
+		b.limitMaxTableElements = defaultLimitMaxTableElements
+	}
+	if b.limitMaxTables == 0 {
+		b.limitMaxTables = defaultLimitMaxTables
+	}
+	if b.limitMaxInstances == 0 {
+		b.limitMaxInstances = defaultLimitMaxInstances
+	}
+	if b.limitMaxMemories == 0 {
+		b.limitMaxMemories = defaultLimitMaxMemories
+		if b.multiMemory {
+			b.limitMaxMemories = defaultLimitMaxMultiMemories
+		}
+	}
+
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
+	if b.noMetering {
+		b.cfg.SetConsumeFuel(false)
+		b.engineSetting("consume_fuel", false)
+	}
+
 	return &Config{
 		// engine config
 		engine: b.cfg,
 
 		// limits
-		limitMaxTableElements: defaultLimitMaxTableElements,
+		limitMaxTableElements: b.limitMaxTableElements,
 		limitMaxMemory:        b.limitMaxMemory,
-		limitMaxTables:        defaultLimitMaxTables,
-		limitMaxInstances:     defaultLimitMaxInstances,
-		limitMaxMemories:      defaultLimitMaxMemories,
+		limitMaxTables:        b.limitMaxTables,
+		limitMaxInstances:     b.limitMaxInstances,
+		limitMaxMemories:      b.limitMaxMemories,
 
 		// runtime config
 		compileStrategy: b.compileStrategy,
 		meterMaxUnits:   b.meterMaxUnits,
+		maxResultSize:   b.maxResultSize,
+		maxParamsSize:   b.maxParamsSize,
+		testingOnlyMode: b.testingOnlyMode,
+		programID:       b.programID,
+		newMeter:        b.newMeter,
+		subsidyPolicy:   b.subsidyPolicy,
+		memoryReset:     b.memoryReset,
+		coverage:        b.coverage,
+		noMetering:      b.noMetering,
+		importAuditSize: b.importAuditSize,
+		engineHash:      engineHash(b.engineSettings),
 	}, nil
 }
 
+// loadCache enables the cache of compiled modules. A custom cache config is
+// loaded from a temporary file if a cache directory or size limit is defined.
+func (b *builder) loadCache() error {
+	if b.cacheDir == "" && b.cacheSizeLimit == 0 {
+		if b.defaultCache {
+			return b.cfg.CacheConfigLoadDefault()
+		}
+		return nil
+	}
+
+	cfg := "[cache]\nenabled = true\n"
+	if b.cacheDir != "" {
+		dir, err := filepath.Abs(b.cacheDir)
+		if err != nil {
+			return err
+		}
+		cfg += fmt.Sprintf("directory = %s\n", tomlString(dir))
+	}
+	if b.cacheSizeLimit > 0 {
+		cfg += fmt.Sprintf("files-total-size-soft-limit = \"%d\"\n", b.cacheSizeLimit)
+	}
+
+	f, err := os.CreateTemp("", "wasmtime-cache-*.toml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(cfg)
+	if err := errors.Join(err, f.Close()); err != nil {
+		return err
+	}
+	// the config is read when loaded, the file can be removed.
+	return b.cfg.CacheConfigLoad(f.Name())
+}
+
+// tomlString returns [s] as a TOML basic string. Unlike Go quoting, TOML
+// only supports \" and \\ and escapes control characters as \uXXXX.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// validate returns every violation of the config, ensuring limits are
+// positive and supported by the enabled features.
+func (b *builder) validate() error {
+	errs := []error{}
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidConfig}, args...)...))
+	}
+
+	if b.maxWasmStack <= 0 {
+		invalid("max wasm stack must be positive: %d", b.maxWasmStack)
+	}
+	if b.limitMaxMemory < 0 {
+		invalid("limit max memory must be positive: %d", b.limitMaxMemory)
+	}
+	if b.limitMaxMemory%MemoryPageSize != 0 {
+		invalid("limit max memory must be a multiple of the page size: %d", b.limitMaxMemory)
+	}
+	if b.limitMaxTableElements < 0 {
+		invalid("limit max table elements must be positive: %d", b.limitMaxTableElements)
+	}
+	if b.limitMaxTables < 0 {
+		invalid("limit max tables must be positive: %d", b.limitMaxTables)
+	}
+	if b.limitMaxInstances < 0 {
+		invalid("limit max instances must be positive: %d", b.limitMaxInstances)
+	}
+	if b.limitMaxMemories < 0 {
+		invalid("limit max memories must be positive: %d", b.limitMaxMemories)
+	}
+	if b.limitMaxMemory > 0 && b.maxResultSize > uint64(b.limitMaxMemory) {
+		invalid("max result size %d exceeds limit max memory %d", b.maxResultSize, b.limitMaxMemory)
+	}
+	if b.limitMaxMemory > 0 && b.maxParamsSize > uint64(b.limitMaxMemory) {
+		invalid("max params size %d exceeds limit max memory %d", b.maxParamsSize, b.limitMaxMemory)
+	}
+	if b.limitMaxTables > 1 && !b.referenceTypes {
+		invalid("limit max tables %d requires reference types", b.limitMaxTables)
+	}
+	if b.limitMaxMemories > 1 && !b.multiMemory {
+		invalid("limit max memories %d requires multi-memory", b.limitMaxMemories)
+	}
+	if b.referenceTypes && !b.bulkMemory {
+		invalid("reference types requires bulk memory")
+	}
+	if b.coverage && !b.testingOnlyMode {
+		invalid("coverage requires testing only mode")
+	}
+	if b.coverage && b.compileStrategy != CompileWasm {
+		invalid("coverage requires the compile wasm strategy")
+	}
+	if b.importAuditSize < 0 {
+		invalid("import audit size must be positive: %d", b.importAuditSize)
+	}
+	if b.noMetering && !b.testingOnlyMode {
+		invalid("no metering requires testing only mode")
+	}
+
+	return errors.Join(errs...)
+}
+
+// engineSetting records the engine setting [name] for the engine hash.
+func (b *builder) engineSetting(name string, value interface{}) {
+	b.engineSettings[name] = fmt.Sprint(value)
+}
+
+// defaultEngineSettings returns the engine settings of defaultWasmtimeConfig.
+func defaultEngineSettings() map[string]string {
+	b := &builder{engineSettings: map[string]string{}}
+	b.engineSetting("cranelift_opt_level", defaultCraneliftOptLevel)
+	b.engineSetting("consume_fuel", defaultFuelMetering)
+	b.engineSetting("threads", defaultWasmThreads)
+	b.engineSetting("strategy", defaultCompilerStrategy)
+	b.engineSetting("epoch_interruption", defaultEpochInterruption)
+	b.engineSetting("nan_canonicalization", defaultNaNCanonicalization)
+	b.engineSetting("cranelift_debug_verifier", defaultEnableCraneliftDebugVerifier)
+	b.engineSetting("debug_info", defaultEnableDebugInfo)
+	b.engineSetting("simd", defaultSIMD)
+	b.engineSetting("multi_memory", defaultWasmMultiMemory)
+	b.engineSetting("memory64", defaultWasmMemory64)
+	b.engineSetting("max_wasm_stack", defaultMaxWasmStack)
+	b.engineSetting("bulk_memory", defaultEnableBulkMemory)
+	b.engineSetting("reference_types", defaultEnableReferenceTypes)
+	b.engineSetting("multi_value", defaultMultiValue)
+	b.engineSetting("profiler", defaultProfiler)
+	return b.engineSettings
+}
+
+// engineHash returns the hash of [settings] and the version of wasmtime.
+func engineHash(settings map[string]string) ids.ID {
+	names := maps.Keys(settings)
+	sort.Strings(names)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "wasmtime=%s\n", WasmtimeVersion())
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s=%s\n", name, settings[name])
+	}
+	return hashing.ComputeHash256Array([]byte(buf.String()))
+}
+
 // non-configurable defaults
 func defaultWasmtimeConfig() *wasmtime.Config {
 	cfg := wasmtime.NewConfig()
@@ -182,18 +691,16 @@ func defaultWasmtimeConfig() *wasmtime.Config {
 	cfg.SetCraneliftOptLevel(defaultCraneliftOptLevel)
 	cfg.SetConsumeFuel(defaultFuelMetering)
 	cfg.SetWasmThreads(defaultWasmThreads)
-	cfg.SetWasmMultiMemory(defaultWasmMultiMemory)
-	cfg.SetWasmMemory64(defaultWasmMemory64)
 	cfg.SetStrategy(defaultCompilerStrategy)
 	cfg.SetEpochInterruption(defaultEpochInterruption)
 	cfg.SetCraneliftFlag("enable_nan_canonicalization", defaultNaNCanonicalization)
 
-	// TODO: expose these knobs for developers
+	// configurable defaults
 	cfg.SetCraneliftDebugVerifier(defaultEnableCraneliftDebugVerifier)
 	cfg.SetDebugInfo(defaultEnableDebugInfo)
-
-	// configurable defaults
 	cfg.SetWasmSIMD(defaultSIMD)
+	cfg.SetWasmMultiMemory(defaultWasmMultiMemory)
+	cfg.SetWasmMemory64(defaultWasmMemory64)
 	cfg.SetMaxWasmStack(defaultMaxWasmStack)
 	cfg.SetWasmBulkMemory(defaultEnableBulkMemory)
 	cfg.SetWasmReferenceTypes(defaultEnableReferenceTypes)
@@ -0,0 +1,288 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder func() *builder
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits)
+			},
+		},
+		{
+			name: "negative table elements",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithLimitMaxTableElements(-1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative instances",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithLimitMaxInstances(-1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple tables without reference types",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithLimitMaxTables(2)
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple tables with reference types",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithBulkMemory(true).
+					WithReferenceTypes(true).
+					WithLimitMaxTables(2)
+			},
+		},
+		{
+			name: "reference types without bulk memory",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithReferenceTypes(true)
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple memories without multi-memory",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithLimitMaxMemories(2)
+			},
+			wantErr: true,
+		},
+		{
+			name: "non positive max wasm stack",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithMaxWasmStack(0)
+			},
+			wantErr: true,
+		},
+		{
+			name: "limit max memory not a multiple of the page size",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).WithLimitMaxMemory(MemoryPageSize + 1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "max result size exceeds limit max memory",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithLimitMaxMemory(MemoryPageSize).
+					WithMaxResultSize(MemoryPageSize + 1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "max params size exceeds limit max memory",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithLimitMaxMemory(MemoryPageSize).
+					WithMaxParamsSize(MemoryPageSize + 1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "coverage without testing only mode",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithCoverage(true)
+			},
+			wantErr: true,
+		},
+		{
+			name: "coverage of precompiled wasm",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithEnableTestingOnlyMode(true).
+					WithCompileStrategy(PrecompiledWasm).
+					WithCoverage(true)
+			},
+			wantErr: true,
+		},
+		{
+			name: "no metering without testing only mode",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithNoMetering(true)
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative import audit size",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithImportAudit(-1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple memories with multi-memory",
+			builder: func() *builder {
+				return NewConfigBuilder(NoUnits).
+					WithMultiMemory(true).
+					WithLimitMaxMemories(2)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder().Build()
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidConfig)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBuildAggregatesErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewConfigBuilder(NoUnits).
+		WithMaxWasmStack(-1).
+		WithLimitMaxInstances(-1).
+		WithLimitMaxTables(2).
+		WithReferenceTypes(true).
+		Build()
+	require.ErrorIs(err, ErrInvalidConfig)
+	require.ErrorContains(err, "max wasm stack must be positive")
+	require.ErrorContains(err, "limit max instances must be positive")
+	require.ErrorContains(err, "reference types requires bulk memory")
+	require.NotContains(err.Error(), "requires reference types")
+}
+
+func TestLimitMaxTables(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (table 1 funcref)
+	  (table 1 funcref)
+	)
+	`)
+	require.NoError(err)
+
+	newConfig := func(tables int64) *Config {
+		cfg, err := NewConfigBuilder(NoUnits).
+			WithBulkMemory(true).
+			WithReferenceTypes(true).
+			WithLimitMaxTables(tables).
+			Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	runtime := New(logging.NoLog{}, newConfig(1), NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.ErrorContains(err, "table")
+	runtime.Stop()
+
+	runtime = New(logging.NoLog{}, newConfig(2), NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.NoError(err)
+	runtime.Stop()
+}
+
+func TestEngineHash(t *testing.T) {
+	require := require.New(t)
+
+	build := func(b *builder) *Config {
+		cfg, err := b.Build()
+		require.NoError(err)
+		return cfg
+	}
+
+	defaultCfg := build(NewConfigBuilder(NoUnits))
+	require.Equal(defaultCfg.EngineHash(), build(NewConfigBuilder(NoUnits)).EngineHash())
+	require.True(strings.HasPrefix(defaultCfg.ArtifactVersion(), WasmtimeVersion()+"+"))
+
+	// settings which do not change compiled code do not change the hash
+	require.Equal(defaultCfg.EngineHash(), build(NewConfigBuilder(1000).WithMemoryReset(true)).EngineHash())
+
+	// engine settings change the hash
+	for _, b := range []*builder{
+		NewConfigBuilder(NoUnits).WithSIMD(true),
+		NewConfigBuilder(NoUnits).WithOptLevel(wasmtime.OptLevelNone),
+		NewConfigBuilder(NoUnits).WithMaxWasmStack(defaultMaxWasmStack / 2),
+		NewConfigBuilder(NoUnits).WithEnableTestingOnlyMode(true).WithNoMetering(true),
+	} {
+		require.NotEqual(defaultCfg.EngineHash(), build(b).EngineHash())
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	// the cache worker of wasmtime writes to the directory in the background,
+	// which can race the cleanup of t.TempDir.
+	dir, err := os.MkdirTemp("", "cache")
+	require.NoError(err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "get_guest") (result i32)
+	    i32.const 1
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithCacheDir(dir).
+		WithCacheSizeLimit(units.MiB).
+		Build()
+	require.NoError(err)
+	rt := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(rt.Initialize(ctx, wasm))
+	defer rt.Stop()
+
+	// compiled modules are stored in the cache dir
+	entries, err := os.ReadDir(dir)
+	require.NoError(err)
+	require.NotEmpty(entries)
+}
+
+func TestTOMLString(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(`"/tmp/cache"`, tomlString("/tmp/cache"))
+	require.Equal(`"a\"b\\c"`, tomlString(`a"b\c`))
+	require.Equal(`"a\u0001b\u007F"`, tomlString("a\x01b\x7f"))
+	require.Equal(`"é"`, tomlString("é"))
+
+	// the escaped dir is loaded by wasmtime
+	dir, err := os.MkdirTemp("", `cache"\`)
+	require.NoError(err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	_, err = NewConfigBuilder(NoUnits).WithCacheDir(dir).Build()
+	require.NoError(err)
+}
@@ -10,6 +10,21 @@ const (
 	DeallocFnName       = "dealloc"
 	MemoryFnName        = "memory"
 	guestSuffix         = "_guest"
+	pureSuffix          = "_pure"
 	wasiPreview1ModName = "wasi_snapshot_preview1"
 	MemoryPageSize      = 64 * units.KiB
+	// fuelConsumedMsg is the message of the trap of a guest out of fuel.
+	fuelConsumedMsg = "all fuel consumed"
+
+	// TinyGo guests export the libc allocator.
+	TinyGoAllocFnName   = "malloc"
+	TinyGoDeallocFnName = "free"
+
+	// AssemblyScript guests allocate managed objects which must be pinned
+	// to prevent them from being collected.
+	AssemblyScriptAllocFnName = "__new"
+	AssemblyScriptPinFnName   = "__pin"
+	AssemblyScriptUnpinFnName = "__unpin"
+	// assemblyScriptArrayBufferID is the class id of an ArrayBuffer.
+	assemblyScriptArrayBufferID = 1
 )
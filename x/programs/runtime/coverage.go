@@ -0,0 +1,446 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+)
+
+const (
+	// coverageExportPrefix prefixes the exported counter of each function of
+	// an instrumented module.
+	coverageExportPrefix = "__coverage_"
+	// coverageUnits is the fuel consumed by the counter increment prepended
+	// to each function: global.get, i64.const, i64.add and global.set.
+	coverageUnits = 4
+)
+
+// wasm binary encoding
+const (
+	wasmHeaderLen = 8
+
+	customSectionID = 0
+	importSectionID = 2
+	globalSectionID = 6
+	exportSectionID = 7
+	codeSectionID   = 10
+
+	externFunc   = 0x00
+	externTable  = 0x01
+	externMemory = 0x02
+	externGlobal = 0x03
+	externTag    = 0x04
+
+	limitsHasMax = 0x01
+
+	valTypeI64   = 0x7e
+	mutable      = 0x01
+	opEnd        = 0x0b
+	opGlobalGet  = 0x23
+	opGlobalSet  = 0x24
+	opI64Const   = 0x42
+	opI64Add     = 0x7c
+	nameFuncsSub = 1
+)
+
+// sectionOrder is the order of the non custom sections of a module.
+var sectionOrder = map[byte]int{
+	1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 13: 6, 6: 7, 7: 8, 8: 9, 9: 10, 12: 11, 10: 12, 11: 13,
+}
+
+var errInvalidModule = errs.New(errs.ErrValidation, "invalid wasm module")
+
+// FunctionCoverage is the number of times a function of the guest was
+// entered.
+type FunctionCoverage struct {
+	// Index is the index of the function in the function index space.
+	Index uint32
+	// Name is the debug name of the function, its export name or its index
+	// if the module has neither.
+	Name  string
+	Calls uint64
+}
+
+// Coverage is the coverage of the functions defined by a guest.
+type Coverage struct {
+	Functions []FunctionCoverage
+}
+
+// Covered returns the number of functions entered at least once.
+func (c *Coverage) Covered() int {
+	covered := 0
+	for _, fn := range c.Functions {
+		if fn.Calls > 0 {
+			covered++
+		}
+	}
+	return covered
+}
+
+// String returns a summary of the coverage listing every function.
+func (c *Coverage) String() string {
+	var b strings.Builder
+	percent := 0.0
+	if len(c.Functions) > 0 {
+		percent = 100 * float64(c.Covered()) / float64(len(c.Functions))
+	}
+	fmt.Fprintf(&b, "%d/%d functions covered (%.1f%%)\n", c.Covered(), len(c.Functions), percent)
+	for _, fn := range c.Functions {
+		fmt.Fprintf(&b, "  %s: %d\n", fn.Name, fn.Calls)
+	}
+	return b.String()
+}
+
+// coverageMap maps the counters of an instrumented module to the functions
+// they count.
+type coverageMap struct {
+	functions []FunctionCoverage
+}
+
+// read returns the coverage of the instance [inst] of the module.
+func (m *coverageMap) read(store wasmtime.Storelike, inst *wasmtime.Instance) (*Coverage, error) {
+	c := &Coverage{Functions: make([]FunctionCoverage, len(m.functions))}
+	for i, fn := range m.functions {
+		name := fmt.Sprintf("%s%d", coverageExportPrefix, fn.Index)
+		ext := inst.GetExport(store, name)
+		if ext == nil || ext.Global() == nil {
+			return nil, fmt.Errorf("%w: %s", ErrMissingExportedFunction, name)
+		}
+		fn.Calls = uint64(ext.Global().Get(store).I64())
+		c.Functions[i] = fn
+	}
+	return c, nil
+}
+
+// total returns the number of function entries counted by [inst].
+func (m *coverageMap) total(store wasmtime.Storelike, inst *wasmtime.Instance) uint64 {
+	c, err := m.read(store, inst)
+	if err != nil {
+		return 0
+	}
+	total := uint64(0)
+	for _, fn := range c.Functions {
+		total += fn.Calls
+	}
+	return total
+}
+
+// instrumentCoverage returns [wasm] with a counter incremented on entry of
+// every defined function. Each counter is an exported mutable global, so the
+// indices of existing functions and globals are unchanged.
+func instrumentCoverage(wasm []byte) ([]byte, *coverageMap, error) {
+	if len(wasm) < wasmHeaderLen {
+		return nil, nil, fmt.Errorf("%w: missing header", errInvalidModule)
+	}
+
+	type section struct {
+		id      byte
+		content []byte
+	}
+	sections := []section{}
+	r := &wasmReader{buf: wasm[wasmHeaderLen:]}
+	for !r.done() {
+		id := r.byte()
+		content := r.bytes()
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+		sections = append(sections, section{id: id, content: content})
+	}
+
+	names := map[uint32]string{}
+	var (
+		importedFuncs   uint32
+		importedGlobals uint32
+		globals         uint32
+		bodies          uint32
+	)
+	for _, s := range sections {
+		var err error
+		switch s.id {
+		case importSectionID:
+			importedFuncs, importedGlobals, err = countImports(s.content)
+		case globalSectionID:
+			globals, err = readCount(s.content)
+		case codeSectionID:
+			bodies, err = readCount(s.content)
+		case exportSectionID:
+			err = readExportNames(s.content, names)
+		case customSectionID:
+			err = readDebugNames(s.content, names)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	cov := &coverageMap{functions: make([]FunctionCoverage, bodies)}
+	globalIndex := func(i uint32) uint32 {
+		return importedGlobals + globals + i
+	}
+	for i := uint32(0); i < bodies; i++ {
+		index := importedFuncs + i
+		name, ok := names[index]
+		if !ok {
+			name = fmt.Sprintf("func[%d]", index)
+		}
+		cov.functions[i] = FunctionCoverage{Index: index, Name: name}
+	}
+	if bodies == 0 {
+		return wasm, cov, nil
+	}
+
+	// the counter of each function is appended to the global section and
+	// exported with the index of the function appended to the prefix.
+	var newGlobals, newExports []byte
+	for i := uint32(0); i < bodies; i++ {
+		newGlobals = append(newGlobals, valTypeI64, mutable, opI64Const, 0, opEnd)
+		name := fmt.Sprintf("%s%d", coverageExportPrefix, importedFuncs+i)
+		newExports = binary.AppendUvarint(newExports, uint64(len(name)))
+		newExports = append(newExports, name...)
+		newExports = append(newExports, externGlobal)
+		newExports = binary.AppendUvarint(newExports, uint64(globalIndex(i)))
+	}
+
+	hasGlobals, hasExports := false, false
+	for i, s := range sections {
+		var err error
+		switch s.id {
+		case globalSectionID:
+			hasGlobals = true
+			sections[i].content, err = appendEntries(s.content, bodies, newGlobals)
+		case exportSectionID:
+			hasExports = true
+			sections[i].content, err = appendEntries(s.content, bodies, newExports)
+		case codeSectionID:
+			sections[i].content, err = instrumentBodies(s.content, globalIndex)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	insert := func(id byte, content []byte) {
+		at := len(sections)
+		for i, s := range sections {
+			if s.id != customSectionID && sectionOrder[s.id] > sectionOrder[id] {
+				at = i
+				break
+			}
+		}
+		sections = append(sections[:at], append([]section{{id: id, content: content}}, sections[at:]...)...)
+	}
+	if !hasGlobals {
+		insert(globalSectionID, append(binary.AppendUvarint(nil, uint64(bodies)), newGlobals...))
+	}
+	if !hasExports {
+		insert(exportSectionID, append(binary.AppendUvarint(nil, uint64(bodies)), newExports...))
+	}
+
+	out := append([]byte{}, wasm[:wasmHeaderLen]...)
+	for _, s := range sections {
+		out = append(out, s.id)
+		out = binary.AppendUvarint(out, uint64(len(s.content)))
+		out = append(out, s.content...)
+	}
+	return out, cov, nil
+}
+
+// appendEntries appends [n] encoded [entries] to the vector [content].
+func appendEntries(content []byte, n uint32, entries []byte) ([]byte, error) {
+	r := &wasmReader{buf: content}
+	count := r.count()
+	if r.err != nil {
+		return nil, r.err
+	}
+	out := binary.AppendUvarint(nil, uint64(count+n))
+	out = append(out, r.buf[r.off:]...)
+	return append(out, entries...), nil
+}
+
+// instrumentBodies prepends the increment of the counter of each function to
+// its body, after its local declarations.
+func instrumentBodies(content []byte, globalIndex func(uint32) uint32) ([]byte, error) {
+	r := &wasmReader{buf: content}
+	count := r.count()
+	out := binary.AppendUvarint(nil, uint64(count))
+	for i := uint32(0); i < count; i++ {
+		body := &wasmReader{buf: r.bytes()}
+		locals := body.count()
+		for j := uint32(0); j < locals; j++ {
+			body.uint()
+			body.byte()
+		}
+		if r.err != nil || body.err != nil {
+			return nil, errors.Join(r.err, body.err)
+		}
+
+		idx := uint64(globalIndex(i))
+		inc := []byte{opGlobalGet}
+		inc = binary.AppendUvarint(inc, idx)
+		inc = append(inc, opI64Const, 1, opI64Add, opGlobalSet)
+		inc = binary.AppendUvarint(inc, idx)
+
+		instrumented := append([]byte{}, body.buf[:body.off]...)
+		instrumented = append(instrumented, inc...)
+		instrumented = append(instrumented, body.buf[body.off:]...)
+		out = binary.AppendUvarint(out, uint64(len(instrumented)))
+		out = append(out, instrumented...)
+	}
+	return out, r.err
+}
+
+// countImports returns the number of imported functions and globals.
+func countImports(content []byte) (uint32, uint32, error) {
+	var funcs, globals uint32
+	r := &wasmReader{buf: content}
+	count := r.count()
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		r.bytes() // module
+		r.bytes() // name
+		switch kind := r.byte(); kind {
+		case externFunc:
+			funcs++
+			r.uint()
+		case externTable:
+			r.byte() // reference type
+			r.limits()
+		case externMemory:
+			r.limits()
+		case externGlobal:
+			globals++
+			r.byte() // value type
+			r.byte() // mutability
+		case externTag:
+			r.byte() // attribute
+			r.uint()
+		default:
+			return 0, 0, fmt.Errorf("%w: unknown import kind %d", errInvalidModule, kind)
+		}
+	}
+	return funcs, globals, r.err
+}
+
+// readExportNames adds the export names of functions to [names].
+func readExportNames(content []byte, names map[uint32]string) error {
+	r := &wasmReader{buf: content}
+	count := r.count()
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		name := string(r.bytes())
+		kind := r.byte()
+		index := r.uint()
+		if _, ok := names[index]; kind == externFunc && !ok {
+			names[index] = name
+		}
+	}
+	return r.err
+}
+
+// readDebugNames adds the function names of the name custom section to
+// [names], replacing export names.
+func readDebugNames(content []byte, names map[uint32]string) error {
+	r := &wasmReader{buf: content}
+	if string(r.bytes()) != "name" || r.err != nil {
+		// other custom sections are ignored
+		return nil
+	}
+	for !r.done() {
+		id := r.byte()
+		sub := &wasmReader{buf: r.bytes()}
+		if r.err != nil {
+			return r.err
+		}
+		if id != nameFuncsSub {
+			continue
+		}
+		count := sub.count()
+		for i := uint32(0); i < count && sub.err == nil; i++ {
+			index := sub.uint()
+			names[index] = string(sub.bytes())
+		}
+		if sub.err != nil {
+			return sub.err
+		}
+	}
+	return nil
+}
+
+// wasmReader decodes the wasm binary format. The first error is kept and
+// subsequent reads return zero values.
+type wasmReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *wasmReader) done() bool {
+	return r.err != nil || r.off >= len(r.buf)
+}
+
+func (r *wasmReader) fail(what string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: failed to read %s at offset %d", errInvalidModule, what, r.off)
+	}
+}
+
+func (r *wasmReader) byte() byte {
+	if r.err != nil || r.off >= len(r.buf) {
+		r.fail("byte")
+		return 0
+	}
+	b := r.buf[r.off]
+	r.off++
+	return b
+}
+
+func (r *wasmReader) uint() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf[r.off:])
+	if n <= 0 || v > uint64(^uint32(0)) {
+		r.fail("integer")
+		return 0
+	}
+	r.off += n
+	return uint32(v)
+}
+
+func (r *wasmReader) count() uint32 {
+	return r.uint()
+}
+
+// readCount returns the number of entries of the vector [content].
+func readCount(content []byte) (uint32, error) {
+	r := &wasmReader{buf: content}
+	count := r.count()
+	return count, r.err
+}
+
+// bytes reads a length prefixed byte vector.
+func (r *wasmReader) bytes() []byte {
+	n := int(r.uint())
+	if r.err != nil || n > len(r.buf)-r.off {
+		r.fail("bytes")
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *wasmReader) limits() {
+	flags := r.byte()
+	r.uint()
+	if flags&limitsHasMax != 0 {
+		r.uint()
+	}
+}
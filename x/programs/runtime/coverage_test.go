@@ -0,0 +1,115 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "debug" "panic" (func $panic (param i32 i32)))
+	  (global $count (mut i32) (i32.const 0))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func $inc (result i32)
+	    (global.set $count (i32.add (global.get $count) (i32.const 1)))
+	    (global.get $count)
+	  )
+	  (func (export "get_guest") (param i32) (result i32)
+	    (if (result i32) (local.get 0)
+	      (then (call $inc))
+	      (else (global.get $count))
+	    )
+	  )
+	  (func $never (local i64 i32)
+	    (call $panic (i32.const 0) (i32.const 0))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	newRuntime := func(coverage bool) *WasmRuntime {
+		cfg, err := NewConfigBuilder(10000).
+			WithEnableTestingOnlyMode(true).
+			WithCoverage(coverage).
+			Build()
+		require.NoError(err)
+		rt := New(logging.NoLog{}, cfg, NoSupportedImports)
+		require.NoError(rt.Initialize(ctx, wasm))
+		return rt.(*WasmRuntime)
+	}
+
+	rt := newRuntime(true)
+	defer rt.Stop()
+	plain := newRuntime(false)
+	defer plain.Stop()
+
+	_, err = plain.Coverage()
+	require.ErrorIs(err, ErrCoverageDisabled)
+
+	for _, param := range []uint64{1, 1, 0} {
+		resp, err := rt.Call(ctx, "get", param)
+		require.NoError(err)
+		want, err := plain.Call(ctx, "get", param)
+		require.NoError(err)
+		require.Equal(want, resp)
+	}
+
+	// the units consumed by the instrumentation are refunded
+	require.Equal(plain.Meter().GetBalance(), rt.Meter().GetBalance())
+
+	coverage, err := rt.Coverage()
+	require.NoError(err)
+	require.Equal([]FunctionCoverage{
+		{Index: 1, Name: "inc", Calls: 2},
+		{Index: 2, Name: "get_guest", Calls: 3},
+		{Index: 3, Name: "never", Calls: 0},
+	}, coverage.Functions)
+	require.Equal(2, coverage.Covered())
+	require.Equal("2/3 functions covered (66.7%)\n  inc: 2\n  get_guest: 3\n  never: 0\n", coverage.String())
+}
+
+func TestInstrumentCoverage(t *testing.T) {
+	require := require.New(t)
+
+	// without global and export sections
+	wasm, err := wasmtime.Wat2Wasm(`(module (func) (func))`)
+	require.NoError(err)
+	instrumented, cov, err := instrumentCoverage(wasm)
+	require.NoError(err)
+	require.Len(cov.functions, 2)
+	require.Equal("func[0]", cov.functions[0].Name)
+
+	engine := wasmtime.NewEngine()
+	mod, err := wasmtime.NewModule(engine, instrumented)
+	require.NoError(err)
+	exports := []string{}
+	for _, exp := range mod.Exports() {
+		exports = append(exports, exp.Name())
+	}
+	require.Equal([]string{"__coverage_0", "__coverage_1"}, exports)
+
+	// without functions
+	wasm, err = wasmtime.Wat2Wasm(`(module)`)
+	require.NoError(err)
+	instrumented, cov, err = instrumentCoverage(wasm)
+	require.NoError(err)
+	require.Equal(wasm, instrumented)
+	require.Empty(cov.functions)
+
+	_, _, err = instrumentCoverage(wasm[:4])
+	require.ErrorIs(err, errInvalidModule)
+	_, _, err = instrumentCoverage(append(wasm, 1, 10))
+	require.ErrorIs(err, errInvalidModule)
+}
@@ -22,6 +22,56 @@ var NoSupportedImports = make(SupportedImports)
 
 type Link struct {
 	*wasmtime.Linker
+
+	// audit records the invocations of metered host functions if import
+	// audit mode is enabled.
+	audit *auditLog
+	// runtime is the runtime the imports are registered for.
+	runtime *WasmRuntime
+}
+
+// Context returns the context of the call in progress of the runtime the
+// imports are registered for, so host functions making calls on its behalf
+// respect its cancellation. Returns context.Background() outside of a call or
+// if the link is not owned by a runtime.
+func (l Link) Context() context.Context {
+	if l.runtime == nil || l.runtime.callCtx == nil {
+		return context.Background()
+	}
+	return l.runtime.callCtx
+}
+
+// MaxResultSize returns the maximum number of bytes host functions read from
+// guest memory or return to the guest as the result of a call, see
+// Config.MaxResultSize. Returns the default if the link is not owned by a
+// runtime.
+func (l Link) MaxResultSize() uint64 {
+	if l.runtime == nil || l.runtime.cfg == nil {
+		return defaultMaxResultSize
+	}
+	return l.runtime.cfg.MaxResultSize()
+}
+
+// ArtifactVersion returns the version of the precompiled modules the engine of
+// the runtime the imports are registered for can load, see
+// Config.ArtifactVersion. Returns an empty string if the link is not owned by
+// an initialized runtime.
+func (l Link) ArtifactVersion() string {
+	if l.runtime == nil || l.runtime.engine == nil {
+		return ""
+	}
+	return l.runtime.engine.ArtifactVersion()
+}
+
+// Runtime returns the runtime the imports are registered for, or nil if the
+// link is not owned by one. Imports making calls on behalf of the runtime,
+// such as program to program calls, use it to create child runtimes, see
+// WasmRuntime.NewChildRuntime.
+func (l Link) Runtime() Runtime {
+	if l.runtime == nil {
+		return nil
+	}
+	return l.runtime
 }
 
 type Runtime interface {
@@ -35,6 +85,9 @@ type Runtime interface {
 	Memory() Memory
 	// Meter returns the runtime meter.
 	Meter() Meter
+	// NewChildRuntime returns a runtime sharing the engine of this runtime
+	// for a call made on its behalf, the child starts without units.
+	NewChildRuntime(*Config, SupportedImports) (Runtime, error)
 	// Stop stops the runtime.
 	Stop()
 }
@@ -67,6 +120,9 @@ type Memory interface {
 	// Alloc allocates a block of memory and returns a pointer
 	// (offset) to its location on the stack.
 	Alloc(uint64) (uint64, error)
+	// Dealloc frees a block of memory of the given length previously
+	// returned by Alloc.
+	Dealloc(uint64, uint64) error
 	// Write writes the given data to the memory at the given offset.
 	Write(uint64, []byte) error
 	// Len returns the length of this memory in bytes.
@@ -75,10 +131,17 @@ type Memory interface {
 	Grow(uint64) (uint64, error)
 }
 
+// NewMeterFn returns the meter used by a runtime given the default meter
+// backed by the fuel of its store. This allows alternative fee models, for
+// example discounting the units charged by imports. Units consumed by guest
+// instructions are always charged to the fuel of the store.
+type NewMeterFn func(Meter) Meter
+
 type Meter interface {
 	// GetBalance returns the balance of the meter's units remaining.
 	GetBalance() uint64
 	// Spend attempts to spend the given amount of units. If the meter has
+	// insufficient units ErrInsufficientUnits is returned.
 	Spend(uint64) (uint64, error)
 	// AddUnits add units back to the meters and returns the new balance.
 	AddUnits(uint64) (uint64, error)
@@ -0,0 +1,46 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+)
+
+// Engine is a wasmtime engine which can be shared by many runtimes, for
+// example one per VM. Runtimes sharing an engine share its compilation
+// settings, caches and epoch while each runtime keeps its own store.
+type Engine struct {
+	engine *wasmtime.Engine
+	hash   ids.ID
+}
+
+// NewEngine returns a new engine using the engine settings of [cfg].
+//
+// Note: the engine settings of [cfg] are consumed and can not be used to
+// create another engine or runtime.
+func NewEngine(cfg *Config) *Engine {
+	return &Engine{
+		engine: wasmtime.NewEngineWithConfig(cfg.engine),
+		hash:   cfg.engineHash,
+	}
+}
+
+// Hash returns the engine hash of the config the engine was created with, see
+// Config.EngineHash.
+func (e *Engine) Hash() ids.ID {
+	return e.hash
+}
+
+// ArtifactVersion returns the version of the precompiled modules the engine
+// can load, see Config.ArtifactVersion.
+func (e *Engine) ArtifactVersion() string {
+	return artifactVersion(e.hash)
+}
+
+// Stop interrupts all in-flight and future calls of every runtime sharing
+// this engine.
+func (e *Engine) Stop() {
+	e.engine.IncrementEpoch()
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedEngine(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "get_guest") (result i32)
+	    i32.const 1
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	engineCfg, err := NewConfigBuilder(0).Build()
+	require.NoError(err)
+	engine := NewEngine(engineCfg)
+
+	newRuntime := func() Runtime {
+		cfg, err := NewConfigBuilder(10000).Build()
+		require.NoError(err)
+		rt := NewWithEngine(logging.NoLog{}, engine, cfg, NoSupportedImports)
+		require.NoError(rt.Initialize(ctx, wasm))
+		return rt
+	}
+
+	rt1 := newRuntime()
+	rt2 := newRuntime()
+	for _, rt := range []Runtime{rt1, rt2} {
+		resp, err := rt.Call(ctx, "get")
+		require.NoError(err)
+		require.Equal(uint64(1), resp[0])
+	}
+
+	// stopping a runtime does not affect other runtimes sharing the engine
+	rt1.Stop()
+	_, err = rt1.Call(ctx, "get")
+	require.ErrorContains(err, "wasm trap: interrupt")
+	_, err = rt2.Call(ctx, "get")
+	require.NoError(err)
+
+	// stopping the engine interrupts all runtimes
+	engine.Stop()
+	_, err = rt2.Call(ctx, "get")
+	require.ErrorContains(err, "wasm trap: interrupt")
+}
+
+func TestPrecompiledEngineHash(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "get_guest") (result i32)
+	    i32.const 1
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	build := func(b *builder) *Config {
+		cfg, err := b.Build()
+		require.NoError(err)
+		return cfg
+	}
+	newConfig := func() *Config {
+		return build(NewConfigBuilder(10000).WithCompileStrategy(PrecompiledWasm))
+	}
+
+	// modules precompiled by an engine with the same hash are loaded
+	artifact, err := PreCompileWasmBytes(wasm, newConfig())
+	require.NoError(err)
+	rt := New(logging.NoLog{}, newConfig(), NoSupportedImports)
+	require.NoError(rt.Initialize(ctx, artifact))
+	resp, err := rt.Call(ctx, "get")
+	require.NoError(err)
+	require.Equal(uint64(1), resp[0])
+	rt.Stop()
+
+	// modules precompiled by an engine with another hash are rejected
+	artifact, err = PreCompileWasmBytes(wasm, build(NewConfigBuilder(10000).WithSIMD(true)))
+	require.NoError(err)
+	rt = New(logging.NoLog{}, newConfig(), NoSupportedImports)
+	require.ErrorIs(rt.Initialize(ctx, artifact), ErrArtifactMismatch)
+
+	// so are modules serialized without the engine hash
+	rt = New(logging.NoLog{}, newConfig(), NoSupportedImports)
+	require.ErrorIs(rt.Initialize(ctx, []byte{1, 2, 3}), ErrArtifactMismatch)
+}
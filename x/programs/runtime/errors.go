@@ -3,15 +3,27 @@
 
 package runtime
 
-import "errors"
+import "github.com/ava-labs/hypersdk/x/programs/errs"
 
 var (
-	ErrMissingExportedFunction      = errors.New("failed to find exported function")
-	ErrMissingImportModule          = errors.New("failed to find import module")
-	ErrMissingInvalidMemoryFunction = errors.New("memory function is invalid")
-	ErrInvalidMemorySize            = errors.New("invalid memory size")
-	ErrInvalidMemoryAddress         = errors.New("invalid memory address: must be positive")
-	ErrInvalidParamCount            = errors.New("invalid parameter count")
-	ErrInvalidParamType             = errors.New("invalid parameter type")
-	ErrInsufficientUnits            = errors.New("insufficient units")
+	ErrMissingExportedFunction      = errs.New(errs.ErrValidation, "failed to find exported function")
+	ErrMissingImportModule          = errs.New(errs.ErrValidation, "failed to find import module")
+	ErrMissingInvalidMemoryFunction = errs.New(errs.ErrMemory, "memory function is invalid")
+	ErrInvalidMemorySize            = errs.New(errs.ErrMemory, "invalid memory size")
+	ErrInvalidMemoryAddress         = errs.New(errs.ErrMemory, "invalid memory address: must be positive")
+	ErrInvalidParamCount            = errs.New(errs.ErrValidation, "invalid parameter count")
+	ErrInvalidParamType             = errs.New(errs.ErrValidation, "invalid parameter type")
+	ErrSignatureMismatch            = errs.New(errs.ErrValidation, "function signature mismatch")
+	ErrInsufficientUnits            = errs.New(errs.ErrOutOfFuel, "insufficient units")
+	ErrInvalidResult                = errs.New(errs.ErrValidation, "invalid result")
+	ErrResultTooLarge               = errs.New(errs.ErrMemory, "result too large")
+	ErrParamsTooLarge               = errs.New(errs.ErrValidation, "params too large")
+	ErrInvalidImportFunction        = errs.New(errs.ErrValidation, "invalid import function")
+	ErrInvalidConfig                = errs.New(errs.ErrValidation, "invalid config")
+	ErrMemoryResetDisabled          = errs.New(errs.ErrValidation, "memory reset disabled")
+	ErrGuestPanic                   = errs.New(errs.ErrTrap, "guest panicked")
+	ErrCoverageDisabled             = errs.New(errs.ErrValidation, "coverage disabled")
+	ErrInvalidChildRuntime          = errs.New(errs.ErrValidation, "invalid child runtime")
+	ErrSubsidyDenied                = errs.New(errs.ErrValidation, "subsidy denied")
+	ErrArtifactMismatch             = errs.New(errs.ErrValidation, "artifact engine hash mismatch")
 )
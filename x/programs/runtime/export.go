@@ -28,11 +28,10 @@ func NewExportClient(mod *wasmtime.Caller) WasmtimeExportClient {
 
 func (c *callerClient) ExportedFunction(name string) (*wasmtime.Func, error) {
 	ext := c.mod.GetExport(name)
-	fn := ext.Func()
-	if ext == nil || fn == nil {
+	if ext == nil || ext.Func() == nil {
 		return nil, fmt.Errorf("%w: %s", ErrMissingExportedFunction, name)
 	}
-	return fn, nil
+	return ext.Func(), nil
 }
 
 func (c *callerClient) GetMemory() (*wasmtime.Memory, error) {
@@ -58,11 +57,10 @@ type exportClient struct {
 
 func (c *exportClient) ExportedFunction(name string) (*wasmtime.Func, error) {
 	ext := c.inst.GetExport(c.store, name)
-	fn := ext.Func()
-	if ext == nil || fn == nil {
+	if ext == nil || ext.Func() == nil {
 		return nil, fmt.Errorf("%w: %s", ErrMissingExportedFunction, name)
 	}
-	return fn, nil
+	return ext.Func(), nil
 }
 
 func (c *exportClient) GetMemory() (*wasmtime.Memory, error) {
@@ -32,6 +32,50 @@ func (s *Supported) Imports() SupportedImports {
 	return s.imports
 }
 
+// Gated returns the supported imports enabled by [rules]. Programs using a
+// disabled import fail to initialize.
+func (s *Supported) Gated(rules ImportRules) SupportedImports {
+	imports := make(SupportedImports, len(s.imports))
+	for name, f := range s.imports {
+		if rules.ImportEnabled(name) {
+			imports[name] = f
+		}
+	}
+	return imports
+}
+
+// ImportRules determines which import modules are enabled. This allows a
+// VM to roll out new imports with its chain rules, for example at an
+// activation height, rather than changing the supported imports.
+type ImportRules interface {
+	// ImportEnabled returns true if the import module [name] is enabled.
+	ImportEnabled(name string) bool
+}
+
+var _ ImportRules = (*activationRules)(nil)
+
+// ActivationHeights maps import module names to the height they are enabled
+// at. Imports without an activation height are always enabled.
+type ActivationHeights map[string]uint64
+
+// At returns the rules of the imports at [height].
+func (a ActivationHeights) At(height uint64) ImportRules {
+	return &activationRules{
+		heights: a,
+		height:  height,
+	}
+}
+
+type activationRules struct {
+	heights ActivationHeights
+	height  uint64
+}
+
+func (r *activationRules) ImportEnabled(name string) bool {
+	activation, ok := r.heights[name]
+	return !ok || r.height >= activation
+}
+
 // Factory is a factory for creating imports.
 type Factory struct {
 	log               logging.Logger
@@ -0,0 +1,79 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatedImports(t *testing.T) {
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "test" "inc" (func $inc (param i32) (result i32)))
+	  (func (export "inc_guest") (param i32) (result i32)
+	    (call $inc (local.get 0))
+	  )
+	)
+	`)
+	require.NoError(t, err)
+
+	supported := NewSupportedImports()
+	supported.Register("test", func() Import {
+		return &testImport{units: 1}
+	})
+	supported.Register("other", func() Import {
+		return &testImport{units: 1}
+	})
+	activations := ActivationHeights{"test": 10}
+
+	tests := []struct {
+		name    string
+		height  uint64
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "before activation",
+			height:  9,
+			want:    []string{"other"},
+			wantErr: ErrMissingImportModule,
+		},
+		{
+			name:   "at activation",
+			height: 10,
+			want:   []string{"other", "test"},
+		},
+		{
+			name:   "after activation",
+			height: 11,
+			want:   []string{"other", "test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			imports := supported.Gated(activations.At(tt.height))
+			names := []string{}
+			for name := range imports {
+				names = append(names, name)
+			}
+			require.ElementsMatch(tt.want, names)
+
+			cfg, err := NewConfigBuilder(10000).Build()
+			require.NoError(err)
+			runtime := New(logging.NoLog{}, cfg, imports)
+			defer runtime.Stop()
+			err = runtime.Initialize(ctx, wasm)
+			require.ErrorIs(err, tt.wantErr)
+		})
+	}
+}
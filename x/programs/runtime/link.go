@@ -0,0 +1,81 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+)
+
+var trapType = reflect.TypeOf((*wasmtime.Trap)(nil))
+
+// MeteredFuncWrap defines the host function [fn] under [module] and [name]
+// which spends [units] from [meter] before every invocation. If the meter has
+// insufficient units [fn] is not invoked and the guest traps.
+//
+// [fn] must be a function accepted by wasmtime.Linker.FuncWrap.
+func (l Link) MeteredFuncWrap(meter Meter, units uint64, module, name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("%w: %s.%s: %s", ErrInvalidImportFunction, module, name, t)
+	}
+
+	// the wrapper always returns a trap as the final result
+	hasTrap := t.NumOut() > 0 && t.Out(t.NumOut()-1) == trapType
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	out := make([]reflect.Type, 0, t.NumOut()+1)
+	for i := 0; i < t.NumOut(); i++ {
+		out = append(out, t.Out(i))
+	}
+	if !hasTrap {
+		out = append(out, trapType)
+	}
+
+	wrapped := reflect.MakeFunc(reflect.FuncOf(in, out, false), func(args []reflect.Value) []reflect.Value {
+		var before uint64
+		if l.audit != nil {
+			before = meter.GetBalance()
+		}
+
+		var results []reflect.Value
+		if _, err := meter.Spend(units); err != nil {
+			results = make([]reflect.Value, len(out))
+			for i, o := range out {
+				results[i] = reflect.Zero(o)
+			}
+			trap := wasmtime.NewTrap(fmt.Sprintf("%s: %s.%s", err, module, name))
+			results[len(out)-1] = reflect.ValueOf(trap)
+		} else {
+			results = v.Call(args)
+			if !hasTrap {
+				results = append(results, reflect.Zero(trapType))
+			}
+		}
+
+		if l.audit != nil {
+			call := ImportCall{
+				Module:   module,
+				Function: name,
+				Args:     summarize(args),
+				Results:  summarize(results),
+			}
+			if after := meter.GetBalance(); after < before {
+				call.Units = before - after
+			}
+			if trap := results[len(results)-1].Interface().(*wasmtime.Trap); trap != nil {
+				call.Trap = trap.Message()
+			}
+			l.audit.add(call)
+		}
+		return results
+	})
+
+	return l.FuncWrap(module, name, wrapped.Interface())
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
+)
+
+type testImport struct {
+	units uint64
+	calls int
+}
+
+func (*testImport) Name() string {
+	return "test"
+}
+
+func (i *testImport) Register(link Link, meter Meter, _ SupportedImports) error {
+	return link.MeteredFuncWrap(meter, i.units, "test", "inc", func(v int32) int32 {
+		i.calls++
+		return v + 1
+	})
+}
+
+func TestMeteredFuncWrap(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "test" "inc" (func $inc (param i32) (result i32)))
+	  (func (export "inc_guest") (param i32) (result i32)
+	    (call $inc (local.get 0))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	imp := &testImport{units: 40}
+	supported := NewSupportedImports()
+	supported.Register("test", func() Import {
+		return imp
+	})
+
+	maxUnits := uint64(100)
+	cfg, err := NewConfigBuilder(maxUnits).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, supported.Imports())
+	err = runtime.Initialize(ctx, wasm)
+	require.NoError(err)
+
+	resp, err := runtime.Call(ctx, "inc", 1)
+	require.NoError(err)
+	require.Equal(uint64(2), resp[0])
+	require.Equal(1, imp.calls)
+	// import units are charged in addition to the guest instructions
+	balance := runtime.Meter().GetBalance()
+	require.Less(balance, maxUnits-imp.units)
+
+	_, err = runtime.Call(ctx, "inc", 1)
+	require.NoError(err)
+
+	// insufficient units for the third call
+	_, err = runtime.Call(ctx, "inc", 1)
+	require.ErrorContains(err, ErrInsufficientUnits.Error())
+	require.ErrorIs(err, errs.ErrOutOfFuel)
+	require.Equal(2, imp.calls)
+}
+
+func TestMeteredFuncWrapInvalid(t *testing.T) {
+	require := require.New(t)
+
+	cfg, err := NewConfigBuilder(1).Build()
+	require.NoError(err)
+	link := Link{Linker: wasmtime.NewLinker(wasmtime.NewEngineWithConfig(cfg.engine))}
+	err = link.MeteredFuncWrap(nil, 1, "test", "invalid", 1)
+	require.ErrorIs(err, ErrInvalidImportFunction)
+}
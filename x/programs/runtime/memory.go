@@ -4,14 +4,42 @@
 package runtime
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"runtime"
+	"unicode/utf16"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+
+	"github.com/ava-labs/hypersdk/x/programs/borsh"
+	"github.com/ava-labs/hypersdk/x/programs/errs"
 )
 
 var _ Memory = (*memory)(nil)
 
 type memory struct {
 	client WasmtimeExportClient
+	// params limits the bytes allocated as the params of a call, nil if the
+	// allocations are not limited.
+	params *paramsBudget
+}
+
+// paramsBudget tracks the bytes allocated as the params of a call.
+type paramsBudget struct {
+	max  uint64
+	used uint64
+}
+
+// reserve adds [length] to the used bytes, returning ErrParamsTooLarge if the
+// budget would be exceeded.
+func (p *paramsBudget) reserve(length uint64) error {
+	if length > p.max-p.used {
+		return fmt.Errorf("%w: %d exceeds max %d", ErrParamsTooLarge, p.used+length, p.max)
+	}
+	p.used += length
+	return nil
 }
 
 func NewMemory(client WasmtimeExportClient) *memory {
@@ -33,8 +61,8 @@ func (m *memory) Range(offset uint64, length uint64) ([]byte, error) {
 		return nil, err
 	}
 
-	// verify available memory is large enough
-	if offset+length > size {
+	// verify available memory is large enough, checked without overflow
+	if offset > size || length > size-offset {
 		return nil, fmt.Errorf("read memory failed: %w", ErrInvalidMemorySize)
 	}
 
@@ -61,9 +89,9 @@ func (m *memory) Write(offset uint64, buf []byte) error {
 		return err
 	}
 
-	lenBuf := len(buf)
+	lenBuf := uint64(len(buf))
 
-	if max < offset+uint64(lenBuf) {
+	if offset > max || lenBuf > max-offset {
 		return fmt.Errorf("write memory failed: %w: max: %d", ErrInvalidMemorySize, max)
 	}
 
@@ -73,17 +101,69 @@ func (m *memory) Write(offset uint64, buf []byte) error {
 	return nil
 }
 
+// Alloc allocates [length] bytes using the allocator exported by the guest.
+// The alloc export of the SDK is preferred, followed by the allocators of
+// TinyGo and AssemblyScript guests. If the guest does not export an allocator
+// the memory is grown and the block is allocated from the new pages, such
+// blocks are never freed.
 func (m *memory) Alloc(length uint64) (uint64, error) {
-	fn, err := m.client.ExportedFunction(AllocFnName)
+	if length > math.MaxInt32 {
+		return 0, fmt.Errorf("alloc memory failed: %w", ErrInvalidMemorySize)
+	}
+	if m.params != nil {
+		if err := m.params.reserve(length); err != nil {
+			return 0, err
+		}
+	}
+	for _, name := range []string{AllocFnName, TinyGoAllocFnName} {
+		fn, err := m.client.ExportedFunction(name)
+		if errors.Is(err, ErrMissingExportedFunction) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return m.callAlloc(name, fn, int32(length))
+	}
+
+	fn, err := m.client.ExportedFunction(AssemblyScriptAllocFnName)
+	if errors.Is(err, ErrMissingExportedFunction) {
+		return m.growAlloc(length)
+	}
+	if err != nil {
+		return 0, err
+	}
+	addr, err := m.callAlloc(AssemblyScriptAllocFnName, fn, int32(length), assemblyScriptArrayBufferID)
+	if err != nil {
+		return 0, err
+	}
+	pin, err := m.client.ExportedFunction(AssemblyScriptPinFnName)
+	if errors.Is(err, ErrMissingExportedFunction) {
+		// the runtime of the guest does not collect garbage
+		return addr, nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	result, err := fn.Call(m.client.Store(), int32(length))
+	_, err = pin.Call(m.client.Store(), int32(addr))
+	if err != nil {
+		return 0, err
+	}
+	return addr, nil
+}
+
+// callAlloc calls the allocator [fn] exported as [name] and validates the
+// returned address.
+func (m *memory) callAlloc(name string, fn *wasmtime.Func, params ...interface{}) (uint64, error) {
+	result, err := fn.Call(m.client.Store(), params...)
 	if err != nil {
 		return 0, err
 	}
 
-	addr := result.(int32)
+	addr, ok := result.(int32)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s must return i32", ErrInvalidResult, name)
+	}
 	if addr < 0 {
 		return 0, ErrInvalidMemoryAddress
 	}
@@ -91,13 +171,74 @@ func (m *memory) Alloc(length uint64) (uint64, error) {
 	return uint64(addr), nil
 }
 
+// growAlloc grows the memory by enough pages to hold [length] bytes and
+// returns the offset of the first new page.
+func (m *memory) growAlloc(length uint64) (uint64, error) {
+	offset, err := m.Len()
+	if err != nil {
+		return 0, err
+	}
+	pages := (length + MemoryPageSize - 1) / MemoryPageSize
+	if pages == 0 {
+		return offset, nil
+	}
+	_, err = m.Grow(pages)
+	if err != nil {
+		return 0, fmt.Errorf("alloc memory failed: %w", err)
+	}
+	return offset, nil
+}
+
+// Dealloc frees [length] bytes at [offset] using the deallocator exported by
+// the guest matching the allocator used by Alloc. If the guest does not
+// export that deallocator, or the block was allocated by growing the memory,
+// this is a no-op.
+func (m *memory) Dealloc(offset uint64, length uint64) error {
+	if offset > math.MaxInt32 || length > math.MaxInt32 {
+		return fmt.Errorf("dealloc memory failed: %w", ErrInvalidMemorySize)
+	}
+	// in the order the allocators are preferred by Alloc
+	calls := []struct {
+		alloc   string
+		dealloc string
+		params  []interface{}
+	}{
+		{alloc: AllocFnName, dealloc: DeallocFnName, params: []interface{}{int32(offset), int32(length)}},
+		{alloc: TinyGoAllocFnName, dealloc: TinyGoDeallocFnName, params: []interface{}{int32(offset)}},
+		{alloc: AssemblyScriptAllocFnName, dealloc: AssemblyScriptUnpinFnName, params: []interface{}{int32(offset)}},
+	}
+	for _, call := range calls {
+		_, err := m.client.ExportedFunction(call.alloc)
+		if errors.Is(err, ErrMissingExportedFunction) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fn, err := m.client.ExportedFunction(call.dealloc)
+		if errors.Is(err, ErrMissingExportedFunction) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fn.Call(m.client.Store(), call.params...)
+		return err
+	}
+	return nil
+}
+
 func (m *memory) Grow(delta uint64) (uint64, error) {
 	mem, err := m.client.GetMemory()
 	if err != nil {
 		return 0, err
 	}
 
-	return mem.Grow(m.client.Store(), delta)
+	pages, err := mem.Grow(m.client.Store(), delta)
+	if err != nil {
+		return 0, errs.Wrap(errs.ErrMemory, err)
+	}
+	return pages, nil
 }
 
 func (m *memory) Len() (uint64, error) {
@@ -123,3 +264,85 @@ func WriteBytes(m Memory, buf []byte) (uint64, error) {
 
 	return offset, nil
 }
+
+// WriteBytesParams is a helper function that writes [buf] to memory and returns
+// the (ptr, len) pair of params expected by a guest function accepting a byte
+// slice.
+func WriteBytesParams(m Memory, buf []byte) ([]uint64, error) {
+	offset, err := WriteBytes(m, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return []uint64{offset, uint64(len(buf))}, nil
+}
+
+// WriteUTF16Params is a helper function that encodes [s] as UTF-16LE, the
+// string encoding of AssemblyScript, and writes it to memory returning the
+// (ptr, len) pair of params. The length is in bytes.
+func WriteUTF16Params(m Memory, s string) ([]uint64, error) {
+	codes := utf16.Encode([]rune(s))
+	buf := make([]byte, 2*len(codes))
+	for i, c := range codes {
+		binary.LittleEndian.PutUint16(buf[2*i:], c)
+	}
+
+	return WriteBytesParams(m, buf)
+}
+
+// WriteBorshParams is a helper function that serializes [v] with borsh and
+// writes it to memory returning the (ptr, len) pair of params. This allows
+// structured arguments to be passed to the guest.
+func WriteBorshParams(m Memory, v interface{}) ([]uint64, error) {
+	buf, err := borsh.Serialize(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return WriteBytesParams(m, buf)
+}
+
+// Scratch tracks blocks written to guest memory so they can be freed once
+// they are no longer needed, for example after a call returns. This prevents
+// long lived instances from growing memory with every call.
+//
+// Note: blocks whose ownership is taken by the guest must not be written
+// with Scratch as they would be freed twice.
+type Scratch struct {
+	mem    Memory
+	blocks [][2]uint64
+}
+
+// NewScratch returns a new scratch for [mem].
+func NewScratch(mem Memory) *Scratch {
+	return &Scratch{mem: mem}
+}
+
+// WriteBytes allocates memory and writes [buf] to it returning the offset.
+// The block is freed by Free, even if the write fails.
+func (s *Scratch) WriteBytes(buf []byte) (uint64, error) {
+	offset, err := s.mem.Alloc(uint64(len(buf)))
+	if err != nil {
+		return 0, err
+	}
+	s.blocks = append(s.blocks, [2]uint64{offset, uint64(len(buf))})
+	if err := s.mem.Write(offset, buf); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// Free frees all blocks written by the scratch. If a block fails to be freed
+// it is forgotten along with the blocks freed before it, so calling Free
+// again never frees a block twice.
+func (s *Scratch) Free() error {
+	for i := len(s.blocks) - 1; i >= 0; i-- {
+		err := s.mem.Dealloc(s.blocks[i][0], s.blocks[i][1])
+		if err != nil {
+			s.blocks = s.blocks[:i]
+			return err
+		}
+	}
+	s.blocks = nil
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	_ "embed"
 	"errors"
+	"math"
 	"os"
 	"testing"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/bytecodealliance/wasmtime-go/v13"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
 )
 
 var log = logging.NewLogger(
@@ -76,6 +79,7 @@ func TestLimitMaxMemoryGrow(t *testing.T) {
 	// attempt to grow memory to 2 pages which exceeds the limit
 	_, err = runtime.Memory().Grow(1)
 	require.ErrorContains(err, "failed to grow memory by `1`")
+	require.ErrorIs(err, errs.ErrMemory)
 }
 
 func TestWriteExceedsLimitMaxMemory(t *testing.T) {
@@ -146,3 +150,609 @@ func TestWithMaxWasmStack(t *testing.T) {
 	code := trap.Code()
 	require.Equal(*code, wasmtime.StackOverflow)
 }
+
+func TestMultiMemory(t *testing.T) {
+	require := require.New(t)
+
+	// second memory is not exported
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (memory $scratch 1)
+	  (export "memory" (memory 0))
+	  (func (export "store_guest") (result i32)
+	    (i32.store $scratch (i32.const 0) (i32.const 42))
+	    (i32.load $scratch (i32.const 0))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	// multi-memory disabled by default
+	cfg, err := NewConfigBuilder(10000).
+		WithLimitMaxMemory(1 * MemoryPageSize). // 1 page
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.ErrorContains(err, "multiple memories")
+
+	cfg, err = NewConfigBuilder(10000).
+		WithLimitMaxMemory(1 * MemoryPageSize). // 1 page
+		WithMultiMemory(true).
+		Build()
+	require.NoError(err)
+	runtime = New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.NoError(err)
+	resp, err := runtime.Call(context.Background(), "store")
+	require.NoError(err)
+	require.Equal(uint64(42), resp[0])
+
+	// exported memory is unaffected by the second memory
+	length, err := runtime.Memory().Len()
+	require.NoError(err)
+	require.Equal(uint64(MemoryPageSize), length)
+}
+
+func TestMultiMemoryLimitMaxMemory(t *testing.T) {
+	require := require.New(t)
+
+	// second memory exceeds the per memory limit
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (memory 2)
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithLimitMaxMemory(1 * MemoryPageSize). // 1 page
+		WithMultiMemory(true).
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.ErrorContains(err, "memory minimum size of 2 pages exceeds memory limits")
+}
+
+func TestMemory64(t *testing.T) {
+	require := require.New(t)
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory i64 1)
+	  (export "memory" (memory 0))
+	  (func (export "load_guest") (param i64) (result i64)
+	    (i64.load (local.get 0))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithLimitMaxMemory(1 * MemoryPageSize). // 1 page
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.ErrorContains(err, "memory64")
+
+	cfg, err = NewConfigBuilder(10000).
+		WithLimitMaxMemory(1 * MemoryPageSize). // 1 page
+		WithMemory64(true).
+		Build()
+	require.NoError(err)
+	runtime = New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.NoError(err)
+
+	err = runtime.Memory().Write(8, []byte{0, 0, 0, 0, 0, 0, 0, 7})
+	require.NoError(err)
+	resp, err := runtime.Call(context.Background(), "load", 8)
+	require.NoError(err)
+	require.Equal(uint64(7<<56), resp[0])
+}
+
+func FuzzMemoryWriteRange(f *testing.F) {
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1) ;; 1 pages
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(f, err)
+
+	cfg, err := NewConfigBuilder(1).
+		WithLimitMaxMemory(1 * MemoryPageSize). // 1 page
+		Build()
+	require.NoError(f, err)
+	runtime := New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.NoError(f, err)
+
+	f.Add(uint64(0), uint64(0), []byte{1})
+	f.Add(uint64(MemoryPageSize-1), uint64(2), []byte{1, 2})
+	f.Add(uint64(1), uint64(math.MaxUint64), []byte{})
+	f.Add(uint64(math.MaxUint64), uint64(1), []byte{1})
+	f.Fuzz(func(t *testing.T, offset uint64, length uint64, data []byte) {
+		mem := runtime.Memory()
+		size, err := mem.Len()
+		require.NoError(t, err)
+
+		err = mem.Write(offset, data)
+		inBounds := offset <= size && uint64(len(data)) <= size-offset
+		if !inBounds {
+			require.ErrorIs(t, err, ErrInvalidMemorySize)
+			return
+		}
+		require.NoError(t, err)
+
+		buf, err := mem.Range(offset, uint64(len(data)))
+		require.NoError(t, err)
+		require.Equal(t, data, append([]byte{}, buf...))
+
+		_, err = mem.Range(offset, length)
+		if length > size-offset {
+			require.ErrorIs(t, err, ErrInvalidMemorySize)
+		}
+	})
+}
+
+func TestWriteBorshParams(t *testing.T) {
+	require := require.New(t)
+
+	// returns the sum of the bytes in the (ptr, len) param pair
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (global $next (mut i32) (i32.const 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    (global.get $next)
+	    (global.set $next (i32.add (global.get $next) (local.get 0)))
+	  )
+	  (func (export "sum_guest") (param $ptr i32) (param $len i32) (result i32)
+	    (local $sum i32)
+	    (block
+	      (loop
+	        (br_if 1 (i32.eqz (local.get $len)))
+	        (local.set $sum (i32.add (local.get $sum) (i32.load8_u (local.get $ptr))))
+	        (local.set $ptr (i32.add (local.get $ptr) (i32.const 1)))
+	        (local.set $len (i32.sub (local.get $len) (i32.const 1)))
+	        (br 0)))
+	    (local.get $sum)
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, nil)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.NoError(err)
+
+	params, err := WriteBytesParams(runtime.Memory(), []byte{1, 2, 3})
+	require.NoError(err)
+	require.Equal([]uint64{0, 3}, params)
+	resp, err := runtime.Call(context.Background(), "sum", params...)
+	require.NoError(err)
+	require.Equal(uint64(6), resp[0])
+
+	type transfer struct {
+		To     [2]byte
+		Amount uint32
+	}
+	params, err = WriteBorshParams(runtime.Memory(), transfer{To: [2]byte{1, 2}, Amount: 4})
+	require.NoError(err)
+	// allocated after the previous params
+	require.Equal([]uint64{3, 6}, params)
+	resp, err = runtime.Call(context.Background(), "sum", params...)
+	require.NoError(err)
+	require.Equal(uint64(7), resp[0])
+}
+
+func TestAllocDealloc(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// bump allocator which counts freed bytes
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (global $next (mut i32) (i32.const 16))
+	  (global $freed (mut i32) (i32.const 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    (global.get $next)
+	    (global.set $next (i32.add (global.get $next) (local.get 0)))
+	  )
+	  (func (export "dealloc") (param i32 i32)
+	    (global.set $freed (i32.add (global.get $freed) (local.get 1)))
+	  )
+	  (func (export "freed_guest") (result i32)
+	    (global.get $freed)
+	  )
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	scratch := NewScratch(runtime.Memory())
+	ptr, err := scratch.WriteBytes([]byte("hello"))
+	require.NoError(err)
+	require.Equal(uint64(16), ptr)
+	ptr, err = scratch.WriteBytes([]byte("world!"))
+	require.NoError(err)
+	require.Equal(uint64(21), ptr)
+
+	require.NoError(scratch.Free())
+	resp, err := runtime.Call(ctx, "freed")
+	require.NoError(err)
+	require.Equal(uint64(11), resp[0])
+
+	// blocks are only freed once
+	require.NoError(scratch.Free())
+	resp, err = runtime.Call(ctx, "freed")
+	require.NoError(err)
+	require.Equal(uint64(11), resp[0])
+}
+
+func TestScratchFreeError(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// bump allocator whose dealloc traps for the block at 21
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (global $next (mut i32) (i32.const 16))
+	  (global $freed (mut i32) (i32.const 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    (global.get $next)
+	    (global.set $next (i32.add (global.get $next) (local.get 0)))
+	  )
+	  (func (export "dealloc") (param i32 i32)
+	    (if (i32.eq (local.get 0) (i32.const 21)) (then unreachable))
+	    (global.set $freed (i32.add (global.get $freed) (local.get 1)))
+	  )
+	  (func (export "freed_guest") (result i32)
+	    (global.get $freed)
+	  )
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	scratch := NewScratch(runtime.Memory())
+	for _, b := range []string{"hello", "world!", "again"} {
+		_, err := scratch.WriteBytes([]byte(b))
+		require.NoError(err)
+	}
+
+	// the last block is freed before the failing one
+	require.Error(scratch.Free())
+	resp, err := runtime.Call(ctx, "freed")
+	require.NoError(err)
+	require.Equal(uint64(5), resp[0])
+
+	// a retry frees the first block but not the last block again
+	require.NoError(scratch.Free())
+	resp, err = runtime.Call(ctx, "freed")
+	require.NoError(err)
+	require.Equal(uint64(10), resp[0])
+}
+
+func TestAllocFallback(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// no alloc or dealloc exports
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithLimitMaxMemory(2 * MemoryPageSize). // 2 pages
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	mem := runtime.Memory()
+	ptr, err := WriteBytes(mem, []byte("hello"))
+	require.NoError(err)
+	require.Equal(uint64(MemoryPageSize), ptr)
+	length, err := mem.Len()
+	require.NoError(err)
+	require.Equal(uint64(2*MemoryPageSize), length)
+	data, err := mem.Range(ptr, 5)
+	require.NoError(err)
+	require.Equal([]byte("hello"), data)
+
+	// dealloc is a no-op
+	require.NoError(mem.Dealloc(ptr, 5))
+
+	// grow is subject to the memory limit
+	_, err = mem.Alloc(1)
+	require.Error(err)
+}
+
+func TestMaxParamsSize(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (func (export "get_guest") (param i64) (result i64)
+	    (local.get 0)
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithLimitMaxMemory(3 * MemoryPageSize). // 3 pages
+		WithMaxParamsSize(8).
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	ptr, err := WriteBytes(runtime.Memory(), []byte("hello"))
+	require.NoError(err)
+	length, err := runtime.Memory().Len()
+	require.NoError(err)
+
+	// the params of a call are limited before the guest allocates
+	_, err = WriteBytes(runtime.Memory(), []byte("world"))
+	require.ErrorIs(err, ErrParamsTooLarge)
+	require.ErrorIs(err, errs.ErrValidation)
+	newLength, err := runtime.Memory().Len()
+	require.NoError(err)
+	require.Equal(length, newLength)
+
+	resp, err := runtime.Call(ctx, "get", ptr)
+	require.NoError(err)
+	require.Equal(ptr, resp[0])
+
+	// the budget is reset after each call
+	_, err = WriteBytes(runtime.Memory(), []byte("world"))
+	require.NoError(err)
+}
+
+func TestAllocConventions(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		wat  string
+	}{
+		{
+			name: "tinygo",
+			wat: `
+			(module
+			  (memory 1)
+			  (global $next (mut i32) (i32.const 16))
+			  (global $freed (mut i32) (i32.const 0))
+			  (func (export "malloc") (param i32) (result i32)
+			    (global.get $next)
+			    (global.set $next (i32.add (global.get $next) (local.get 0)))
+			  )
+			  (func (export "free") (param i32)
+			    (global.set $freed (i32.add (global.get $freed) (i32.const 1)))
+			  )
+			  (func (export "freed") (result i32)
+			    (global.get $freed)
+			  )
+			  (export "memory" (memory 0))
+			)
+			`,
+		},
+		{
+			// the dealloc export does not belong to the allocator in use
+			name: "tinygo with dealloc",
+			wat: `
+			(module
+			  (memory 1)
+			  (global $next (mut i32) (i32.const 16))
+			  (global $freed (mut i32) (i32.const 0))
+			  (func (export "malloc") (param i32) (result i32)
+			    (global.get $next)
+			    (global.set $next (i32.add (global.get $next) (local.get 0)))
+			  )
+			  (func (export "free") (param i32)
+			    (global.set $freed (i32.add (global.get $freed) (i32.const 1)))
+			  )
+			  (func (export "dealloc") (param i32 i32)
+			    unreachable
+			  )
+			  (func (export "freed") (result i32)
+			    (global.get $freed)
+			  )
+			  (export "memory" (memory 0))
+			)
+			`,
+		},
+		{
+			name: "assemblyscript",
+			wat: `
+			(module
+			  (memory 1)
+			  (global $next (mut i32) (i32.const 16))
+			  (global $pinned (mut i32) (i32.const 0))
+			  (global $freed (mut i32) (i32.const 0))
+			  (func (export "__new") (param i32 i32) (result i32)
+			    ;; only array buffers are allocated by the host
+			    (if (i32.ne (local.get 1) (i32.const 1)) (then unreachable))
+			    (global.get $next)
+			    (global.set $next (i32.add (global.get $next) (local.get 0)))
+			  )
+			  (func (export "__pin") (param i32) (result i32)
+			    (global.set $pinned (i32.add (global.get $pinned) (i32.const 1)))
+			    (local.get 0)
+			  )
+			  (func (export "__unpin") (param i32)
+			    (global.set $freed (i32.add (global.get $freed) (i32.const 1)))
+			  )
+			  (func (export "pinned") (result i32)
+			    (global.get $pinned)
+			  )
+			  (func (export "freed") (result i32)
+			    (global.get $freed)
+			  )
+			  (export "memory" (memory 0))
+			)
+			`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			wasm, err := wasmtime.Wat2Wasm(tt.wat)
+			require.NoError(err)
+			cfg, err := NewConfigBuilder(10000).Build()
+			require.NoError(err)
+			runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+			require.NoError(runtime.Initialize(ctx, wasm))
+			defer runtime.Stop()
+
+			scratch := NewScratch(runtime.Memory())
+			ptr, err := scratch.WriteBytes([]byte("hello"))
+			require.NoError(err)
+			require.Equal(uint64(16), ptr)
+			ptr, err = scratch.WriteBytes([]byte("world!"))
+			require.NoError(err)
+			require.Equal(uint64(21), ptr)
+
+			if tt.name == "assemblyscript" {
+				resp, err := runtime.Call(ctx, "pinned")
+				require.NoError(err)
+				require.Equal(uint64(2), resp[0])
+			}
+
+			require.NoError(scratch.Free())
+			// exports without the guest suffix are called
+			resp, err := runtime.Call(ctx, "freed")
+			require.NoError(err)
+			require.Equal(uint64(2), resp[0])
+		})
+	}
+}
+
+func TestWriteUTF16Params(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(err)
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	mem := runtime.Memory()
+	params, err := WriteUTF16Params(mem, "hi😀")
+	require.NoError(err)
+	require.Equal(uint64(8), params[1])
+	data, err := mem.Range(params[0], params[1])
+	require.NoError(err)
+	require.Equal([]byte{'h', 0, 'i', 0, 0x3d, 0xd8, 0x00, 0xde}, data)
+}
+
+func TestResetMemory(t *testing.T) {
+	ctx := context.Background()
+
+	// write stores a value at 16 and in the first byte of a grown page,
+	// read returns the sum of the bytes at 0, 16 and the grown page.
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (data (i32.const 0) "\01")
+	  (func (export "write_guest") (param i32) (result i32)
+	    (i32.store8 (i32.const 0) (local.get 0))
+	    (i32.store8 (i32.const 16) (local.get 0))
+	    (drop (memory.grow (i32.const 1)))
+	    (i32.store8 (i32.const 65536) (local.get 0))
+	    i32.const 0
+	  )
+	  (func (export "read_guest") (result i32)
+	    (i32.add
+	      (i32.add (i32.load8_u (i32.const 0)) (i32.load8_u (i32.const 16)))
+	      (i32.load8_u (i32.const 65536)))
+	  )
+	  (export "memory" (memory 0))
+	)
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		reset    bool
+		wantRead uint64
+		wantErr  error
+	}{
+		{
+			name:     "reset",
+			reset:    true,
+			wantRead: 1,
+		},
+		{
+			name:     "disabled",
+			wantRead: 3 * 7,
+			wantErr:  ErrMemoryResetDisabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			cfg, err := NewConfigBuilder(10000).
+				WithMemoryReset(tt.reset).
+				Build()
+			require.NoError(err)
+			runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+			require.NoError(runtime.Initialize(ctx, wasm))
+			defer runtime.Stop()
+
+			_, err = runtime.Call(ctx, "write", 7)
+			require.NoError(err)
+			err = runtime.(*WasmRuntime).ResetMemory()
+			require.ErrorIs(err, tt.wantErr)
+
+			// data of the previous call is not readable after a reset
+			resp, err := runtime.Call(ctx, "read")
+			require.NoError(err)
+			require.Equal(tt.wantRead, resp[0])
+		})
+	}
+}
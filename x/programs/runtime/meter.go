@@ -4,12 +4,19 @@
 package runtime
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/bytecodealliance/wasmtime-go/v13"
 )
 
 const NoUnits = 0
 
-var _ Meter = (*meter)(nil)
+var (
+	_ Meter = (*meter)(nil)
+	_ Meter = (*unmeteredMeter)(nil)
+	_ Meter = (*importMeter)(nil)
+)
 
 // NewMeter returns a new meter.
 func NewMeter(store *wasmtime.Store) Meter {
@@ -63,3 +70,75 @@ func (m *meter) TransferUnits(to Meter, units uint64) (uint64, error) {
 	// add units to the other meter
 	return to.AddUnits(units)
 }
+
+// unmeteredMeter is the meter of a runtime without fuel metering. It never
+// runs out of units.
+type unmeteredMeter struct{}
+
+func (unmeteredMeter) GetBalance() uint64 {
+	return math.MaxUint64
+}
+
+func (unmeteredMeter) Spend(uint64) (uint64, error) {
+	return math.MaxUint64, nil
+}
+
+func (unmeteredMeter) AddUnits(uint64) (uint64, error) {
+	return math.MaxUint64, nil
+}
+
+func (unmeteredMeter) TransferUnits(to Meter, units uint64) (uint64, error) {
+	return to.AddUnits(units)
+}
+
+// SubsidyPolicy returns whether the import [module] may add [units] to the
+// meter of a call in excess of the units it spent, subsidizing the call.
+type SubsidyPolicy func(module string, units uint64) bool
+
+// importMeter is the meter given to an import. The units an import adds are
+// limited to the units it spent, such as the leftover of a program to program
+// call, any excess must be allowed by the subsidy policy. This prevents a
+// program from minting units for itself through an import.
+type importMeter struct {
+	Meter
+	module string
+	policy SubsidyPolicy
+	// refundable is the number of units spent by the import which have not
+	// been added back.
+	refundable uint64
+}
+
+func (m *importMeter) Spend(units uint64) (uint64, error) {
+	balance, err := m.Meter.Spend(units)
+	if err != nil {
+		return 0, err
+	}
+	m.refundable += units
+	return balance, nil
+}
+
+func (m *importMeter) AddUnits(units uint64) (uint64, error) {
+	refund := units
+	if refund > m.refundable {
+		refund = m.refundable
+	}
+	excess := units - refund
+	if excess > 0 && (m.policy == nil || !m.policy(m.module, excess)) {
+		return 0, fmt.Errorf("%w: %s: %d units exceed the %d units spent", ErrSubsidyDenied, m.module, units, m.refundable)
+	}
+
+	balance, err := m.Meter.AddUnits(units)
+	if err != nil {
+		return 0, err
+	}
+	m.refundable -= refund
+	return balance, nil
+}
+
+func (m *importMeter) TransferUnits(to Meter, units uint64) (uint64, error) {
+	_, err := m.Spend(units)
+	if err != nil {
+		return 0, err
+	}
+	return to.AddUnits(units)
+}
@@ -5,6 +5,7 @@ package runtime
 
 import (
 	"context"
+	"math"
 	"testing"
 
 	"github.com/bytecodealliance/wasmtime-go/v13"
@@ -12,6 +13,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
 )
 
 func TestInfiniteLoop(t *testing.T) {
@@ -42,6 +45,47 @@ func TestInfiniteLoop(t *testing.T) {
 	var trap *wasmtime.Trap
 	require.ErrorAs(err, &trap)
 	require.ErrorContains(trap, "wasm trap: all fuel consumed")
+	require.ErrorIs(err, errs.ErrOutOfFuel)
+}
+
+func TestNoMetering(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// loops for [n] iterations
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "loop_guest") (param $n i32) (result i32)
+	    (loop $l
+	      (local.set $n (i32.sub (local.get $n) (i32.const 1)))
+	      (br_if $l (i32.gt_s (local.get $n) (i32.const 0)))
+	    )
+	    (local.get $n)
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	maxUnits := uint64(100)
+	cfg, err := NewConfigBuilder(maxUnits).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	_, err = runtime.Call(ctx, "loop", 1000)
+	require.ErrorIs(err, errs.ErrOutOfFuel)
+
+	cfg, err = NewConfigBuilder(maxUnits).
+		WithEnableTestingOnlyMode(true).
+		WithNoMetering(true).
+		Build()
+	require.NoError(err)
+	runtime = New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+	resp, err := runtime.Call(ctx, "loop", 1000)
+	require.NoError(err)
+	require.Equal([]uint64{0}, resp)
+	require.Equal(uint64(math.MaxUint64), runtime.Meter().GetBalance())
 }
 
 func TestMetering(t *testing.T) {
@@ -118,3 +162,183 @@ func TestMeterAfterStop(t *testing.T) {
 	require.NoError(err)
 	require.Equal(runtime.Meter().GetBalance(), maxUnits)
 }
+
+// freeMeter does not charge units spent by imports.
+type freeMeter struct {
+	Meter
+	waived uint64
+}
+
+func (m *freeMeter) Spend(units uint64) (uint64, error) {
+	m.waived += units
+	return m.GetBalance(), nil
+}
+
+func TestWithMeter(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "test" "inc" (func $inc (param i32) (result i32)))
+	  (func (export "inc_guest") (param i32) (result i32)
+	    (call $inc (local.get 0))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	imp := &testImport{units: 40}
+	supported := NewSupportedImports()
+	supported.Register("test", func() Import {
+		return imp
+	})
+
+	var meter *freeMeter
+	maxUnits := uint64(100)
+	cfg, err := NewConfigBuilder(maxUnits).
+		WithMeter(func(m Meter) Meter {
+			meter = &freeMeter{Meter: m}
+			return meter
+		}).
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, supported.Imports())
+	err = runtime.Initialize(ctx, wasm)
+	require.NoError(err)
+	require.Equal(meter, runtime.Meter())
+
+	// the import would exhaust the default meter after 2 calls
+	for i := 0; i < 5; i++ {
+		_, err := runtime.Call(ctx, "inc", 1)
+		require.NoError(err)
+	}
+	require.Equal(5*imp.units, meter.waived)
+	// guest instructions are still charged
+	balance := runtime.Meter().GetBalance()
+	require.Less(balance, maxUnits)
+	require.Greater(balance, maxUnits-imp.units)
+}
+
+// subsidyImport spends and adds units to its meter on behalf of the guest.
+type subsidyImport struct {
+	meter Meter
+}
+
+func (*subsidyImport) Name() string {
+	return "subsidy"
+}
+
+func (i *subsidyImport) Register(link Link, meter Meter, _ SupportedImports) error {
+	i.meter = meter
+	if err := link.FuncWrap("subsidy", "spend", func(units int64) int32 {
+		if _, err := i.meter.Spend(uint64(units)); err != nil {
+			return -1
+		}
+		return 0
+	}); err != nil {
+		return err
+	}
+	return link.FuncWrap("subsidy", "add", func(units int64) int32 {
+		if _, err := i.meter.AddUnits(uint64(units)); err != nil {
+			return -1
+		}
+		return 0
+	})
+}
+
+func TestSubsidyPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "subsidy" "spend" (func $spend (param i64) (result i32)))
+	  (import "subsidy" "add" (func $add (param i64) (result i32)))
+	  (func (export "spend_guest") (param i64) (result i32)
+	    (call $spend (local.get 0))
+	  )
+	  (func (export "add_guest") (param i64) (result i32)
+	    (call $add (local.get 0))
+	  )
+	)
+	`)
+	require.NoError(t, err)
+
+	type step struct {
+		fn    string
+		units uint64
+		ok    bool
+	}
+	tests := []struct {
+		name   string
+		policy SubsidyPolicy
+		steps  []step
+	}{
+		{
+			name: "refund of spent units",
+			steps: []step{
+				{"spend", 300, true},
+				{"add", 200, true},
+				{"add", 100, true},
+				// the units spent were refunded
+				{"add", 1, false},
+			},
+		},
+		{
+			name: "no policy denies minting",
+			steps: []step{
+				{"add", 1, false},
+				{"spend", 100, true},
+				{"add", 101, false},
+			},
+		},
+		{
+			name: "policy allows subsidy",
+			policy: func(module string, units uint64) bool {
+				return module == "subsidy" && units <= 500
+			},
+			steps: []step{
+				{"add", 500, true},
+				{"add", 501, false},
+				// refunds are applied before the policy
+				{"spend", 100, true},
+				{"add", 600, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			supported := NewSupportedImports()
+			supported.Register("subsidy", func() Import {
+				return &subsidyImport{}
+			})
+			cfg, err := NewConfigBuilder(10000).
+				WithSubsidyPolicy(tt.policy).
+				Build()
+			require.NoError(err)
+			runtime := New(logging.NoLog{}, cfg, supported.Imports())
+			require.NoError(runtime.Initialize(ctx, wasm))
+			defer runtime.Stop()
+
+			for _, s := range tt.steps {
+				before := runtime.Meter().GetBalance()
+				resp, err := runtime.Call(ctx, s.fn, s.units)
+				require.NoError(err)
+				after := runtime.Meter().GetBalance()
+				if !s.ok {
+					require.Equal(int32(-1), int32(resp[0]), "%s %d", s.fn, s.units)
+					require.LessOrEqual(after, before)
+					continue
+				}
+				require.Zero(resp[0], "%s %d", s.fn, s.units)
+				if s.fn == "add" {
+					require.Greater(after, before)
+				} else {
+					require.LessOrEqual(after, before-s.units)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"go.uber.org/zap"
+)
+
+const (
+	// debugModName is the import module defined by the runtime in testing
+	// only mode.
+	debugModName = "debug"
+	panicFnName  = "panic"
+
+	// maxPanicMessageLen is the maximum length of a panic message, longer
+	// messages are truncated.
+	maxPanicMessageLen = 4 * units.KiB
+)
+
+// definePanic defines the host function a guest panic hook calls with the
+// panic message before the guest traps. The message is attached to the error
+// returned by Call.
+func (r *WasmRuntime) definePanic(link Link) error {
+	return link.FuncWrap(debugModName, panicFnName, r.panicFn)
+}
+
+func (r *WasmRuntime) panicFn(caller *wasmtime.Caller, ptr int32, length int32) {
+	if length < 0 {
+		return
+	}
+	n := uint64(length)
+	if n > maxPanicMessageLen {
+		n = maxPanicMessageLen
+	}
+	memory := NewMemory(NewExportClient(caller))
+	msg, err := memory.Range(uint64(ptr), n)
+	if err != nil {
+		r.log.Error("failed to read panic message from memory",
+			zap.Error(err),
+		)
+		return
+	}
+	r.panicMsg = string(msg)
+}
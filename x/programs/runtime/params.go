@@ -0,0 +1,113 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ava-labs/hypersdk/x/programs/borsh"
+)
+
+const (
+	paramTag = "program"
+
+	// ParamU64 passes an integer or bool field by value.
+	ParamU64 = "u64"
+	// ParamPtr writes a byte slice or array field to memory and passes its
+	// offset.
+	ParamPtr = "ptr"
+	// ParamBytes writes a byte slice or array field to memory and passes its
+	// (offset, length).
+	ParamBytes = "bytes"
+	// ParamBorsh serializes a field with borsh, writes it to memory and
+	// passes its (offset, length).
+	ParamBorsh = "borsh"
+)
+
+// WriteParams returns the params of a guest function call described by the
+// struct [v]. Each exported field tagged with `program:"<kind>"` is converted
+// to params in field order, fields without a tag are ignored. Supported kinds
+// are "u64", "ptr", "bytes" and "borsh".
+func WriteParams(m Memory, v interface{}) ([]uint64, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: expected struct: %s", ErrInvalidParamType, rv.Kind())
+	}
+
+	params := []uint64{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		kind, ok := field.Tag.Lookup(paramTag)
+		if !ok || kind == "-" || !field.IsExported() {
+			continue
+		}
+
+		fieldParams, err := writeParam(m, kind, rv.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		params = append(params, fieldParams...)
+	}
+
+	return params, nil
+}
+
+func writeParam(m Memory, kind string, v reflect.Value) ([]uint64, error) {
+	switch kind {
+	case ParamU64:
+		switch v.Kind() {
+		case reflect.Bool:
+			if v.Bool() {
+				return []uint64{1}, nil
+			}
+			return []uint64{0}, nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return []uint64{uint64(v.Int())}, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return []uint64{v.Uint()}, nil
+		}
+	case ParamPtr, ParamBytes:
+		buf, ok := byteValue(v)
+		if !ok {
+			break
+		}
+		params, err := WriteBytesParams(m, buf)
+		if err != nil {
+			return nil, err
+		}
+		if kind == ParamPtr {
+			return params[:1], nil
+		}
+		return params, nil
+	case ParamBorsh:
+		buf, err := borsh.Serialize(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return WriteBytesParams(m, buf)
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidParamType, kind)
+	}
+
+	return nil, fmt.Errorf("%w: %s can not be passed as %q", ErrInvalidParamType, v.Type(), kind)
+}
+
+// byteValue returns the bytes of a byte slice or array value.
+func byteValue(v reflect.Value) ([]byte, bool) {
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), true
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		buf := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(buf), v)
+		return buf, true
+	default:
+		return nil, false
+	}
+}
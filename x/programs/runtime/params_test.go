@@ -0,0 +1,80 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteParams(t *testing.T) {
+	require := require.New(t)
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (global $next (mut i32) (i32.const 0))
+	  (func (export "alloc") (param i32) (result i32)
+	    (global.get $next)
+	    (global.set $next (i32.add (global.get $next) (local.get 0)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(context.Background(), wasm))
+	defer runtime.Stop()
+	mem := runtime.Memory()
+
+	type amount struct {
+		Value uint32
+	}
+	type transfer struct {
+		ID       [4]byte `program:"ptr"`
+		Memo     []byte  `program:"bytes"`
+		Amount   amount  `program:"borsh"`
+		Units    int64   `program:"u64"`
+		Enabled  bool    `program:"u64"`
+		Ignored  uint64
+		Skipped  uint64 `program:"-"`
+		internal uint64 `program:"u64"`
+	}
+	params, err := WriteParams(mem, &transfer{
+		ID:      [4]byte{1, 2, 3, 4},
+		Memo:    []byte("hi"),
+		Amount:  amount{Value: 7},
+		Units:   -1,
+		Enabled: true,
+		Ignored: 5,
+		Skipped: 6,
+		// unexported fields are ignored
+		internal: 8,
+	})
+	require.NoError(err)
+	require.Equal([]uint64{0, 4, 2, 6, 4, 0xffffffffffffffff, 1}, params)
+
+	data, err := mem.Range(0, 10)
+	require.NoError(err)
+	require.Equal([]byte{1, 2, 3, 4, 'h', 'i', 7, 0, 0, 0}, data)
+
+	// invalid kinds
+	_, err = WriteParams(mem, struct {
+		Value string `program:"u64"`
+	}{})
+	require.ErrorIs(err, ErrInvalidParamType)
+	_, err = WriteParams(mem, struct {
+		Value uint64 `program:"unknown"`
+	}{})
+	require.ErrorIs(err, ErrInvalidParamType)
+	_, err = WriteParams(mem, 1)
+	require.ErrorIs(err, ErrInvalidParamType)
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// StatusOK is the status code returned by a guest function which completed
+// successfully.
+const StatusOK = 0
+
+// statusResultLen is the number of values returned by a guest function
+// following the status code calling convention.
+const statusResultLen = 2
+
+// ProgramError is returned by a guest function which completed with a non
+// zero status code.
+type ProgramError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *ProgramError) Error() string {
+	return fmt.Sprintf("program error: code %d: %s", e.Code, e.Message)
+}
+
+// ReadResultBytes returns [length] bytes of a call result at [ptr] in guest
+// memory. If [length] exceeds [maxSize] ErrResultTooLarge is returned before
+// any memory is allocated.
+func ReadResultBytes(mem Memory, ptr uint64, length uint64, maxSize uint64) ([]byte, error) {
+	if length > maxSize {
+		return nil, fmt.Errorf("%w: %d exceeds max %d", ErrResultTooLarge, length, maxSize)
+	}
+
+	return mem.Range(ptr, length)
+}
+
+// DecodeStatusResult decodes the result of a guest function returning a
+// (status_code, result_ptr) pair. If the status code is StatusOK result_ptr is
+// returned. Otherwise result_ptr points to the error message in guest memory,
+// encoded as a big endian uint32 length followed by the message bytes, and a
+// *ProgramError is returned. Messages longer than [maxSize] and status codes
+// which do not fit a uint32 are rejected.
+//
+// Note: requires multi-value to be enabled.
+func DecodeStatusResult(mem Memory, result []uint64, maxSize uint64) (uint64, error) {
+	if len(result) != statusResultLen {
+		return 0, fmt.Errorf("%w: expected %d values: got %d", ErrInvalidResult, statusResultLen, len(result))
+	}
+
+	code, ptr := result[0], result[1]
+	if code == StatusOK {
+		return ptr, nil
+	}
+	if code > math.MaxUint32 {
+		return 0, fmt.Errorf("%w: status code %d exceeds max %d", ErrInvalidResult, code, uint32(math.MaxUint32))
+	}
+
+	lenBytes, err := mem.Range(ptr, 4)
+	if err != nil {
+		return 0, err
+	}
+	msg, err := ReadResultBytes(mem, ptr+4, uint64(binary.BigEndian.Uint32(lenBytes)), maxSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, &ProgramError{
+		Code:    uint32(code),
+		Message: string(msg),
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeStatusResult(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// returns (0, 42) if param is 0 otherwise (7, ptr) to an error message
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "\00\00\00\0dinvalid owner")
+	  (func (export "run_guest") (param i64) (result i64 i64)
+	    (if (result i64 i64) (i64.eqz (local.get 0))
+	      (then (i64.const 0) (i64.const 42))
+	      (else (i64.const 7) (i64.const 0)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithMultiValue(true).
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.NoError(err)
+
+	resp, err := runtime.Call(ctx, "run", 0)
+	require.NoError(err)
+	require.Len(resp, 2)
+	result, err := DecodeStatusResult(runtime.Memory(), resp, cfg.MaxResultSize())
+	require.NoError(err)
+	require.Equal(uint64(42), result)
+
+	resp, err = runtime.Call(ctx, "run", 1)
+	require.NoError(err)
+	_, err = DecodeStatusResult(runtime.Memory(), resp, cfg.MaxResultSize())
+	var programErr *ProgramError
+	require.ErrorAs(err, &programErr)
+	require.Equal(uint32(7), programErr.Code)
+	require.Equal("invalid owner", programErr.Message)
+
+	// message exceeds the max result size
+	_, err = DecodeStatusResult(runtime.Memory(), resp, 12)
+	require.ErrorIs(err, ErrResultTooLarge)
+
+	// status codes are uint32, a negative i64 code is not truncated
+	_, err = DecodeStatusResult(runtime.Memory(), []uint64{math.MaxUint32 + 1, 0}, cfg.MaxResultSize())
+	require.ErrorIs(err, ErrInvalidResult)
+	_, err = DecodeStatusResult(runtime.Memory(), []uint64{uint64(math.MaxUint64), 0}, cfg.MaxResultSize())
+	require.ErrorIs(err, ErrInvalidResult)
+
+	// single value results do not follow the convention
+	_, err = DecodeStatusResult(runtime.Memory(), []uint64{0}, cfg.MaxResultSize())
+	require.ErrorIs(err, ErrInvalidResult)
+}
+
+func TestReadResultBytes(t *testing.T) {
+	require := require.New(t)
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 0) "result")
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithMaxResultSize(6).
+		Build()
+	require.NoError(err)
+	require.Equal(uint64(6), cfg.MaxResultSize())
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(context.Background(), wasm)
+	require.NoError(err)
+
+	result, err := ReadResultBytes(runtime.Memory(), 0, 6, cfg.MaxResultSize())
+	require.NoError(err)
+	require.Equal([]byte("result"), result)
+
+	_, err = ReadResultBytes(runtime.Memory(), 0, 7, cfg.MaxResultSize())
+	require.ErrorIs(err, ErrResultTooLarge)
+
+	// the default is used when not configured
+	cfg, err = NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	require.Equal(uint64(defaultMaxResultSize), cfg.MaxResultSize())
+}
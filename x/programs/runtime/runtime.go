@@ -5,17 +5,26 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/bytecodealliance/wasmtime-go/v13"
 
+	"go.uber.org/zap"
+
+	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
 )
 
 var _ Runtime = &WasmRuntime{}
 
-// New returns a new wasm runtime.
+// New returns a new wasm runtime with its own engine.
 func New(log logging.Logger, cfg *Config, imports SupportedImports) Runtime {
 	return &WasmRuntime{
 		imports: imports,
@@ -24,23 +33,77 @@ func New(log logging.Logger, cfg *Config, imports SupportedImports) Runtime {
 	}
 }
 
+// NewWithEngine returns a new wasm runtime which uses the shared [engine].
+// The engine settings of [cfg] are ignored in favor of those of [engine].
+//
+// Note: Stop only prevents future calls of this runtime, in-flight calls of
+// all runtimes sharing the engine are interrupted by Engine.Stop.
+func NewWithEngine(log logging.Logger, engine *Engine, cfg *Config, imports SupportedImports) Runtime {
+	return &WasmRuntime{
+		engine:       engine,
+		sharedEngine: true,
+		imports:      imports,
+		log:          log,
+		cfg:          cfg,
+	}
+}
+
 type WasmRuntime struct {
-	cfg   *Config
-	inst  *wasmtime.Instance
-	store *wasmtime.Store
-	mod   *wasmtime.Module
-	exp   WasmtimeExportClient
-	meter Meter
+	cfg    *Config
+	engine *Engine
+	inst   *wasmtime.Instance
+	store  *wasmtime.Store
+	mod    *wasmtime.Module
+	exp    WasmtimeExportClient
+	meter  Meter
+
+	// sharedEngine is true if the engine is shared with other runtimes.
+	sharedEngine bool
+	stopped      atomic.Bool
+
+	// parent is the runtime which created this child runtime, see
+	// NewChildRuntime.
+	parent *WasmRuntime
 
 	once     sync.Once
 	cancelFn context.CancelFunc
 
 	imports SupportedImports
 
+	// capture, output and resource usage of the guest in testing only mode
+	capture *outputCapture
+	// captureMu guards capture against Stop while a call is in progress.
+	captureMu sync.Mutex
+	// inCall is true while a call is in progress, Stop then leaves closing
+	// the capture to the call so its output is read.
+	inCall bool
+	// callCtx is the context of the call in progress, see Link.Context.
+	callCtx context.Context
+	output  Output
+	usage   Usage
+	// coverage maps the counters of the guest instrumented in testing only
+	// mode to its functions.
+	coverage *coverageMap
+	// audit records the import calls of the last call in import audit mode.
+	audit *auditLog
+	// panicMsg is the message of the guest panic during the last call in
+	// testing only mode.
+	panicMsg string
+	// params tracks the bytes written through Memory as the params of the
+	// next call.
+	params paramsBudget
+
+	// memoryImage is the exported memory at instantiation, restored after
+	// each call if memory reset is enabled.
+	memoryImage []byte
+
 	log logging.Logger
 }
 
 func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	ctx, r.cancelFn = context.WithCancel(ctx)
 	go func(ctx context.Context) {
 		<-ctx.Done()
@@ -48,7 +111,11 @@ func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err
 		r.Stop()
 	}(ctx)
 
-	r.store = wasmtime.NewStore(wasmtime.NewEngineWithConfig(r.cfg.engine))
+	if r.engine == nil {
+		r.engine = NewEngine(r.cfg)
+	}
+	r.store = wasmtime.NewStore(r.engine.engine)
+	r.params = paramsBudget{max: r.cfg.maxParamsSize}
 	r.store.Limiter(
 		r.cfg.limitMaxMemory,
 		r.cfg.limitMaxTableElements,
@@ -60,6 +127,13 @@ func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err
 	// set initial epoch deadline
 	r.store.SetEpochDeadline(1)
 
+	if r.cfg.coverage {
+		programBytes, r.coverage, err = instrumentCoverage(programBytes)
+		if err != nil {
+			return err
+		}
+	}
+
 	switch r.cfg.compileStrategy {
 	case PrecompiledWasm:
 		// Note: that to deserialize successfully the bytes provided must have been
@@ -68,7 +142,7 @@ func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err
 		//
 		// A precompile is not something we would store on chain.
 		// Instead we would prefetch programs and precompile them.
-		r.mod, err = wasmtime.NewModuleDeserialize(r.store.Engine, programBytes)
+		r.mod, err = deserializeModule(r.engine, programBytes)
 		if err != nil {
 			return err
 		}
@@ -81,9 +155,44 @@ func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err
 		return fmt.Errorf("unsupported compile strategy: %v", r.cfg.compileStrategy)
 	}
 
-	link := Link{wasmtime.NewLinker(r.store.Engine)}
+	if r.cfg.importAuditSize > 0 {
+		r.audit = newAuditLog(r.cfg.importAuditSize)
+	}
+	link := Link{Linker: wasmtime.NewLinker(r.store.Engine), audit: r.audit, runtime: r}
+	if r.cfg.testingOnlyMode {
+		var wasiCfg *wasmtime.WasiConfig
+		wasiCfg, r.capture, err = newOutputCapture()
+		if err != nil {
+			return err
+		}
+		// Stop may never be called if initialization fails.
+		defer func() {
+			if err != nil {
+				r.captureMu.Lock()
+				r.closeCapture()
+				r.captureMu.Unlock()
+			}
+		}()
+		r.store.SetWasi(wasiCfg)
+		err = link.DefineWasi()
+		if err != nil {
+			return err
+		}
+		err = r.definePanic(link)
+		if err != nil {
+			return err
+		}
+	}
+
 	// setup metering
 	r.meter = NewMeter(r.store)
+	if r.cfg.noMetering {
+		r.log.Warn("fuel metering disabled")
+		r.meter = unmeteredMeter{}
+	}
+	if r.cfg.newMeter != nil {
+		r.meter = r.cfg.newMeter(r.meter)
+	}
 	_, err = r.meter.AddUnits(r.cfg.meterMaxUnits)
 	if err != nil {
 		return err
@@ -95,12 +204,21 @@ func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err
 	imports := getRegisteredImportModules(r.mod.Imports())
 	// register host functions exposed to the guest (imports)
 	for _, imp := range imports {
+		if imp == debugModName && r.cfg.testingOnlyMode {
+			// defined by the runtime
+			continue
+		}
 		// registered separately by linker
 		mod, ok := r.imports[imp]
 		if !ok {
 			return fmt.Errorf("%w: %s", ErrMissingImportModule, imp)
 		}
-		err = mod().Register(link, r.meter, r.imports)
+		meter := &importMeter{
+			Meter:  r.meter,
+			module: imp,
+			policy: r.cfg.subsidyPolicy,
+		}
+		err = mod().Register(link, meter, r.imports)
 		if err != nil {
 			return err
 		}
@@ -112,6 +230,16 @@ func (r *WasmRuntime) Initialize(ctx context.Context, programBytes []byte) (err
 		return err
 	}
 
+	if r.cfg.memoryReset {
+		mem, err := newExportClient(r.inst, r.store).GetMemory()
+		if err != nil {
+			return err
+		}
+		data := mem.UnsafeData(r.store)
+		r.memoryImage = make([]byte, len(data))
+		copy(r.memoryImage, data)
+	}
+
 	return nil
 }
 
@@ -134,7 +262,25 @@ func getRegisteredImportModules(importTypes []*wasmtime.ImportType) []string {
 	return imports
 }
 
-func (r *WasmRuntime) Call(_ context.Context, name string, params ...uint64) ([]uint64, error) {
+// Call invokes the exported function [name] with [params]. If [ctx] is
+// cancelled during the call the runtime is stopped, interrupting the call.
+//
+// Functions exported by the SDK take a pointer to the program ID as their
+// first param, which the caller must provide. Functions which do not take
+// the program ID, including those without params, are called with exactly
+// the params they declare. A function without results returns no values.
+//
+// Note: a runtime sharing an engine can not be interrupted by [ctx] as doing
+// so would interrupt all runtimes sharing the engine, use Engine.Stop instead.
+func (r *WasmRuntime) Call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, name)
+	}
+	// the params written before this call do not count towards the next.
+	defer func() {
+		r.params.used = 0
+	}()
+
 	var fnName string
 	switch name {
 	case AllocFnName, DeallocFnName, MemoryFnName:
@@ -144,14 +290,25 @@ func (r *WasmRuntime) Call(_ context.Context, name string, params ...uint64) ([]
 		fnName = name + guestSuffix
 	}
 
+	if r.stopped.Load() {
+		// the epoch of a shared engine is not incremented by Stop, instead
+		// the deadline of this store is set to the current epoch.
+		r.store.SetEpochDeadline(0)
+	}
+
 	fn := r.inst.GetFunc(r.store, fnName)
+	if fn == nil && fnName != name {
+		// guests not built with the SDK, such as AssemblyScript and TinyGo,
+		// export functions without the guest suffix.
+		fn = r.inst.GetFunc(r.store, name)
+	}
 	if fn == nil {
 		return nil, fmt.Errorf("%w: %s", ErrMissingExportedFunction, name)
 	}
 
 	fnParams := fn.Type(r.store).Params()
-	if len(params) != len(fnParams) {
-		return nil, fmt.Errorf("%w for function %s: %d expected: %d", ErrInvalidParamCount, name, len(params), len(fnParams))
+	if err := validateFunctionParams(name, params, fnParams); err != nil {
+		return nil, err
 	}
 
 	callParams, err := mapFunctionParams(params, fnParams)
@@ -159,25 +316,235 @@ func (r *WasmRuntime) Call(_ context.Context, name string, params ...uint64) ([]
 		return nil, err
 	}
 
+	if !r.sharedEngine && ctx.Done() != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				// send immediate interrupt to engine
+				r.Stop()
+			case <-done:
+			}
+		}()
+	}
+
+	var watch *usageWatch
+	if r.cfg.testingOnlyMode {
+		r.panicMsg = ""
+		watch = startUsage()
+	}
+	if r.audit != nil {
+		r.audit.reset()
+	}
+	r.callCtx = ctx
+	defer func() {
+		r.callCtx = nil
+	}()
+	r.captureMu.Lock()
+	r.inCall = true
+	r.captureMu.Unlock()
+	var entries uint64
+	if r.coverage != nil {
+		entries = r.coverage.total(r.store, r.inst)
+	}
 	result, err := fn.Call(r.store, callParams...)
+	if r.coverage != nil {
+		r.refundCoverage(name, r.coverage.total(r.store, r.inst)-entries)
+	}
+	if watch != nil {
+		r.usage = watch.stop()
+		r.log.Debug("guest resource usage",
+			zap.String("function", name),
+			zap.Duration("wallTime", r.usage.WallTime),
+			zap.Duration("cpuTime", r.usage.CPUTime),
+			zap.Uint64("processMaxRSS", r.usage.ProcessMaxRSS),
+		)
+	}
+	r.captureOutput(name)
+	r.captureMu.Lock()
+	r.inCall = false
+	if r.stopped.Load() {
+		r.closeCapture()
+	}
+	r.captureMu.Unlock()
 	if err != nil {
-		return nil, fmt.Errorf("export function call failed %s: %w", name, err)
+		r.logImportCalls(name)
+		category := callErrorCategory(err)
+		if r.panicMsg != "" {
+			r.log.Info("guest panic",
+				zap.String("function", name),
+				zap.String("message", r.panicMsg),
+			)
+			err = fmt.Errorf("%w: %s: %w", ErrGuestPanic, r.panicMsg, err)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("%w: %w", ctxErr, err)
+		}
+		return nil, errs.Wrapf(category, err, "export function call failed %s", name)
 	}
 
 	switch v := result.(type) {
+	case nil:
+		// function without results
+		return []uint64{}, nil
 	case int32:
 		value := uint64(result.(int32))
 		return []uint64{value}, nil
 	case int64:
 		value := uint64(result.(int64))
 		return []uint64{value}, nil
+	case []wasmtime.Val:
+		// multi-value results
+		return mapFunctionResults(v)
 	default:
 		return nil, fmt.Errorf("invalid result type: %v", v)
 	}
 }
 
+// callErrorCategory returns the category of the error [err] of a guest call.
+func callErrorCategory(err error) error {
+	if category := errs.Category(err); category != nil {
+		return category
+	}
+	var trap *wasmtime.Trap
+	if errors.As(err, &trap) {
+		if code := trap.Code(); code != nil && *code == wasmtime.MemoryOutOfBounds {
+			return errs.ErrMemory
+		}
+	}
+	// traps of host functions lose the type of their error
+	msg := err.Error()
+	if strings.Contains(msg, fuelConsumedMsg) || strings.Contains(msg, ErrInsufficientUnits.Error()) {
+		return errs.ErrOutOfFuel
+	}
+	return errs.ErrTrap
+}
+
+// captureOutput reads the guest output of the call to [name] in testing only
+// mode and logs it. The output of a call interrupted by Stop is read as well,
+// as Stop leaves the capture open until the call returns.
+func (r *WasmRuntime) captureOutput(name string) {
+	if r.capture == nil {
+		return
+	}
+	output, err := r.capture.read()
+	if err != nil {
+		r.log.Error("failed to read guest output",
+			zap.Stringer("programID", r.cfg.programID),
+			zap.String("function", name),
+			zap.Error(err),
+		)
+	}
+	r.output = output
+	if len(output.Stdout) > 0 {
+		r.log.Info("guest stdout",
+			zap.Stringer("programID", r.cfg.programID),
+			zap.String("function", name),
+			zap.ByteString("output", output.Stdout),
+		)
+	}
+	if len(output.Stderr) > 0 {
+		r.log.Info("guest stderr",
+			zap.Stringer("programID", r.cfg.programID),
+			zap.String("function", name),
+			zap.ByteString("output", output.Stderr),
+		)
+	}
+}
+
+// ResetMemory restores the exported memory to its contents at instantiation
+// and zeroes the pages grown since. A runtime reused across transactions
+// should be reset once the results of a transaction are read so the data of
+// one transaction can not be read by the next. Memory reset must be enabled
+// by the config.
+func (r *WasmRuntime) ResetMemory() error {
+	if r.memoryImage == nil {
+		return ErrMemoryResetDisabled
+	}
+	mem, err := newExportClient(r.inst, r.store).GetMemory()
+	if err != nil {
+		return err
+	}
+	data := mem.UnsafeData(r.store)
+	n := copy(data, r.memoryImage)
+	for i := n; i < len(data); i++ {
+		data[i] = 0
+	}
+	return nil
+}
+
+// Output returns the stdout and stderr written by the guest during the last
+// call. Output is only captured in testing only mode.
+func (r *WasmRuntime) Output() Output {
+	return r.output
+}
+
+// IsPure returns true if the guest marks the function [name] as pure by
+// exporting [name] with the pure suffix. See ResultCache.
+func (r *WasmRuntime) IsPure(name string) bool {
+	if r.inst == nil {
+		return false
+	}
+	return r.inst.GetExport(r.store, name+pureSuffix) != nil
+}
+
+// Coverage returns the number of entries of each function of the guest over
+// all calls. Coverage must be enabled by the config.
+func (r *WasmRuntime) Coverage() (*Coverage, error) {
+	if r.coverage == nil {
+		return nil, ErrCoverageDisabled
+	}
+	return r.coverage.read(r.store, r.inst)
+}
+
+// refundCoverage adds back the units consumed by the instrumentation of the
+// [entries] function entries of the call to [name].
+func (r *WasmRuntime) refundCoverage(name string, entries uint64) {
+	if _, err := r.meter.AddUnits(entries * coverageUnits); err != nil {
+		r.log.Error("failed to refund coverage units",
+			zap.String("function", name),
+			zap.Error(err),
+		)
+	}
+}
+
+// ImportCalls returns the host function invocations of the last call, oldest
+// first. Only the most recent invocations up to the size of the import audit
+// are kept. Returns nil unless import audit mode is enabled.
+func (r *WasmRuntime) ImportCalls() []ImportCall {
+	if r.audit == nil {
+		return nil
+	}
+	return r.audit.list()
+}
+
+// logImportCalls logs the import calls of the failed call to [name].
+func (r *WasmRuntime) logImportCalls(name string) {
+	if r.audit == nil {
+		return
+	}
+	for _, call := range r.audit.list() {
+		r.log.Info("import call",
+			zap.String("function", name),
+			zap.Stringer("call", call),
+		)
+	}
+}
+
+// Usage returns the resource usage of the last call. Usage is only measured
+// in testing only mode.
+func (r *WasmRuntime) Usage() Usage {
+	return r.usage
+}
+
+// Memory returns the memory of the guest. Blocks allocated through it count
+// towards the params of the next call, limited by Config.MaxParamsSize.
 func (r *WasmRuntime) Memory() Memory {
-	return NewMemory(newExportClient(r.inst, r.store))
+	return &memory{
+		client: newExportClient(r.inst, r.store),
+		params: &r.params,
+	}
 }
 
 func (r *WasmRuntime) Meter() Meter {
@@ -187,16 +554,37 @@ func (r *WasmRuntime) Meter() Meter {
 func (r *WasmRuntime) Stop() {
 	r.once.Do(func() {
 		r.log.Debug("shutting down runtime engine...")
-		// send immediate interrupt to engine
-		r.store.Engine.IncrementEpoch()
-		r.cancelFn()
+		r.stopped.Store(true)
+		if !r.sharedEngine && r.engine != nil {
+			// send immediate interrupt to engine
+			r.engine.Stop()
+		}
+		r.captureMu.Lock()
+		if !r.inCall {
+			r.closeCapture()
+		}
+		r.captureMu.Unlock()
+		if r.cancelFn != nil {
+			r.cancelFn()
+		}
 	})
 }
 
-// PreCompileWasm returns a precompiled wasm module.
+// closeCapture releases the output capture. Must be called with captureMu
+// held.
+func (r *WasmRuntime) closeCapture() {
+	if r.capture != nil {
+		_ = r.capture.close(nil)
+		r.capture = nil
+	}
+}
+
+// PreCompileWasm returns a precompiled wasm module prefixed by the engine hash
+// of [cfg], see Config.EngineHash.
 //
-// Note: these bytes can be deserialized by an `Engine` that has the same version.
-// For that reason precompiled wasm modules should not be stored on chain.
+// Note: these bytes can only be deserialized by an `Engine` with the same
+// hash, runtimes reject the bytes of any other engine. For that reason
+// precompiled wasm modules should not be stored on chain.
 func PreCompileWasmBytes(programBytes []byte, cfg *Config) ([]byte, error) {
 	store := wasmtime.NewStore(wasmtime.NewEngineWithConfig(cfg.engine))
 	store.Limiter(
@@ -212,7 +600,62 @@ func PreCompileWasmBytes(programBytes []byte, cfg *Config) ([]byte, error) {
 		return nil, err
 	}
 
-	return module.Serialize()
+	serialized, err := module.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return append(cfg.engineHash[:], serialized...), nil
+}
+
+// deserializeModule returns the module precompiled by PreCompileWasmBytes.
+// Modules precompiled by an engine with a different hash than [engine] are
+// rejected as they may not behave the same, even if wasmtime can load them.
+func deserializeModule(engine *Engine, artifact []byte) (*wasmtime.Module, error) {
+	if len(artifact) < ids.IDLen {
+		return nil, fmt.Errorf("%w: missing engine hash", ErrArtifactMismatch)
+	}
+	hash, err := ids.ToID(artifact[:ids.IDLen])
+	if err != nil {
+		return nil, err
+	}
+	if hash != engine.hash {
+		return nil, fmt.Errorf("%w: %s != %s", ErrArtifactMismatch, hash, engine.hash)
+	}
+	return wasmtime.NewModuleDeserialize(engine.engine, artifact[ids.IDLen:])
+}
+
+// validateFunctionParams ensures [input] matches the parameters of the
+// exported function [name] so a mismatch is reported before invocation rather
+// than surfacing as a trap.
+func validateFunctionParams(name string, input []uint64, values []*wasmtime.ValType) error {
+	if len(input) != len(values) {
+		return fmt.Errorf("%w: %w for function %s: expected %d %s, provided %d",
+			ErrSignatureMismatch, ErrInvalidParamCount, name, len(values), formatParamKinds(values), len(input))
+	}
+	for i, v := range values {
+		switch v.Kind() {
+		case wasmtime.KindI32:
+			// allow both unsigned and sign extended 32 bit values
+			if value := int64(input[i]); value < math.MinInt32 || value > math.MaxUint32 {
+				return fmt.Errorf("%w: %w for function %s: param %d value %d overflows i32",
+					ErrSignatureMismatch, ErrInvalidParamType, name, i, input[i])
+			}
+		case wasmtime.KindI64:
+		default:
+			return fmt.Errorf("%w: %w for function %s: param %d kind %v is not supported",
+				ErrSignatureMismatch, ErrInvalidParamType, name, i, v.Kind())
+		}
+	}
+	return nil
+}
+
+// formatParamKinds returns the parameter kinds of a function as "(i32, i64)".
+func formatParamKinds(values []*wasmtime.ValType) string {
+	kinds := make([]string, len(values))
+	for i, v := range values {
+		kinds[i] = v.Kind().String()
+	}
+	return "(" + strings.Join(kinds, ", ") + ")"
 }
 
 // mapFunctionParams maps call input to the expected wasm function params.
@@ -231,3 +674,20 @@ func mapFunctionParams(input []uint64, values []*wasmtime.ValType) ([]interface{
 
 	return params, nil
 }
+
+// mapFunctionResults maps multi-value wasm function results to uint64 values.
+func mapFunctionResults(values []wasmtime.Val) ([]uint64, error) {
+	results := make([]uint64, len(values))
+	for i, v := range values {
+		switch v.Kind() {
+		case wasmtime.KindI32:
+			results[i] = uint64(v.I32())
+		case wasmtime.KindI64:
+			results[i] = uint64(v.I64())
+		default:
+			return nil, fmt.Errorf("invalid result type: %v", v.Kind())
+		}
+	}
+
+	return results, nil
+}
@@ -5,13 +5,17 @@ package runtime
 
 import (
 	"context"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/ava-labs/avalanchego/utils/logging"
 
 	"github.com/bytecodealliance/wasmtime-go/v13"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/ava-labs/hypersdk/x/programs/errs"
 )
 
 func TestStop(t *testing.T) {
@@ -48,6 +52,80 @@ func TestStop(t *testing.T) {
 	require.Equal(runtime.Meter().GetBalance(), maxUnits)
 }
 
+func TestStopInFlight(t *testing.T) {
+	// infinite loop
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "run_guest")
+	    (loop
+	      br 0)
+	  )
+	)
+	`)
+	require.NoError(t, err)
+
+	newRuntime := func(ctx context.Context) Runtime {
+		cfg, err := NewConfigBuilder(1_000_000_000).Build()
+		require.NoError(t, err)
+		runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+		require.NoError(t, runtime.Initialize(ctx, wasm))
+		return runtime
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		require := require.New(t)
+		runtime := newRuntime(context.Background())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			runtime.Stop()
+		}()
+		_, err := runtime.Call(context.Background(), "run")
+		require.ErrorContains(err, "wasm trap: interrupt")
+
+		// calls after stop are interrupted
+		_, err = runtime.Call(context.Background(), "run")
+		require.ErrorContains(err, "wasm trap: interrupt")
+	})
+
+	t.Run("call context cancelled", func(t *testing.T) {
+		require := require.New(t)
+		runtime := newRuntime(context.Background())
+		defer runtime.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := runtime.Call(ctx, "run")
+		require.ErrorIs(err, context.DeadlineExceeded)
+		require.ErrorContains(err, "wasm trap: interrupt")
+
+		// cancelled context is checked before invocation
+		_, err = runtime.Call(ctx, "run")
+		require.ErrorIs(err, context.DeadlineExceeded)
+	})
+
+	t.Run("initialize context cancelled", func(t *testing.T) {
+		require := require.New(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		runtime := newRuntime(ctx)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		_, err := runtime.Call(context.Background(), "run")
+		require.ErrorContains(err, "wasm trap: interrupt")
+
+		cfg, err := NewConfigBuilder(NoUnits).Build()
+		require.NoError(err)
+		runtime = New(logging.NoLog{}, cfg, NoSupportedImports)
+		err = runtime.Initialize(ctx, wasm)
+		require.ErrorIs(err, context.Canceled)
+		// stop is safe after a failed initialize
+		runtime.Stop()
+	})
+}
+
 func TestCallParams(t *testing.T) {
 	require := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -79,4 +157,240 @@ func TestCallParams(t *testing.T) {
 	// pass 3 params when 2 are expected.
 	_, err = runtime.Call(ctx, "add", uint64(10), uint64(10), uint64(10))
 	require.ErrorIs(err, ErrInvalidParamCount)
+	require.ErrorIs(err, ErrSignatureMismatch)
+	require.ErrorContains(err, "expected 2 (i32, i32), provided 3")
+
+	// pass a value which does not fit in an i32 param.
+	_, err = runtime.Call(ctx, "add", uint64(10), uint64(1<<32))
+	require.ErrorIs(err, ErrInvalidParamType)
+	require.ErrorIs(err, ErrSignatureMismatch)
+
+	// sign extended values are accepted.
+	resp, err = runtime.Call(ctx, "add", uint64(10), uint64(0xffffffffffffffff))
+	require.NoError(err)
+	require.Equal(uint64(9), resp[0])
+}
+
+func TestCallNullary(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (global $count (mut i32) (i32.const 0))
+	  (func (export "inc_guest")
+	    (global.set $count (i32.add (global.get $count) (i32.const 1)))
+	  )
+	  (func (export "count_guest") (result i32)
+	    (global.get $count)
+	  )
+	)
+	`)
+	require.NoError(err)
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	// no params and no results
+	resp, err := runtime.Call(ctx, "inc")
+	require.NoError(err)
+	require.Empty(resp)
+
+	// no params
+	resp, err = runtime.Call(ctx, "count")
+	require.NoError(err)
+	require.Equal([]uint64{1}, resp)
+
+	// the program id is not prepended
+	_, err = runtime.Call(ctx, "count", 0)
+	require.ErrorIs(err, ErrInvalidParamCount)
+	require.ErrorContains(err, "expected 0 (), provided 1")
+}
+
+func TestTestingOnlyModeOutput(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// writes "hello\n" to stdout (fd 1) and "oops\n" to stderr (fd 2)
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (param i32 i32 i32 i32) (result i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 16) "hello\n")
+	  (data (i32.const 32) "oops\n")
+	  (func (export "print_loop_guest") (param i64)
+	    (drop (call $print (local.get 0)))
+	    (loop
+	      br 0)
+	  )
+	  (func $print (export "print_guest") (param i64) (result i32)
+	    ;; iovec stdout
+	    (i32.store (i32.const 0) (i32.const 16))
+	    (i32.store (i32.const 4) (i32.const 6))
+	    (drop (call $fd_write (i32.const 1) (i32.const 0) (i32.const 1) (i32.const 48)))
+	    ;; iovec stderr
+	    (i32.store (i32.const 0) (i32.const 32))
+	    (i32.store (i32.const 4) (i32.const 5))
+	    (call $fd_write (i32.const 2) (i32.const 0) (i32.const 1) (i32.const 48))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	// wasi is not linked unless testing only mode is enabled
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.ErrorContains(err, "unknown import")
+
+	cfg, err = NewConfigBuilder(1_000_000_000).
+		WithEnableTestingOnlyMode(true).
+		Build()
+	require.NoError(err)
+	runtime = New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.NoError(err)
+	defer runtime.Stop()
+
+	resp, err := runtime.Call(ctx, "print", 0)
+	require.NoError(err)
+	require.Equal(uint64(0), resp[0])
+
+	output := runtime.(*WasmRuntime).Output()
+	require.Equal("hello\n", string(output.Stdout))
+	require.Equal("oops\n", string(output.Stderr))
+
+	// output is captured per call
+	_, err = runtime.Call(ctx, "print", 0)
+	require.NoError(err)
+	output = runtime.(*WasmRuntime).Output()
+	require.Equal("hello\n", string(output.Stdout))
+
+	// the output of a call interrupted by stop is kept
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		runtime.Stop()
+	}()
+	_, err = runtime.Call(ctx, "print_loop", 0)
+	require.ErrorContains(err, "wasm trap: interrupt")
+	output = runtime.(*WasmRuntime).Output()
+	require.Equal("hello\n", string(output.Stdout))
+	require.Equal("oops\n", string(output.Stderr))
+}
+
+func TestTestingOnlyModeInitializeError(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// the output capture is created in the temp dir
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "env" "missing" (func $missing))
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithEnableTestingOnlyMode(true).
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.ErrorIs(err, ErrMissingImportModule)
+
+	// the capture is released without Stop
+	entries, err := os.ReadDir(dir)
+	require.NoError(err)
+	require.Empty(entries)
+}
+
+func TestTestingOnlyModePanic(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// calls the panic hook with "boom" before trapping if the param is 0
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (import "debug" "panic" (func $panic (param i32 i32)))
+	  (memory 1)
+	  (export "memory" (memory 0))
+	  (data (i32.const 16) "boom")
+	  (func (export "check_guest") (param i32) (result i32)
+	    (if (i32.eqz (local.get 0))
+	      (then
+	        (call $panic (i32.const 16) (i32.const 4))
+	        unreachable
+	      )
+	    )
+	    (local.get 0)
+	  )
+	  (func (export "trap_guest") (result i32)
+	    unreachable
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	// the panic hook is not defined unless testing only mode is enabled
+	cfg, err := NewConfigBuilder(10000).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.ErrorIs(err, ErrMissingImportModule)
+
+	cfg, err = NewConfigBuilder(10000).
+		WithEnableTestingOnlyMode(true).
+		Build()
+	require.NoError(err)
+	runtime = New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	_, err = runtime.Call(ctx, "check", 0)
+	require.ErrorIs(err, ErrGuestPanic)
+	require.ErrorContains(err, "guest panicked: boom")
+
+	// the message is cleared between calls
+	resp, err := runtime.Call(ctx, "check", 1)
+	require.NoError(err)
+	require.Equal([]uint64{1}, resp)
+
+	_, err = runtime.Call(ctx, "trap")
+	require.ErrorIs(err, errs.ErrTrap)
+	require.NotErrorIs(err, ErrGuestPanic)
+}
+
+func TestDebugInfo(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module $test
+	  (func $trap_guest (param i64) (result i32)
+	    unreachable
+	  )
+	  (export "trap_guest" (func $trap_guest))
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(10000).
+		WithDebugInfo(true).
+		WithCraneliftDebugVerifier(true).
+		Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	err = runtime.Initialize(ctx, wasm)
+	require.NoError(err)
+	defer runtime.Stop()
+
+	_, err = runtime.Call(ctx, "trap", 0)
+	require.ErrorContains(err, "trap_guest")
 }
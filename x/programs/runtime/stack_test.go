@@ -0,0 +1,86 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+// the default stack limit must trap unbounded recursion before the native
+// stack of the platform overflows.
+func TestDefaultMaxWasmStack(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func $recurse_guest (export "recurse_guest") (param i64) (result i64)
+	    (call $recurse_guest (i64.add (local.get 0) (i64.const 1)))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	cfg, err := NewConfigBuilder(1 << 40).Build()
+	require.NoError(err)
+	runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+	require.NoError(runtime.Initialize(ctx, wasm))
+	defer runtime.Stop()
+
+	_, err = runtime.Call(ctx, "recurse", 0)
+	var trap *wasmtime.Trap
+	require.True(errors.As(err, &trap))
+	require.Equal(wasmtime.StackOverflow, *trap.Code())
+}
+
+// recursion deeper than the default stack limit traps unless the limit is
+// raised.
+func TestMaxWasmStack(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// recurses [depth] times
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func $recurse_guest (export "recurse_guest") (param i64) (result i64)
+	    (if (result i64) (i64.eqz (local.get 0))
+	      (then (i64.const 0))
+	      (else (i64.add (i64.const 1) (call $recurse_guest (i64.sub (local.get 0) (i64.const 1))))))
+	  )
+	)
+	`)
+	require.NoError(err)
+
+	const depth = 6000
+	call := func(cfg *Config) ([]uint64, error) {
+		runtime := New(logging.NoLog{}, cfg, NoSupportedImports)
+		require.NoError(runtime.Initialize(ctx, wasm))
+		defer runtime.Stop()
+		return runtime.Call(ctx, "recurse", depth)
+	}
+
+	cfg, err := NewConfigBuilder(1 << 40).Build()
+	require.NoError(err)
+	_, err = call(cfg)
+	var trap *wasmtime.Trap
+	require.True(errors.As(err, &trap))
+	require.Equal(wasmtime.StackOverflow, *trap.Code())
+
+	// raised while still under the native stack of non-main threads on
+	// darwin.
+	cfg, err = NewConfigBuilder(1 << 40).
+		WithMaxWasmStack(448 * units.KiB).
+		Build()
+	require.NoError(err)
+	resp, err := call(cfg)
+	require.NoError(err)
+	require.Equal(uint64(depth), resp[0])
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+// Status codes returned by host functions in place of a result, so the guest
+// can handle an expected failure instead of the whole call trapping. Traps
+// are reserved for failures the guest must not recover from, such as
+// insufficient units.
+const (
+	// StatusError is returned for any failure without a more specific code.
+	StatusError = -1
+	// StatusNotFound is returned if the requested key or program does not
+	// exist.
+	StatusNotFound = -2
+	// StatusPermissionDenied is returned if the guest is not allowed to
+	// perform the operation, such as a write to read-only state.
+	StatusPermissionDenied = -3
+)
@@ -0,0 +1,50 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"runtime"
+	"time"
+)
+
+// Usage is the resource usage of a call measured in testing only mode. It
+// helps calibrate the units charged for a call against real resources.
+type Usage struct {
+	// WallTime is the elapsed time of the call.
+	WallTime time.Duration
+	// CPUTime is the user and system CPU time of the call. It is zero if the
+	// platform does not report CPU time.
+	CPUTime time.Duration
+	// ProcessMaxRSS is the maximum resident set size in bytes of the process
+	// over its lifetime, sampled at the end of the call. It is a high-water
+	// mark of the whole process, not the memory used by the call. It is zero
+	// if the platform does not report it.
+	ProcessMaxRSS uint64
+}
+
+// usageWatch measures the resource usage of a call.
+type usageWatch struct {
+	start time.Time
+	cpu   time.Duration
+}
+
+// startUsage starts measuring the resource usage of the calling goroutine.
+// The goroutine is locked to its thread until stop is called so the CPU time
+// of the thread is attributable to the call.
+func startUsage() *usageWatch {
+	runtime.LockOSThread()
+	return &usageWatch{
+		start: time.Now(),
+		cpu:   cpuTime(),
+	}
+}
+
+func (w *usageWatch) stop() Usage {
+	defer runtime.UnlockOSThread()
+	return Usage{
+		WallTime:      time.Since(w.start),
+		CPUTime:       cpuTime() - w.cpu,
+		ProcessMaxRSS: processMaxRSS(),
+	}
+}
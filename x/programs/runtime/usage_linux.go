@@ -0,0 +1,34 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build linux
+
+package runtime
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const usageSupported = true
+
+// cpuTime returns the CPU time of the calling thread.
+func cpuTime() time.Duration {
+	var ru unix.Rusage
+	if err := unix.Getrusage(unix.RUSAGE_THREAD, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// processMaxRSS returns the maximum resident set size of the process in bytes
+// over its lifetime.
+func processMaxRSS() uint64 {
+	var ru unix.Rusage
+	if err := unix.Getrusage(unix.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	// reported in KiB
+	return uint64(ru.Maxrss) * 1024
+}
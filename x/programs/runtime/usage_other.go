@@ -0,0 +1,18 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+//go:build !linux && !darwin
+
+package runtime
+
+import "time"
+
+const usageSupported = false
+
+func cpuTime() time.Duration {
+	return 0
+}
+
+func processMaxRSS() uint64 {
+	return 0
+}
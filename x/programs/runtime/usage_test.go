@@ -0,0 +1,73 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/bytecodealliance/wasmtime-go/v13"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestingOnlyModeUsage(t *testing.T) {
+	ctx := context.Background()
+
+	// loops for [n] iterations
+	wasm, err := wasmtime.Wat2Wasm(`
+	(module
+	  (func (export "loop_guest") (param $n i32) (result i32)
+	    (loop $l
+	      (local.set $n (i32.sub (local.get $n) (i32.const 1)))
+	      (br_if $l (i32.gt_s (local.get $n) (i32.const 0)))
+	    )
+	    (local.get $n)
+	  )
+	)
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		wantUsage bool
+	}{
+		{
+			name:      "testing only mode",
+			enabled:   true,
+			wantUsage: true,
+		},
+		{
+			name: "disabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			cfg, err := NewConfigBuilder(1_000_000_000).
+				WithEnableTestingOnlyMode(tt.enabled).
+				Build()
+			require.NoError(err)
+			rt := New(logging.NoLog{}, cfg, NoSupportedImports)
+			require.NoError(rt.Initialize(ctx, wasm))
+			defer rt.Stop()
+
+			_, err = rt.Call(ctx, "loop", 50_000_000)
+			require.NoError(err)
+
+			usage := rt.(*WasmRuntime).Usage()
+			if !tt.wantUsage {
+				require.Equal(Usage{}, usage)
+				return
+			}
+			require.Positive(usage.WallTime)
+			if usageSupported {
+				require.Positive(usage.CPUTime)
+				require.Positive(usage.ProcessMaxRSS)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+const (
+	wasmtimeModulePath = "github.com/bytecodealliance/wasmtime-go/v13"
+	unknownVersion     = "unknown"
+)
+
+var (
+	wasmtimeVersion     string
+	wasmtimeVersionOnce sync.Once
+)
+
+// WasmtimeVersion returns the version of the wasmtime-go module linked into
+// the binary. Precompiled modules can only be deserialized by the same
+// version which produced them.
+func WasmtimeVersion() string {
+	wasmtimeVersionOnce.Do(func() {
+		wasmtimeVersion = unknownVersion
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, dep := range info.Deps {
+			if dep.Path != wasmtimeModulePath {
+				continue
+			}
+			wasmtimeVersion = dep.Version
+			if dep.Replace != nil {
+				wasmtimeVersion = dep.Replace.Version
+			}
+			return
+		}
+	})
+	return wasmtimeVersion
+}
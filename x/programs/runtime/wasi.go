@@ -0,0 +1,82 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runtime
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bytecodealliance/wasmtime-go/v13"
+)
+
+// Output is the stdout and stderr written by the guest during a call.
+type Output struct {
+	Stdout []byte
+	Stderr []byte
+}
+
+// outputCapture redirects the WASI stdout and stderr of a store to temporary
+// files so output can be read after each call.
+type outputCapture struct {
+	dir    string
+	stdout *os.File
+	stderr *os.File
+}
+
+// newOutputCapture returns a WASI config which writes stdout and stderr to
+// files owned by the returned capture.
+func newOutputCapture() (*wasmtime.WasiConfig, *outputCapture, error) {
+	dir, err := os.MkdirTemp("", "wasi")
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &outputCapture{dir: dir}
+	cfg := wasmtime.NewWasiConfig()
+
+	stdoutPath := filepath.Join(dir, "stdout")
+	stderrPath := filepath.Join(dir, "stderr")
+	if err := cfg.SetStdoutFile(stdoutPath); err != nil {
+		return nil, nil, c.close(err)
+	}
+	if err := cfg.SetStderrFile(stderrPath); err != nil {
+		return nil, nil, c.close(err)
+	}
+	// wasmtime creates the files, open for reading only.
+	c.stdout, err = os.Open(stdoutPath)
+	if err != nil {
+		return nil, nil, c.close(err)
+	}
+	c.stderr, err = os.Open(stderrPath)
+	if err != nil {
+		return nil, nil, c.close(err)
+	}
+
+	return cfg, c, nil
+}
+
+// read returns the output written since the previous read.
+func (c *outputCapture) read() (Output, error) {
+	stdout, err := io.ReadAll(c.stdout)
+	if err != nil {
+		return Output{}, err
+	}
+	stderr, err := io.ReadAll(c.stderr)
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{Stdout: stdout, Stderr: stderr}, nil
+}
+
+// close releases the files of the capture and returns [err].
+func (c *outputCapture) close(err error) error {
+	if c.stdout != nil {
+		_ = c.stdout.Close()
+	}
+	if c.stderr != nil {
+		_ = c.stderr.Close()
+	}
+	_ = os.RemoveAll(c.dir)
+	return err
+}
@@ -1,4 +1,4 @@
-use wasmlanche_sdk::{program::Program, public, state_keys, types::Address};
+use wasmlanche_sdk::{host::actor, program::Program, public, state_keys, types::Address};
 
 /// The program state keys.
 #[state_keys]
@@ -11,6 +11,9 @@ enum StateKey {
     Symbol,
     /// The balance of the token by address. Key prefix 0x3 + address.
     Balance(Address),
+    /// The amount a spender may transfer on behalf of an owner. Key prefix
+    /// 0x4 + owner address + spender address.
+    Allowance(Address, Address),
 }
 
 /// Initializes the program with a name, symbol, and total supply.
@@ -62,9 +65,16 @@ pub fn mint_to(program: Program, recipient: Address, amount: i64) -> bool {
     true
 }
 
-/// Transfers balance from the sender to the the recipient.
+/// Transfers balance from the actor to the recipient.
 #[public]
-pub fn transfer(program: Program, sender: Address, recipient: Address, amount: i64) -> bool {
+pub fn transfer(program: Program, recipient: Address, amount: i64) -> bool {
+    let sender = actor().expect("missing actor");
+    move_balance(program, sender, recipient, amount)
+}
+
+/// Moves amount from the balance of the sender to the balance of the
+/// recipient.
+fn move_balance(program: Program, sender: Address, recipient: Address, amount: i64) -> bool {
     assert_ne!(sender, recipient, "sender and recipient must be different");
 
     // ensure the sender has adequate balance
@@ -108,3 +118,50 @@ pub fn get_balance(program: Program, recipient: Address) -> i64 {
         .get(StateKey::Balance(recipient).to_vec())
         .unwrap_or_default()
 }
+
+/// Approves the spender to transfer up to amount from the balance of the
+/// actor.
+#[public]
+pub fn approve(program: Program, spender: Address, amount: i64) -> bool {
+    assert!(amount >= 0, "invalid input");
+    let owner = actor().expect("missing actor");
+
+    program
+        .state()
+        .store(StateKey::Allowance(owner, spender).to_vec(), &amount)
+        .expect("failed to store allowance");
+
+    true
+}
+
+/// Gets the amount the spender may transfer on behalf of the owner.
+#[public]
+pub fn allowance(program: Program, owner: Address, spender: Address) -> i64 {
+    program
+        .state()
+        .get(StateKey::Allowance(owner, spender).to_vec())
+        .unwrap_or_default()
+}
+
+/// Transfers balance from the owner to the recipient on behalf of the actor,
+/// reducing the allowance of the actor.
+#[public]
+pub fn transfer_from(program: Program, owner: Address, recipient: Address, amount: i64) -> bool {
+    let spender = actor().expect("missing actor");
+    let allowance = program
+        .state()
+        .get::<i64, _>(StateKey::Allowance(owner, spender).to_vec())
+        .unwrap_or_default();
+
+    assert!(amount >= 0 && allowance >= amount, "insufficient allowance");
+
+    program
+        .state()
+        .store(
+            StateKey::Allowance(owner, spender).to_vec(),
+            &(allowance - amount),
+        )
+        .expect("failed to store allowance");
+
+    move_balance(program, owner, recipient, amount)
+}
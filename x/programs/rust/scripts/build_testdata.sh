@@ -0,0 +1,22 @@
+#!/usr/bin/env bash
+
+set -ex
+
+# Rebuilds the example programs embedded by the x/programs/examples tests from
+# source. Regenerate the golden files afterwards:
+#
+#   go test ./x/programs/examples -run TestGolden -update
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+EXAMPLES_DIR="$SCRIPT_DIR/../examples"
+TESTDATA_DIR="$SCRIPT_DIR/../../examples/testdata"
+CARGO_TARGET_DIR="$(mktemp -d)"
+trap 'rm -rf "$CARGO_TARGET_DIR"' EXIT
+
+for program in counter token; do
+  cargo build \
+    --manifest-path "$EXAMPLES_DIR/$program/Cargo.toml" \
+    --target wasm32-unknown-unknown \
+    --target-dir "$CARGO_TARGET_DIR" \
+    --release
+  cp "$CARGO_TARGET_DIR/wasm32-unknown-unknown/release/$program.wasm" "$TESTDATA_DIR/$program.wasm"
+done
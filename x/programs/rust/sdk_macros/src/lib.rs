@@ -14,8 +14,16 @@ use syn::{
 /// The wrapper function will have the same name as the original function, but with "_guest" appended to it.
 /// The wrapper functions parameters will be converted to WASM supported types. When called, the wrapper function
 /// calls the original function by converting the parameters back to their intended types using .into().
+///
+/// A function which only reads state can be marked as pure with `#[public(pure)]`, allowing the host to
+/// cache its results. The macro then also exports an empty function with "_pure" appended to the name.
 #[proc_macro_attribute]
-pub fn public(_: TokenStream, item: TokenStream) -> TokenStream {
+pub fn public(attr: TokenStream, item: TokenStream) -> TokenStream {
+    let pure = match attr.to_string().as_str() {
+        "" => false,
+        "pure" => true,
+        _ => panic!("Unsupported attribute, expected `pure`."),
+    };
     let input = parse_macro_input!(item as ItemFn);
     let name = &input.sig.ident;
     let input_args = &input.sig.inputs;
@@ -66,11 +74,23 @@ pub fn public(_: TokenStream, item: TokenStream) -> TokenStream {
 
     // Extract the original function's return type. This must be a WASM supported type.
     let return_type = &input.sig.output;
+    // The host checks for this export to determine if the function is pure.
+    let pure_marker = if pure {
+        let pure_name = Ident::new(&format!("{}_pure", name), name.span());
+        quote! {
+            #[no_mangle]
+            pub extern "C" fn #pure_name() {}
+        }
+    } else {
+        quote! {}
+    };
     let output = quote! {
+        #pure_marker
         // Need to include the original function in the output, so contract can call itself
         #input
         #[no_mangle]
         pub extern "C" fn #new_name(#(#param_names: #param_types), *) #return_type {
+            wasmlanche_sdk::debug::register_panic();
             // .into() uses the From() on each argument in the iterator to convert it to the type we want. 70% sure about this statement.
             #name(#(#param_names_cloned.into()),*) // This means that every parameter type must implement From<i64>(except for the supported primitive types).
         }
@@ -128,9 +148,16 @@ fn generate_to_vec(
             let index = idx as u8;
             match &variant.fields {
                 // ex: Point(f64, f64)
-                Fields::Unnamed(_) => quote! {
-                    Self::#variant_ident(a) => std::iter::once(#index).chain(a.into_iter()).collect()
-                },
+                Fields::Unnamed(fields) => {
+                    let bindings: Vec<_> = (0..fields.unnamed.len())
+                        .map(|i| quote::format_ident!("a{}", i))
+                        .collect();
+                    quote! {
+                        Self::#variant_ident(#(#bindings),*) => std::iter::once(#index)
+                            #(.chain(#bindings.into_iter()))*
+                            .collect()
+                    }
+                }
                 // ex: Point
                 Fields::Unit => quote! {
                     Self::#variant_ident => vec![#index]
@@ -0,0 +1,28 @@
+//! The `debug` module forwards guest panic messages to the host. Without the
+//! `debug` feature a panic only surfaces as an unreachable trap, with it the
+//! host attaches the message of the panic to the error of the call. The host
+//! only defines the import in testing only mode.
+
+#[cfg(feature = "debug")]
+#[link(wasm_import_module = "debug")]
+extern "C" {
+    #[link_name = "panic"]
+    fn _panic(ptr: *const u8, len: usize);
+}
+
+/// Registers a panic hook sending the panic message to the host. Called by
+/// every function annotated with `#[public]`, it does nothing unless the
+/// `debug` feature is enabled.
+#[inline]
+pub fn register_panic() {
+    #[cfg(feature = "debug")]
+    {
+        static ONCE: std::sync::Once = std::sync::Once::new();
+        ONCE.call_once(|| {
+            std::panic::set_hook(Box::new(|info| {
+                let msg = info.to_string();
+                unsafe { _panic(msg.as_ptr(), msg.len()) };
+            }));
+        });
+    }
+}
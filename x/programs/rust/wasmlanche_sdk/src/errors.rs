@@ -20,6 +20,12 @@ pub enum StateError {
     #[error("failed to read from host storage")]
     Read,
 
+    #[error("key not found in host storage")]
+    NotFound,
+
+    #[error("permission denied to write to host storage")]
+    PermissionDenied,
+
     #[error("failed to serialize bytes")]
     Serialization,
 }
@@ -0,0 +1,43 @@
+//! The `context` module provides the identity of the executing program, so
+//! programs can derive namespaced keys and verify their own identity, and of
+//! the actor executing it, so programs can authorize operations.
+
+use crate::types::{Address, Bytes32, ADDRESS_LEN};
+
+#[link(wasm_import_module = "context")]
+extern "C" {
+    #[link_name = "program_id"]
+    fn _program_id(out_ptr: *mut u8) -> i32;
+
+    #[link_name = "program_code_hash"]
+    fn _program_code_hash(out_ptr: *mut u8) -> i32;
+
+    #[link_name = "actor"]
+    fn _actor(out_ptr: *mut u8) -> i32;
+}
+
+/// Reads 32 bytes written by `op`.
+fn read(op: unsafe extern "C" fn(*mut u8) -> i32) -> Option<[u8; ADDRESS_LEN]> {
+    let mut out = [0u8; ADDRESS_LEN];
+    let res = unsafe { op(out.as_mut_ptr()) };
+    (res == 0).then_some(out)
+}
+
+/// Returns the ID of the executing program.
+#[must_use]
+pub fn program_id() -> Option<Address> {
+    read(_program_id).map(Address::new)
+}
+
+/// Returns the hash of the code of the executing program.
+#[must_use]
+pub fn program_code_hash() -> Option<Bytes32> {
+    read(_program_code_hash).map(Bytes32::new)
+}
+
+/// Returns the address of the actor which signed the transaction executing
+/// the program, or `None` if the host provides no actor.
+#[must_use]
+pub fn actor() -> Option<Address> {
+    read(_actor).map(Address::new)
+}
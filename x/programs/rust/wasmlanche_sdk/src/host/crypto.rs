@@ -0,0 +1,36 @@
+//! The `crypto` module provides functions for verifying signatures with the
+//! host.
+
+/// The length of a compressed BLS public key.
+pub const BLS_PUBLIC_KEY_LEN: usize = 48;
+/// The length of a compressed BLS signature.
+pub const BLS_SIGNATURE_LEN: usize = 96;
+
+#[link(wasm_import_module = "crypto")]
+extern "C" {
+    #[link_name = "verify_bls"]
+    fn _verify_bls(
+        public_key_ptr: *const u8,
+        signature_ptr: *const u8,
+        message_ptr: *const u8,
+        message_len: usize,
+    ) -> i32;
+}
+
+/// Returns true if `signature` is a valid BLS signature of `message` for
+/// `public_key`.
+#[must_use]
+pub fn verify_bls(
+    public_key: &[u8; BLS_PUBLIC_KEY_LEN],
+    signature: &[u8; BLS_SIGNATURE_LEN],
+    message: &[u8],
+) -> bool {
+    unsafe {
+        _verify_bls(
+            public_key.as_ptr(),
+            signature.as_ptr(),
+            message.as_ptr(),
+            message.len(),
+        ) == 1
+    }
+}
@@ -0,0 +1,55 @@
+//! The `encoding` module provides hex and base64 encoding computed by the
+//! host, so programs do not need to bundle encoding libraries.
+
+#[link(wasm_import_module = "encoding")]
+extern "C" {
+    #[link_name = "hex_encode"]
+    fn _hex_encode(in_ptr: *const u8, in_len: usize, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "hex_decode"]
+    fn _hex_decode(in_ptr: *const u8, in_len: usize, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "base64_encode"]
+    fn _base64_encode(in_ptr: *const u8, in_len: usize, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "base64_decode"]
+    fn _base64_decode(in_ptr: *const u8, in_len: usize, out_ptr: *mut u8) -> i32;
+}
+
+type Convert = unsafe extern "C" fn(*const u8, usize, *mut u8) -> i32;
+
+/// Converts `input` with `op` into a buffer of at most `max_len` bytes.
+fn convert(op: Convert, input: &[u8], max_len: usize) -> Option<Vec<u8>> {
+    let mut out = vec![0u8; max_len];
+    let len = unsafe { op(input.as_ptr(), input.len(), out.as_mut_ptr()) };
+    let len = usize::try_from(len).ok()?;
+    out.truncate(len);
+    Some(out)
+}
+
+/// Returns the lowercase hex encoding of `input`.
+#[must_use]
+pub fn hex_encode(input: &[u8]) -> Option<String> {
+    let out = convert(_hex_encode, input, input.len() * 2)?;
+    String::from_utf8(out).ok()
+}
+
+/// Returns the bytes of the hex encoded `input` or `None` if it is invalid.
+#[must_use]
+pub fn hex_decode(input: &str) -> Option<Vec<u8>> {
+    convert(_hex_decode, input.as_bytes(), input.len() / 2)
+}
+
+/// Returns the standard padded base64 encoding of `input`.
+#[must_use]
+pub fn base64_encode(input: &[u8]) -> Option<String> {
+    let out = convert(_base64_encode, input, input.len().div_ceil(3) * 4)?;
+    String::from_utf8(out).ok()
+}
+
+/// Returns the bytes of the standard padded base64 encoded `input` or `None`
+/// if it is invalid.
+#[must_use]
+pub fn base64_decode(input: &str) -> Option<Vec<u8>> {
+    convert(_base64_decode, input.as_bytes(), input.len() / 4 * 3)
+}
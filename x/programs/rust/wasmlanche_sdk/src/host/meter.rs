@@ -0,0 +1,17 @@
+//! The `meter` module exposes the remaining units of the current call, so
+//! programs can return partial results before running out of units.
+
+#[link(wasm_import_module = "meter")]
+extern "C" {
+    #[link_name = "remaining_fuel"]
+    fn _remaining_fuel() -> i64;
+}
+
+/// Returns the units remaining for the current call.
+#[must_use]
+pub fn remaining_fuel() -> u64 {
+    #[allow(clippy::cast_sign_loss)]
+    unsafe {
+        _remaining_fuel() as u64
+    }
+}
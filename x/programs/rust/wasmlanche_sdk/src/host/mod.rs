@@ -1,9 +1,29 @@
 //! This module contains functionality for interacting with a `HyperSDK` `Program`
 //! host. The host implements modules that can be imported into a Program
 //! (guest).
+mod context;
+mod crypto;
+mod encoding;
+mod meter;
 mod program;
 mod state;
+mod token;
+mod u256;
 
+/// Returned by host functions if the requested key or program does not exist.
+pub const STATUS_NOT_FOUND: i32 = -2;
+/// Returned by host functions if the program is not allowed to perform the
+/// operation, such as a write to read-only state.
+pub const STATUS_PERMISSION_DENIED: i32 = -3;
+
+pub use context::*;
+pub use crypto::*;
+pub use encoding::*;
+pub use meter::*;
 pub(crate) use program::call as call_program;
+pub(crate) use program::call_readonly as call_program_readonly;
+pub(crate) use program::call_version as call_program_version;
 #[allow(unused_imports)]
 pub use state::*;
+pub use token::*;
+pub use u256::*;
@@ -12,20 +12,49 @@ extern "C" {
         function_len: usize,
         args_ptr: *const u8,
         args_len: usize,
-    ) -> i64;
+        result_ptr: *mut i64,
+    ) -> i32;
+
+    #[link_name = "call_program_readonly"]
+    fn _call_program_readonly(
+        caller_id: i64,
+        target_id: i64,
+        max_units: i64,
+        function_ptr: *const u8,
+        function_len: usize,
+        args_ptr: *const u8,
+        args_len: usize,
+        result_ptr: *mut i64,
+    ) -> i32;
+
+    #[link_name = "call_program_version"]
+    fn _call_program_version(
+        caller_id: i64,
+        target_id: i64,
+        version: i64,
+        max_units: i64,
+        function_ptr: *const u8,
+        function_len: usize,
+        args_ptr: *const u8,
+        args_len: usize,
+        result_ptr: *mut i64,
+    ) -> i32;
 }
 
 /// Calls another program `target` and returns the result.
-#[must_use]
+/// # Errors
+/// Returns the status of the host if the call fails, such as
+/// `STATUS_NOT_FOUND` if `target` does not exist.
 pub(crate) fn call(
     caller: &Program,
     target: &Program,
     max_units: i64,
     function_name: &str,
     args: &[u8],
-) -> i64 {
+) -> Result<i64, i32> {
     let function_bytes = function_name.as_bytes();
-    unsafe {
+    let mut result = 0;
+    let status = unsafe {
         _call_program(
             caller.id(),
             target.id(),
@@ -34,6 +63,77 @@ pub(crate) fn call(
             function_bytes.len(),
             args.as_ptr(),
             args.len(),
+            &mut result,
+        )
+    };
+    status_result(status, result)
+}
+
+/// Calls another program `target` against a read-only view of state and
+/// returns the result. Any state write by `target` fails with
+/// `STATUS_PERMISSION_DENIED`.
+/// # Errors
+/// Returns the status of the host if the call fails.
+pub(crate) fn call_readonly(
+    caller: &Program,
+    target: &Program,
+    max_units: i64,
+    function_name: &str,
+    args: &[u8],
+) -> Result<i64, i32> {
+    let function_bytes = function_name.as_bytes();
+    let mut result = 0;
+    let status = unsafe {
+        _call_program_readonly(
+            caller.id(),
+            target.id(),
+            max_units,
+            function_bytes.as_ptr(),
+            function_bytes.len(),
+            args.as_ptr(),
+            args.len(),
+            &mut result,
         )
+    };
+    status_result(status, result)
+}
+
+/// Calls `version` of another program `target` rather than its latest version
+/// and returns the result.
+/// # Errors
+/// Returns the status of the host if the call fails, such as
+/// `STATUS_NOT_FOUND` if the version does not exist.
+pub(crate) fn call_version(
+    caller: &Program,
+    target: &Program,
+    version: u32,
+    max_units: i64,
+    function_name: &str,
+    args: &[u8],
+) -> Result<i64, i32> {
+    let function_bytes = function_name.as_bytes();
+    let mut result = 0;
+    let status = unsafe {
+        _call_program_version(
+            caller.id(),
+            target.id(),
+            i64::from(version),
+            max_units,
+            function_bytes.as_ptr(),
+            function_bytes.len(),
+            args.as_ptr(),
+            args.len(),
+            &mut result,
+        )
+    };
+    status_result(status, result)
+}
+
+/// Returns `result` if the host returned a zero `status`, the result is only
+/// written by the host on success.
+fn status_result(status: i32, result: i64) -> Result<i64, i32> {
+    match status {
+        0 => Ok(result),
+        _ => Err(status),
     }
 }
@@ -18,6 +18,9 @@ extern "C" {
 
     #[link_name = "len"]
     fn _len(caller_id: i64, key_ptr: *const u8, key_len: usize) -> i32;
+
+    #[link_name = "delete"]
+    fn _delete(caller_id: i64, key_ptr: *const u8, key_len: usize) -> i32;
 }
 
 /// Persists the bytes at `value_ptr` to the bytes at key ptr on the host storage.
@@ -58,3 +61,12 @@ pub(crate) unsafe fn get_bytes(
 ) -> i32 {
     unsafe { _get(caller.id(), key_ptr, key_len, val_len) }
 }
+
+/// Removes the bytes associated with the key from the host storage.
+///
+/// # Safety
+/// The caller must ensure that `key_ptr` + `key_len` points to valid memory locations.
+#[must_use]
+pub(crate) unsafe fn delete_bytes(caller: &Program, key_ptr: *const u8, key_len: usize) -> i32 {
+    unsafe { _delete(caller.id(), key_ptr, key_len) }
+}
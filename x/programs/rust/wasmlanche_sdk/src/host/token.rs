@@ -0,0 +1,53 @@
+//! The `token` module provides functions for interacting with the standard
+//! token implemented by the host in the namespace of the calling program. The
+//! namespace is bound by the host, so a program can only operate on its own
+//! token.
+use crate::types::Address;
+
+#[link(wasm_import_module = "token")]
+extern "C" {
+    #[link_name = "mint"]
+    fn _mint(to_ptr: *const u8, amount: i64) -> i32;
+
+    #[link_name = "burn"]
+    fn _burn(from_ptr: *const u8, amount: i64) -> i32;
+
+    #[link_name = "transfer"]
+    fn _transfer(from_ptr: *const u8, to_ptr: *const u8, amount: i64) -> i32;
+
+    #[link_name = "balance_of"]
+    fn _balance_of(owner_ptr: *const u8) -> i64;
+
+    #[link_name = "total_supply"]
+    fn _total_supply() -> i64;
+}
+
+/// Mints `amount` tokens to `to`. Returns true on success.
+#[must_use]
+pub fn mint(to: &Address, amount: i64) -> bool {
+    unsafe { _mint(to.as_bytes().as_ptr(), amount) == 0 }
+}
+
+/// Burns `amount` tokens from `from`. Returns true on success.
+#[must_use]
+pub fn burn(from: &Address, amount: i64) -> bool {
+    unsafe { _burn(from.as_bytes().as_ptr(), amount) == 0 }
+}
+
+/// Transfers `amount` tokens from `from` to `to`. Returns true on success.
+#[must_use]
+pub fn transfer(from: &Address, to: &Address, amount: i64) -> bool {
+    unsafe { _transfer(from.as_bytes().as_ptr(), to.as_bytes().as_ptr(), amount) == 0 }
+}
+
+/// Returns the balance of `owner`.
+#[must_use]
+pub fn balance_of(owner: &Address) -> i64 {
+    unsafe { _balance_of(owner.as_bytes().as_ptr()) }
+}
+
+/// Returns the total supply of the token.
+#[must_use]
+pub fn total_supply() -> i64 {
+    unsafe { _total_supply() }
+}
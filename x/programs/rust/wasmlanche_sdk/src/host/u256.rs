@@ -0,0 +1,65 @@
+//! The `u256` module provides 256-bit unsigned integer arithmetic over 32 byte
+//! big-endian values computed by the host.
+use std::cmp::Ordering;
+
+/// The length of a big-endian encoded 256-bit value.
+pub const U256_LEN: usize = 32;
+
+#[link(wasm_import_module = "u256")]
+extern "C" {
+    #[link_name = "add"]
+    fn _add(a_ptr: *const u8, b_ptr: *const u8, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "sub"]
+    fn _sub(a_ptr: *const u8, b_ptr: *const u8, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "mul"]
+    fn _mul(a_ptr: *const u8, b_ptr: *const u8, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "div"]
+    fn _div(a_ptr: *const u8, b_ptr: *const u8, out_ptr: *mut u8) -> i32;
+
+    #[link_name = "cmp"]
+    fn _cmp(a_ptr: *const u8, b_ptr: *const u8) -> i32;
+}
+
+type Op = unsafe extern "C" fn(*const u8, *const u8, *mut u8) -> i32;
+
+fn binary_op(op: Op, a: &[u8; U256_LEN], b: &[u8; U256_LEN]) -> Option<[u8; U256_LEN]> {
+    let mut out = [0u8; U256_LEN];
+    if unsafe { op(a.as_ptr(), b.as_ptr(), out.as_mut_ptr()) } == 0 {
+        Some(out)
+    } else {
+        None
+    }
+}
+
+/// Returns `a + b` or `None` on overflow.
+#[must_use]
+pub fn u256_add(a: &[u8; U256_LEN], b: &[u8; U256_LEN]) -> Option<[u8; U256_LEN]> {
+    binary_op(_add, a, b)
+}
+
+/// Returns `a - b` or `None` on underflow.
+#[must_use]
+pub fn u256_sub(a: &[u8; U256_LEN], b: &[u8; U256_LEN]) -> Option<[u8; U256_LEN]> {
+    binary_op(_sub, a, b)
+}
+
+/// Returns `a * b` or `None` on overflow.
+#[must_use]
+pub fn u256_mul(a: &[u8; U256_LEN], b: &[u8; U256_LEN]) -> Option<[u8; U256_LEN]> {
+    binary_op(_mul, a, b)
+}
+
+/// Returns `a / b` rounded down or `None` if `b` is zero.
+#[must_use]
+pub fn u256_div(a: &[u8; U256_LEN], b: &[u8; U256_LEN]) -> Option<[u8; U256_LEN]> {
+    binary_op(_div, a, b)
+}
+
+/// Compares `a` and `b`.
+#[must_use]
+pub fn u256_cmp(a: &[u8; U256_LEN], b: &[u8; U256_LEN]) -> Ordering {
+    unsafe { _cmp(a.as_ptr(), b.as_ptr()) }.cmp(&0)
+}
@@ -1,5 +1,6 @@
 #![deny(clippy::pedantic)]
 
+pub mod debug;
 pub mod errors;
 pub mod host;
 pub mod memory;
@@ -1,4 +1,8 @@
-use crate::{host::call_program, state::State, types::Argument};
+use crate::{
+    host::{call_program, call_program_readonly, call_program_version},
+    state::State,
+    types::Argument,
+};
 use serde::{Deserialize, Serialize};
 
 /// Represents the current Program in the context of the caller. Or an external
@@ -25,14 +29,15 @@ impl Program {
     /// Attempts to call another program `target` from this program `caller`.
     /// # Safety
     /// The caller must ensure that `function_name` + `args` point to valid memory locations.
-    #[must_use]
+    /// # Errors
+    /// Returns the status of the host if the call fails.
     pub fn call_program(
         &self,
         target: &Program,
         max_units: i64,
         function_name: &str,
         args: &[Box<dyn Argument>],
-    ) -> i64 {
+    ) -> Result<i64, i32> {
         call_program(
             self,
             target,
@@ -41,6 +46,52 @@ impl Program {
             marshal_args(args).as_ref(),
         )
     }
+
+    /// Attempts to call another program `target` from this program `caller`
+    /// without allowing `target` to modify state.
+    /// # Safety
+    /// The caller must ensure that `function_name` + `args` point to valid memory locations.
+    /// # Errors
+    /// Returns the status of the host if the call fails.
+    pub fn call_program_readonly(
+        &self,
+        target: &Program,
+        max_units: i64,
+        function_name: &str,
+        args: &[Box<dyn Argument>],
+    ) -> Result<i64, i32> {
+        call_program_readonly(
+            self,
+            target,
+            max_units,
+            function_name,
+            marshal_args(args).as_ref(),
+        )
+    }
+
+    /// Attempts to call `version` of another program `target` from this
+    /// program `caller` rather than its latest version.
+    /// # Safety
+    /// The caller must ensure that `function_name` + `args` point to valid memory locations.
+    /// # Errors
+    /// Returns the status of the host if the call fails.
+    pub fn call_program_version(
+        &self,
+        target: &Program,
+        version: u32,
+        max_units: i64,
+        function_name: &str,
+        args: &[Box<dyn Argument>],
+    ) -> Result<i64, i32> {
+        call_program_version(
+            self,
+            target,
+            version,
+            max_units,
+            function_name,
+            marshal_args(args).as_ref(),
+        )
+    }
 }
 
 impl From<Program> for i64 {
@@ -3,7 +3,9 @@ use serde_bare::{from_slice, to_vec};
 
 use crate::{
     errors::StateError,
-    host::{get_bytes, len_bytes, put_bytes},
+    host::{
+        delete_bytes, get_bytes, len_bytes, put_bytes, STATUS_NOT_FOUND, STATUS_PERMISSION_DENIED,
+    },
     program::Program,
 };
 
@@ -38,6 +40,23 @@ impl State {
             )
         } {
             0 => Ok(()),
+            STATUS_PERMISSION_DENIED => Err(StateError::PermissionDenied),
+            _ => Err(StateError::Write),
+        }
+    }
+
+    /// Remove a key and its value from the host storage.
+    /// # Errors
+    /// Returns an `StateError` if the key does not exist or if the host
+    /// fails to handle the operation.
+    pub fn delete<K>(&self, key: K) -> Result<(), StateError>
+    where
+        K: AsRef<[u8]>,
+    {
+        match unsafe { delete_bytes(&self.program, key.as_ref().as_ptr(), key.as_ref().len()) } {
+            0 => Ok(()),
+            STATUS_NOT_FOUND => Err(StateError::NotFound),
+            STATUS_PERMISSION_DENIED => Err(StateError::PermissionDenied),
             _ => Err(StateError::Write),
         }
     }
@@ -61,6 +80,12 @@ impl State {
         let key_len = key.as_ref().len();
 
         let val_len = unsafe { len_bytes(&self.program, key_ptr, key_len) };
+        if val_len == STATUS_NOT_FOUND {
+            return Err(StateError::NotFound);
+        }
+        if val_len < 0 {
+            return Err(StateError::Read);
+        }
         let val_ptr = unsafe { get_bytes(&self.program, key_ptr, key_len, val_len) };
         if val_ptr < 0 {
             return Err(StateError::Read);
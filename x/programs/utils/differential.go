@@ -0,0 +1,290 @@
+// Copyright (C) 2023, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+
+	"github.com/ava-labs/hypersdk/state"
+	"github.com/ava-labs/hypersdk/x/programs/runtime"
+)
+
+var (
+	_ runtime.Runtime = &DifferentialRuntime{}
+	_ runtime.Memory  = &differentialMemory{}
+	_ runtime.Meter   = &differentialMeter{}
+
+	ErrDivergence              = errors.New("runtimes diverged")
+	ErrChildRuntimeUnsupported = errors.New("child runtimes are not supported")
+)
+
+// NewConfigFn returns a new runtime config using [strategy].
+type NewConfigFn func(strategy runtime.EngineCompileStrategy) (*runtime.Config, error)
+
+// NewImportsFn returns the supported imports backed by [db].
+type NewImportsFn func(db state.Mutable) runtime.SupportedImports
+
+// NewDifferentialRuntime returns a runtime which executes every call twice,
+// once with a module compiled by CompileWasm and once with a module loaded by
+// PrecompiledWasm. Each runtime has its own state. Results, state writes and
+// units consumed are compared after each call and any difference is returned
+// as an ErrDivergence error.
+//
+// Note: this is intended for testing nondeterminism between engine
+// configurations and must not be used in production.
+func NewDifferentialRuntime(log logging.Logger, newConfig NewConfigFn, newImports NewImportsFn) *DifferentialRuntime {
+	return &DifferentialRuntime{
+		log:        log,
+		newConfig:  newConfig,
+		newImports: newImports,
+		dbs:        [2]*testDB{NewTestDB(), NewTestDB()},
+	}
+}
+
+type DifferentialRuntime struct {
+	log        logging.Logger
+	newConfig  NewConfigFn
+	newImports NewImportsFn
+
+	runtimes [2]runtime.Runtime
+	dbs      [2]*testDB
+}
+
+func (d *DifferentialRuntime) Initialize(ctx context.Context, programBytes []byte) error {
+	// configs can only be used once
+	precompileCfg, err := d.newConfig(runtime.CompileWasm)
+	if err != nil {
+		return err
+	}
+	precompiledBytes, err := runtime.PreCompileWasmBytes(programBytes, precompileCfg)
+	if err != nil {
+		return err
+	}
+
+	for i, strategy := range []runtime.EngineCompileStrategy{runtime.CompileWasm, runtime.PrecompiledWasm} {
+		cfg, err := d.newConfig(strategy)
+		if err != nil {
+			return err
+		}
+		d.runtimes[i] = runtime.New(d.log, cfg, d.newImports(d.dbs[i]))
+	}
+
+	err = d.runtimes[0].Initialize(ctx, programBytes)
+	if err != nil {
+		return err
+	}
+	return d.runtimes[1].Initialize(ctx, precompiledBytes)
+}
+
+func (d *DifferentialRuntime) Call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	compiledResult, compiledErr := d.runtimes[0].Call(ctx, name, params...)
+	precompiledResult, precompiledErr := d.runtimes[1].Call(ctx, name, params...)
+
+	if (compiledErr == nil) != (precompiledErr == nil) {
+		return nil, fmt.Errorf("%w: %s: errors: compiled: %v precompiled: %v", ErrDivergence, name, compiledErr, precompiledErr)
+	}
+	if !equalUint64s(compiledResult, precompiledResult) {
+		return nil, fmt.Errorf("%w: %s: results: compiled: %v precompiled: %v", ErrDivergence, name, compiledResult, precompiledResult)
+	}
+	compiledBalance := d.runtimes[0].Meter().GetBalance()
+	precompiledBalance := d.runtimes[1].Meter().GetBalance()
+	if compiledBalance != precompiledBalance {
+		return nil, fmt.Errorf("%w: %s: balance: compiled: %d precompiled: %d", ErrDivergence, name, compiledBalance, precompiledBalance)
+	}
+	if err := equalState(d.dbs[0], d.dbs[1]); err != nil {
+		return nil, fmt.Errorf("%w: %s: state: %w", ErrDivergence, name, err)
+	}
+
+	return compiledResult, compiledErr
+}
+
+func (d *DifferentialRuntime) Memory() runtime.Memory {
+	return &differentialMemory{
+		memories: [2]runtime.Memory{d.runtimes[0].Memory(), d.runtimes[1].Memory()},
+	}
+}
+
+func (d *DifferentialRuntime) Meter() runtime.Meter {
+	return &differentialMeter{
+		meters: [2]runtime.Meter{d.runtimes[0].Meter(), d.runtimes[1].Meter()},
+	}
+}
+
+// NewChildRuntime is not supported as a config can only be used by one of the
+// runtimes.
+func (d *DifferentialRuntime) NewChildRuntime(*runtime.Config, runtime.SupportedImports) (runtime.Runtime, error) {
+	return nil, ErrChildRuntimeUnsupported
+}
+
+func (d *DifferentialRuntime) Stop() {
+	for _, rt := range d.runtimes {
+		if rt != nil {
+			rt.Stop()
+		}
+	}
+}
+
+// State returns the state of the CompileWasm runtime.
+func (d *DifferentialRuntime) State() state.Mutable {
+	return d.dbs[0]
+}
+
+// differentialMemory applies every operation to the memory of both runtimes.
+type differentialMemory struct {
+	memories [2]runtime.Memory
+}
+
+func (m *differentialMemory) Range(offset uint64, length uint64) ([]byte, error) {
+	a, err := m.memories[0].Range(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	b, err := m.memories[1].Range(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(a, b) {
+		return nil, fmt.Errorf("%w: memory range at %d", ErrDivergence, offset)
+	}
+	return a, nil
+}
+
+func (m *differentialMemory) Alloc(length uint64) (uint64, error) {
+	a, err := m.memories[0].Alloc(length)
+	if err != nil {
+		return 0, err
+	}
+	b, err := m.memories[1].Alloc(length)
+	if err != nil {
+		return 0, err
+	}
+	if a != b {
+		return 0, fmt.Errorf("%w: alloc: compiled: %d precompiled: %d", ErrDivergence, a, b)
+	}
+	return a, nil
+}
+
+func (m *differentialMemory) Dealloc(offset uint64, length uint64) error {
+	for _, mem := range m.memories {
+		if err := mem.Dealloc(offset, length); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *differentialMemory) Write(offset uint64, buf []byte) error {
+	for _, mem := range m.memories {
+		if err := mem.Write(offset, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *differentialMemory) Len() (uint64, error) {
+	a, err := m.memories[0].Len()
+	if err != nil {
+		return 0, err
+	}
+	b, err := m.memories[1].Len()
+	if err != nil {
+		return 0, err
+	}
+	if a != b {
+		return 0, fmt.Errorf("%w: memory len: compiled: %d precompiled: %d", ErrDivergence, a, b)
+	}
+	return a, nil
+}
+
+func (m *differentialMemory) Grow(delta uint64) (uint64, error) {
+	a, err := m.memories[0].Grow(delta)
+	if err != nil {
+		return 0, err
+	}
+	b, err := m.memories[1].Grow(delta)
+	if err != nil {
+		return 0, err
+	}
+	if a != b {
+		return 0, fmt.Errorf("%w: memory grow: compiled: %d precompiled: %d", ErrDivergence, a, b)
+	}
+	return a, nil
+}
+
+// differentialMeter applies every operation to the meter of both runtimes.
+type differentialMeter struct {
+	meters [2]runtime.Meter
+}
+
+func (m *differentialMeter) GetBalance() uint64 {
+	return m.meters[0].GetBalance()
+}
+
+func (m *differentialMeter) Spend(units uint64) (uint64, error) {
+	for _, meter := range m.meters {
+		if _, err := meter.Spend(units); err != nil {
+			return 0, err
+		}
+	}
+	return m.GetBalance(), nil
+}
+
+func (m *differentialMeter) AddUnits(units uint64) (uint64, error) {
+	for _, meter := range m.meters {
+		if _, err := meter.AddUnits(units); err != nil {
+			return 0, err
+		}
+	}
+	return m.GetBalance(), nil
+}
+
+// TransferUnits spends [units] from both meters but only adds them to [to]
+// once, as both meters represent the same logical balance.
+func (m *differentialMeter) TransferUnits(to runtime.Meter, units uint64) (uint64, error) {
+	if _, err := m.Spend(units); err != nil {
+		return 0, err
+	}
+	return to.AddUnits(units)
+}
+
+func equalUint64s(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalState returns an error describing the first key which differs between
+// [a] and [b].
+func equalState(a, b *testDB) error {
+	iterA := a.db.NewIterator()
+	defer iterA.Release()
+	iterB := b.db.NewIterator()
+	defer iterB.Release()
+
+	for {
+		nextA, nextB := iterA.Next(), iterB.Next()
+		switch {
+		case !nextA && !nextB:
+			return errors.Join(iterA.Error(), iterB.Error())
+		case nextA != nextB:
+			return errors.New("number of keys differ")
+		case !bytes.Equal(iterA.Key(), iterB.Key()):
+			return fmt.Errorf("keys differ: compiled: %x precompiled: %x", iterA.Key(), iterB.Key())
+		case !bytes.Equal(iterA.Value(), iterB.Value()):
+			return fmt.Errorf("values of key %x differ", iterA.Key())
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"os"
 
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/database/memdb"
 	"github.com/ava-labs/hypersdk/state"
 )
@@ -42,6 +43,11 @@ func (c *testDB) Remove(_ context.Context, key []byte) error {
 	return c.db.Delete(key)
 }
 
+// NewIterator returns an iterator over all key values of the db ordered by key.
+func (c *testDB) NewIterator() database.Iterator {
+	return c.db.NewIterator()
+}
+
 func GetProgramBytes(filePath string) ([]byte, error) {
 	return os.ReadFile(filePath)
 }